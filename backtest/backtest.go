@@ -0,0 +1,356 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strikelogic/calculator"
+	"strikelogic/chainhistory"
+	"strikelogic/storage"
+	"strikelogic/strategies"
+	"time"
+)
+
+// Rules control when positions are opened and closed.
+// ProfitTarget and StopLoss are fractions of the entry premium (|NetDebit|):
+// ProfitTarget 0.5 closes once half the premium is made, StopLoss 2 closes at a loss of twice the premium.
+// Zero disables the rule.
+type Rules struct {
+	EntryDTE     int     `json:"entryDTE"`     // Open using the expiry closest to this many days out
+	ExitDTE      int     `json:"exitDTE"`      // Close once the front leg has this many days left
+	ProfitTarget float64 `json:"profitTarget"` // Close at this fraction of entry premium in profit
+	StopLoss     float64 `json:"stopLoss"`     // Close at this fraction of entry premium in loss
+}
+
+// Config describes one backtest run
+type Config struct {
+	Ticker string    `json:"ticker"`
+	Recipe string    `json:"recipe"` // Recipe name; empty runs every recipe
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Rules  Rules     `json:"rules"`
+}
+
+// TradeLog records a single round trip
+type TradeLog struct {
+	Recipe      string    `json:"recipe"`
+	Description string    `json:"description"`
+	EntryDate   string    `json:"entryDate"`
+	ExitDate    string    `json:"exitDate"`
+	Expiry      string    `json:"expiry"`
+	EntryPrice  float64   `json:"entryPrice"` // Underlying at entry
+	ExitPrice   float64   `json:"exitPrice"`  // Underlying at exit
+	NetDebit    float64   `json:"netDebit"`   // Positive for debit, negative for credit
+	PnL         float64   `json:"pnl"`
+	ExitReason  string    `json:"exitReason"`
+	DailyMarks  []float64 `json:"dailyMarks"` // Unrealized P&L at each daily mark
+}
+
+// EquityPoint is cumulative realized plus open P&L at the end of a day
+type EquityPoint struct {
+	Date   string  `json:"date"`
+	Equity float64 `json:"equity"`
+}
+
+// RecipeReport holds the results for one recipe
+type RecipeReport struct {
+	Recipe      string        `json:"recipe"`
+	Trades      []TradeLog    `json:"trades"`
+	Equity      []EquityPoint `json:"equity"`
+	TotalPnL    float64       `json:"totalPnL"`
+	AvgPnL      float64       `json:"avgPnL"`
+	WinRate     float64       `json:"winRate"` // Percent of closed trades with positive P&L
+	MaxDrawdown float64       `json:"maxDrawdown"`
+}
+
+// Report is the result of Run
+type Report struct {
+	Ticker  string         `json:"ticker"`
+	Start   string         `json:"start"`
+	End     string         `json:"end"`
+	Rules   Rules          `json:"rules"`
+	Days    int            `json:"days"` // Number of days with stored chain data
+	Recipes []RecipeReport `json:"recipes"`
+}
+
+// Exit reasons
+const (
+	ExitProfitTarget = "Profit Target"
+	ExitStopLoss     = "Stop Loss"
+	ExitDTE          = "DTE"
+	ExitExpiry       = "Expiry"
+	ExitEndOfTest    = "End of Test"
+)
+
+// day is the last stored snapshot of one trading day
+type day struct {
+	date  time.Time
+	price float64
+	chain []calculator.OptionContract
+}
+
+// openPosition tracks a trade while it is on
+type openPosition struct {
+	trade strategies.Trade
+	log   TradeLog
+	front time.Time // Earliest leg expiry (the short leg of calendars and diagonals)
+	marks []float64 // Unrealized P&L at each mark
+}
+
+// Run replays the configured recipes over stored chain history
+func Run(cfg Config) (Report, error) {
+	report := Report{
+		Ticker: cfg.Ticker,
+		Start:  cfg.Start.Format("2006-01-02"),
+		End:    cfg.End.Format("2006-01-02"),
+		Rules:  cfg.Rules,
+	}
+
+	days, err := loadDays(cfg.Ticker, cfg.Start, cfg.End)
+	if err != nil {
+		return report, err
+	}
+	if len(days) == 0 {
+		return report, fmt.Errorf("no stored chain history for %s between %s and %s", cfg.Ticker, report.Start, report.End)
+	}
+	report.Days = len(days)
+
+	// Recipe names are stable regardless of price, so resolve them once
+	var names []string
+	for _, r := range strategies.Recipes(days[0].price) {
+		if cfg.Recipe == "" || r.Name == cfg.Recipe {
+			names = append(names, r.Name)
+		}
+	}
+	if len(names) == 0 {
+		return report, fmt.Errorf("unknown recipe: %s", cfg.Recipe)
+	}
+
+	for _, name := range names {
+		report.Recipes = append(report.Recipes, runRecipe(name, days, cfg.Rules))
+	}
+	return report, nil
+}
+
+// loadDays fetches the last snapshot of each day in [start, end]
+func loadDays(ticker string, start, end time.Time) ([]day, error) {
+	times, err := storage.GetSnapshotTimes(ticker, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the latest capture per calendar day
+	lastPerDay := make(map[string]time.Time)
+	for _, t := range times {
+		key := t.Format("2006-01-02")
+		if t.After(lastPerDay[key]) {
+			lastPerDay[key] = t
+		}
+	}
+
+	var days []day
+	for _, t := range lastPerDay {
+		quote, err := storage.GetQuoteAsOf(ticker, t)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := storage.GetChainAsOf(ticker, t)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, day{date: t, price: quote.Price, chain: chainhistory.ToContracts(rows)})
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].date.Before(days[j].date) })
+	return days, nil
+}
+
+func runRecipe(name string, days []day, rules Rules) RecipeReport {
+	report := RecipeReport{Recipe: name}
+	var pos *openPosition
+	realized := 0.0
+
+	for i, d := range days {
+		if pos == nil {
+			// A trade opened on the last day could never be marked or closed
+			if i < len(days)-1 {
+				pos = openTrade(name, d, rules)
+			}
+		} else {
+			pnl := markPosition(pos, d)
+			reason := exitReason(pos, d, pnl, rules)
+			if reason == "" && i == len(days)-1 {
+				reason = ExitEndOfTest
+			}
+			if reason != "" {
+				closed := closePosition(pos, d, pnl, reason)
+				report.Trades = append(report.Trades, closed)
+				realized += closed.PnL
+				pos = nil
+			}
+		}
+
+		equity := realized
+		if pos != nil && len(pos.marks) > 0 {
+			equity += pos.marks[len(pos.marks)-1]
+		}
+		report.Equity = append(report.Equity, EquityPoint{
+			Date:   d.date.Format("2006-01-02"),
+			Equity: math.Round(equity*100) / 100,
+		})
+	}
+
+	summarize(&report)
+	return report
+}
+
+// openTrade builds the recipe on the expiry closest to EntryDTE; returns nil if the recipe can't be built.
+// ExitDTE and expiry exits follow the front leg.
+func openTrade(name string, d day, rules Rules) *openPosition {
+	var recipe *strategies.StrategyRecipe
+	recipes := strategies.Recipes(d.price)
+	for i := range recipes {
+		if recipes[i].Name == name {
+			recipe = &recipes[i]
+		}
+	}
+	if recipe == nil {
+		return nil
+	}
+
+	// Multi-expiry recipes also get their back month from the same snapshot
+	target := d.date.AddDate(0, 0, rules.EntryDTE).Format("2006-01-02")
+	chain := strategies.ChainForRecipe(*recipe, d.chain, target)
+	if len(chain) == 0 {
+		return nil
+	}
+
+	// With no view on direction, target-price recipes are struck at the money
+	trade := recipe.Builder(chain, d.price)
+	if trade == nil {
+		return nil
+	}
+	trade.CalculateMetrics(d.price)
+
+	front, err := time.Parse("2006-01-02", trade.ExpirationDate)
+	if err != nil {
+		return nil
+	}
+
+	return &openPosition{
+		trade: *trade,
+		front: front,
+		log: TradeLog{
+			Recipe:      name,
+			Description: trade.Description,
+			EntryDate:   d.date.Format("2006-01-02"),
+			Expiry:      trade.ExpirationDate,
+			EntryPrice:  d.price,
+			NetDebit:    math.Round(trade.NetDebit*100) / 100,
+		},
+	}
+}
+
+// markPosition values every leg against the day's chain and returns unrealized P&L.
+// Options are marked at mid, options on or past their expiry date at intrinsic value,
+// and legs missing from the snapshot at their entry mid.
+func markPosition(pos *openPosition, d day) float64 {
+	value := 0.0
+	for _, leg := range pos.trade.Legs {
+		var legValue float64
+		if leg.IsStock {
+			legValue = d.price * float64(leg.Quantity)
+		} else {
+			legValue = optionMark(leg.Option, d) * 100 * float64(leg.Quantity)
+		}
+
+		if leg.Action == strategies.Buy {
+			value += legValue
+		} else {
+			value -= legValue
+		}
+	}
+
+	pnl := value - pos.trade.NetDebit
+	pos.marks = append(pos.marks, math.Round(pnl*100)/100)
+	return pnl
+}
+
+func optionMark(opt calculator.OptionContract, d day) float64 {
+	expiry, err := time.Parse("2006-01-02", opt.Expiry)
+	if err == nil && !d.date.Before(expiry) {
+		if opt.Type == calculator.Call {
+			return math.Max(0, d.price-opt.Strike)
+		}
+		return math.Max(0, opt.Strike-d.price)
+	}
+
+	for _, c := range d.chain {
+		if c.Expiry != opt.Expiry || c.Type != opt.Type || c.Strike != opt.Strike {
+			continue
+		}
+		if c.Bid > 0 && c.Ask > 0 {
+			return (c.Bid + c.Ask) / 2
+		}
+		if c.Last > 0 {
+			return c.Last
+		}
+	}
+
+	// Not quoted today; fall back to the entry quote
+	return (opt.Bid + opt.Ask) / 2
+}
+
+func exitReason(pos *openPosition, d day, pnl float64, rules Rules) string {
+	premium := math.Abs(pos.trade.NetDebit)
+	if rules.ProfitTarget > 0 && premium > 0 && pnl >= rules.ProfitTarget*premium {
+		return ExitProfitTarget
+	}
+	if rules.StopLoss > 0 && premium > 0 && pnl <= -rules.StopLoss*premium {
+		return ExitStopLoss
+	}
+
+	dte := pos.front.Sub(d.date).Hours() / 24
+	if dte <= 0 {
+		return ExitExpiry
+	}
+	if rules.ExitDTE > 0 && dte <= float64(rules.ExitDTE) {
+		return ExitDTE
+	}
+	return ""
+}
+
+func closePosition(pos *openPosition, d day, pnl float64, reason string) TradeLog {
+	tl := pos.log
+	tl.ExitDate = d.date.Format("2006-01-02")
+	tl.ExitPrice = d.price
+	tl.PnL = math.Round(pnl*100) / 100
+	tl.ExitReason = reason
+	tl.DailyMarks = pos.marks
+	return tl
+}
+
+func summarize(r *RecipeReport) {
+	wins := 0
+	for _, t := range r.Trades {
+		r.TotalPnL += t.PnL
+		if t.PnL > 0 {
+			wins++
+		}
+	}
+	if len(r.Trades) > 0 {
+		r.AvgPnL = math.Round(r.TotalPnL/float64(len(r.Trades))*100) / 100
+		r.WinRate = math.Round(float64(wins)/float64(len(r.Trades))*1000) / 10
+	}
+	r.TotalPnL = math.Round(r.TotalPnL*100) / 100
+
+	peak := 0.0
+	for _, p := range r.Equity {
+		if p.Equity > peak {
+			peak = p.Equity
+		}
+		if dd := peak - p.Equity; dd > r.MaxDrawdown {
+			r.MaxDrawdown = math.Round(dd*100) / 100
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package backtest
+
+import (
+	"strikelogic/calculator"
+	"strikelogic/strategies"
+	"testing"
+	"time"
+)
+
+func TestExitReason(t *testing.T) {
+	entry := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	front := entry.AddDate(0, 0, 30)
+	credit := &openPosition{trade: strategies.Trade{NetDebit: -200}, front: front}
+	debit := &openPosition{trade: strategies.Trade{NetDebit: 300}, front: front}
+	rules := Rules{ProfitTarget: 0.5, StopLoss: 2, ExitDTE: 7}
+
+	tests := []struct {
+		name string
+		pos  *openPosition
+		days int // After entry
+		pnl  float64
+		want string
+	}{
+		{"hold", credit, 5, 50, ""},
+		{"half the credit", credit, 5, 100, ExitProfitTarget},
+		{"twice the credit lost", credit, 5, -400, ExitStopLoss},
+		{"just short of the stop", credit, 5, -399, ""},
+		{"half the debit", debit, 5, 150, ExitProfitTarget},
+		{"exit DTE", credit, 23, 0, ExitDTE},
+		{"expiry", credit, 30, 0, ExitExpiry},
+		{"profit target before expiry", credit, 30, 150, ExitProfitTarget},
+	}
+	for _, tt := range tests {
+		d := day{date: entry.AddDate(0, 0, tt.days)}
+		if got := exitReason(tt.pos, d, tt.pnl, rules); got != tt.want {
+			t.Errorf("%s: exitReason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Disabled rules only leave expiry
+	if got := exitReason(credit, day{date: entry.AddDate(0, 0, 29)}, 1000, Rules{}); got != "" {
+		t.Errorf("no rules: exitReason = %q", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	r := RecipeReport{
+		Trades: []TradeLog{{PnL: 100}, {PnL: -50}, {PnL: 25}, {PnL: 0}},
+		Equity: []EquityPoint{{Equity: 0}, {Equity: 100}, {Equity: 40}, {Equity: 120}, {Equity: -10}, {Equity: 75}},
+	}
+	summarize(&r)
+
+	if r.TotalPnL != 75 || r.AvgPnL != 18.75 {
+		t.Errorf("total %v avg %v, want 75 and 18.75", r.TotalPnL, r.AvgPnL)
+	}
+	if r.WinRate != 50 {
+		t.Errorf("win rate %v, want 50", r.WinRate)
+	}
+	// The deepest fall is from the 120 peak to -10
+	if r.MaxDrawdown != 130 {
+		t.Errorf("max drawdown %v, want 130", r.MaxDrawdown)
+	}
+
+	var empty RecipeReport
+	summarize(&empty)
+	if empty.TotalPnL != 0 || empty.WinRate != 0 || empty.MaxDrawdown != 0 {
+		t.Errorf("empty report summarized to %+v", empty)
+	}
+}
+
+func TestOptionMark(t *testing.T) {
+	call := calculator.OptionContract{Expiry: "2030-01-18", Type: calculator.Call, Strike: 100, Bid: 1, Ask: 2}
+	d := day{
+		date:  time.Date(2030, 1, 10, 0, 0, 0, 0, time.UTC),
+		price: 104,
+		chain: []calculator.OptionContract{
+			{Expiry: "2030-01-18", Type: calculator.Put, Strike: 100, Bid: 9, Ask: 10},
+			{Expiry: "2030-01-18", Type: calculator.Call, Strike: 100, Bid: 4.5, Ask: 5.5},
+		},
+	}
+	if got := optionMark(call, d); got != 5 {
+		t.Errorf("quoted mark %v, want the mid 5", got)
+	}
+
+	// A two-sided market is missing: use the last trade, then the entry quote
+	d.chain[1] = calculator.OptionContract{Expiry: "2030-01-18", Type: calculator.Call, Strike: 100, Last: 4.8}
+	if got := optionMark(call, d); got != 4.8 {
+		t.Errorf("last-trade mark %v, want 4.8", got)
+	}
+	d.chain = d.chain[:1]
+	if got := optionMark(call, d); got != 1.5 {
+		t.Errorf("unquoted mark %v, want the entry mid 1.5", got)
+	}
+
+	// On its expiry date the option is worth intrinsic value
+	d.date = time.Date(2030, 1, 18, 0, 0, 0, 0, time.UTC)
+	if got := optionMark(call, d); got != 4 {
+		t.Errorf("expiry mark %v, want 4", got)
+	}
+}
@@ -3,6 +3,7 @@ package calculator
 import (
 	"math"
 	"math/rand"
+	"strikelogic/rates"
 	"time"
 )
 
@@ -16,18 +17,24 @@ const (
 
 // OptionContract holds details about an option contract
 type OptionContract struct {
-	Strike     float64    `json:"strike"`
-	Expiry     string     `json:"expiry"` // ISO date string
-	Type       OptionType `json:"type"`
-	Bid        float64    `json:"bid"`
-	Ask        float64    `json:"ask"`
-	Last       float64    `json:"last"`
-	Vol        float64    `json:"vol"` // Implied Volatility
-	Delta      float64    `json:"delta"`
-	Gamma      float64    `json:"gamma"`
-	Theta      float64    `json:"theta"`
-	Vega       float64    `json:"vega"`
-	Underlying string     `json:"underlying"`
+	Strike       float64    `json:"strike"`
+	Expiry       string     `json:"expiry"` // ISO date string
+	Type         OptionType `json:"type"`
+	Bid          float64    `json:"bid"`
+	Ask          float64    `json:"ask"`
+	Last         float64    `json:"last"`
+	Volume       int64      `json:"volume"`             // Contracts traded today
+	OpenInterest int64      `json:"openInterest"`       // Contracts open as of the prior close
+	Vol          float64    `json:"vol"`                // Implied Volatility
+	IVSource     string     `json:"ivSource,omitempty"` // How Vol was obtained (see IVSource* constants)
+	Delta        float64    `json:"delta"`
+	Gamma        float64    `json:"gamma"`
+	Theta        float64    `json:"theta"`
+	Vega         float64    `json:"vega"`
+	Underlying   string     `json:"underlying"`
+
+	// Extra value over the European price from the right to exercise early (American models only)
+	EarlyExercisePremium float64 `json:"earlyExercisePremium,omitempty"`
 }
 
 // cumulativeDistributionFunction for standard normal distribution
@@ -43,63 +50,57 @@ func pdf(x float64) float64 {
 // CalculateOptionPrice calculates Price and Greeks for Call or Put
 // S: Current Price, K: Strike Price, T: Time to Expiry (years), r: Risk-Free Rate, sigma: Implied Volatility
 func CalculateOptionPrice(optType OptionType, S, K, T, r, sigma float64) (price, delta, gamma, theta, vega float64) {
-	d1 := (math.Log(S/K) + (r+0.5*sigma*sigma)*T) / (sigma * math.Sqrt(T))
+	return CalculateOptionPriceWithYield(optType, S, K, T, r, 0, sigma)
+}
+
+// CalculateOptionPriceWithYield is the Merton extension of Black-Scholes for a continuous dividend yield q
+func CalculateOptionPriceWithYield(optType OptionType, S, K, T, r, q, sigma float64) (price, delta, gamma, theta, vega float64) {
+	d1 := (math.Log(S/K) + (r-q+0.5*sigma*sigma)*T) / (sigma * math.Sqrt(T))
 	d2 := d1 - sigma*math.Sqrt(T)
+	divDiscount := math.Exp(-q * T)
 
 	// Common Greeks
-	gamma = pdf(d1) / (S * sigma * math.Sqrt(T))
-	vega = S * pdf(d1) * math.Sqrt(T) / 100 // Divide by 100 for percentage change
+	gamma = divDiscount * pdf(d1) / (S * sigma * math.Sqrt(T))
+	vega = S * divDiscount * pdf(d1) * math.Sqrt(T) / 100 // Divide by 100 for percentage change
+
+	term1 := -(S * divDiscount * pdf(d1) * sigma) / (2 * math.Sqrt(T))
 
 	if optType == Call {
-		price = S*cdf(d1) - K*math.Exp(-r*T)*cdf(d2)
-		delta = cdf(d1)
+		price = S*divDiscount*cdf(d1) - K*math.Exp(-r*T)*cdf(d2)
+		delta = divDiscount * cdf(d1)
 
-		term1 := -(S * pdf(d1) * sigma) / (2 * math.Sqrt(T))
 		term2 := r * K * math.Exp(-r*T) * cdf(d2)
-		theta = (term1 - term2) / 365 // Daily Theta
+		term3 := q * S * divDiscount * cdf(d1)
+		theta = (term1 - term2 + term3) / 365 // Daily Theta
 	} else {
-		price = K*math.Exp(-r*T)*cdf(-d2) - S*cdf(-d1)
-		delta = cdf(d1) - 1
+		price = K*math.Exp(-r*T)*cdf(-d2) - S*divDiscount*cdf(-d1)
+		delta = divDiscount * (cdf(d1) - 1)
 
-		term1 := -(S * pdf(d1) * sigma) / (2 * math.Sqrt(T))
 		term2 := r * K * math.Exp(-r*T) * cdf(-d2)
-		theta = (term1 + term2) / 365 // Daily Theta
+		term3 := q * S * divDiscount * cdf(-d1)
+		theta = (term1 + term2 - term3) / 365 // Daily Theta
 	}
 
 	return price, delta, gamma, theta, vega
 }
 
-// CalculateIV solves for Implied Volatility using Newton-Raphson
+// CalculateIV solves for Implied Volatility, returning 0 when no volatility reproduces the price.
+// Use SolveIV to find out why a solve failed.
 func CalculateIV(marketPrice float64, optType OptionType, S, K, T, r float64) float64 {
-	sigma := 0.5 // Initial guess
-	tolerance := 1e-5
-	maxIterations := 100
-
-	for i := 0; i < maxIterations; i++ {
-		price, _, _, _, vega := CalculateOptionPrice(optType, S, K, T, r, sigma)
-		diff := marketPrice - price
-
-		if math.Abs(diff) < tolerance {
-			return sigma
-		}
-
-		// Avoid division by zero
-		if vega == 0 {
-			break
-		}
-
-		// Vega in CalculateOptionPrice is scaled by 100, so we need to adjust or use raw vega
-		// Let's recalculate raw vega here for precision in NR
-		d1 := (math.Log(S/K) + (r+0.5*sigma*sigma)*T) / (sigma * math.Sqrt(T))
-		rawVega := S * pdf(d1) * math.Sqrt(T)
-
-		sigma = sigma + diff/rawVega
+	iv, err := SolveIV(marketPrice, optType, S, K, T, r, 0)
+	if err != nil {
+		return 0
 	}
+	return iv
+}
 
-	return sigma
+// CalculateIVFromCurve solves for Implied Volatility, discounting at the curve rate for the option's tenor
+func CalculateIVFromCurve(marketPrice float64, optType OptionType, S, K, T float64) float64 {
+	return CalculateIV(marketPrice, optType, S, K, T, rates.RateFor(T))
 }
 
-// CalculatePoP calculates Probability of Profit
+// CalculatePoP calculates Probability of Profit for a trade that profits above a single breakeven,
+// i.e. the chance of finishing above breakEven. Multi-leg trades should use strategies.Trade.PoP.
 // Formula: PoP = 1 - NormCDF( (log(BreakEven / StockPrice) - (Volatility^2 / 2) * Time) / (Volatility * sqrt(Time)) )
 func CalculatePoP(breakEven, S, T, sigma float64) float64 {
 	if T <= 0 || sigma <= 0 {
@@ -138,19 +139,17 @@ func GetChain(ticker string) []OptionContract {
 
 // GetChainForExpiry generates a mock option chain for a given ticker and days to expiry
 func GetChainForExpiry(ticker string, daysOut int) []OptionContract {
-	currentPrice := 100.0 // Base price for mock
-	if ticker == "GOOG" {
-		currentPrice = 2800.0
-	} else if ticker == "AMZN" {
-		currentPrice = 3400.0
-	} else if ticker == "TSLA" {
-		currentPrice = 900.0
-	} else if ticker == "SPY" {
-		currentPrice = 450.0
-	}
+	return generateMockChain(ticker, daysOut, rand.Float64)
+}
+
+// generateMockChain builds the mock chain, drawing IVs from randFloat so callers can supply a seeded source
+func generateMockChain(ticker string, daysOut int, randFloat func() float64) []OptionContract {
+	currentPrice, _ := getMockQuote(ticker) // Base price for mock
 
-	r := 0.05                     // 5% risk free rate
 	T := float64(daysOut) / 365.0 // Convert days to years
+	r := rates.RateFor(T)
+	model := DefaultModel()
+	q, dividends := tickerDividendInputs(ticker, time.Now())
 
 	var chain []OptionContract
 
@@ -164,10 +163,18 @@ func GetChainForExpiry(ticker string, daysOut int) []OptionContract {
 
 	for k := startStrike; k <= endStrike; k += 5 {
 		// Randomize IV slightly between 20% and 40%
-		iv := 0.20 + rand.Float64()*0.20
+		iv := 0.20 + randFloat()*0.20
+
+		// Open interest peaks at the money; volume follows the IV draw, so the richest strikes trade
+		// up to twice their open interest
+		moneyness := (k - currentPrice) / currentPrice
+		openInterest := int64(math.Round(5000 * math.Exp(-50*moneyness*moneyness)))
+		volume := int64(math.Round(float64(openInterest) * 2 * math.Pow((iv-0.20)/0.20, 4)))
 
 		// Call
-		cPrice, cDelta, cGamma, cTheta, cVega := CalculateOptionPrice(Call, currentPrice, k, T, r, iv)
+		callIn := PricingInput{Type: Call, S: currentPrice, K: k, T: T, R: r, Sigma: iv, Q: q, Dividends: dividends}
+		cRes := model.Price(callIn)
+		cPrice, cDelta, cGamma, cTheta, cVega := cRes.Price, cRes.Delta, cRes.Gamma, cRes.Theta, cRes.Vega
 
 		// Spread logic
 		spread := cPrice * 0.02
@@ -178,23 +185,29 @@ func GetChainForExpiry(ticker string, daysOut int) []OptionContract {
 		}
 
 		callContract := OptionContract{
-			Strike:     math.Round(k*100) / 100,
-			Expiry:     time.Now().AddDate(0, 0, daysOut).Format("2006-01-02"),
-			Type:       Call,
-			Bid:        math.Round(cBid*100) / 100,
-			Ask:        math.Round(cAsk*100) / 100,
-			Last:       math.Round(cPrice*100) / 100,
-			Vol:        math.Round(iv*100) / 100,
-			Delta:      math.Round(cDelta*1000) / 1000,
-			Gamma:      math.Round(cGamma*1000) / 1000,
-			Theta:      math.Round(cTheta*1000) / 1000,
-			Vega:       math.Round(cVega*1000) / 1000,
-			Underlying: ticker,
+			Strike:       math.Round(k*100) / 100,
+			Expiry:       time.Now().AddDate(0, 0, daysOut).Format("2006-01-02"),
+			Type:         Call,
+			Bid:          math.Round(cBid*100) / 100,
+			Ask:          math.Round(cAsk*100) / 100,
+			Last:         math.Round(cPrice*100) / 100,
+			Volume:       volume,
+			OpenInterest: openInterest,
+			Vol:          math.Round(iv*100) / 100,
+			Delta:        math.Round(cDelta*1000) / 1000,
+			Gamma:        math.Round(cGamma*1000) / 1000,
+			Theta:        math.Round(cTheta*1000) / 1000,
+			Vega:         math.Round(cVega*1000) / 1000,
+			Underlying:   ticker,
+
+			EarlyExercisePremium: EarlyExercisePremium(model, callIn, cPrice),
 		}
 		chain = append(chain, callContract)
 
 		// Put
-		pPrice, pDelta, pGamma, pTheta, pVega := CalculateOptionPrice(Put, currentPrice, k, T, r, iv)
+		putIn := PricingInput{Type: Put, S: currentPrice, K: k, T: T, R: r, Sigma: iv, Q: q, Dividends: dividends}
+		pRes := model.Price(putIn)
+		pPrice, pDelta, pGamma, pTheta, pVega := pRes.Price, pRes.Delta, pRes.Gamma, pRes.Theta, pRes.Vega
 
 		pBid := pPrice - spread/2
 		pAsk := pPrice + spread/2
@@ -203,18 +216,22 @@ func GetChainForExpiry(ticker string, daysOut int) []OptionContract {
 		}
 
 		putContract := OptionContract{
-			Strike:     math.Round(k*100) / 100,
-			Expiry:     time.Now().AddDate(0, 0, daysOut).Format("2006-01-02"),
-			Type:       Put,
-			Bid:        math.Round(pBid*100) / 100,
-			Ask:        math.Round(pAsk*100) / 100,
-			Last:       math.Round(pPrice*100) / 100,
-			Vol:        math.Round(iv*100) / 100,
-			Delta:      math.Round(pDelta*1000) / 1000,
-			Gamma:      math.Round(pGamma*1000) / 1000,
-			Theta:      math.Round(pTheta*1000) / 1000,
-			Vega:       math.Round(pVega*1000) / 1000,
-			Underlying: ticker,
+			Strike:       math.Round(k*100) / 100,
+			Expiry:       time.Now().AddDate(0, 0, daysOut).Format("2006-01-02"),
+			Type:         Put,
+			Bid:          math.Round(pBid*100) / 100,
+			Ask:          math.Round(pAsk*100) / 100,
+			Last:         math.Round(pPrice*100) / 100,
+			Volume:       volume,
+			OpenInterest: openInterest,
+			Vol:          math.Round(iv*100) / 100,
+			Delta:        math.Round(pDelta*1000) / 1000,
+			Gamma:        math.Round(pGamma*1000) / 1000,
+			Theta:        math.Round(pTheta*1000) / 1000,
+			Vega:         math.Round(pVega*1000) / 1000,
+			Underlying:   ticker,
+
+			EarlyExercisePremium: EarlyExercisePremium(model, putIn, pPrice),
 		}
 		chain = append(chain, putContract)
 	}
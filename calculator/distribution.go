@@ -0,0 +1,86 @@
+package calculator
+
+import "math"
+
+// DistributionPoints is the number of price nodes in a terminal distribution
+const DistributionPoints = 400
+
+// Distribution is a discretised risk-neutral distribution of the underlying at one expiry:
+// Probs[i] is the probability of finishing in the bucket represented by Prices[i].
+type Distribution struct {
+	Prices []float64 `json:"prices"`
+	Probs  []float64 `json:"probs"`
+}
+
+// LognormalDistribution is the Black-Scholes terminal distribution with a single volatility
+func LognormalDistribution(S, T, r, q, sigma float64) Distribution {
+	return SmileDistribution(S, T, r, q, sigma, func(float64) float64 { return sigma })
+}
+
+// SmileDistribution is the terminal distribution implied by a volatility smile (Breeden-Litzenberger):
+// P(S_T > K) = -e^{rT} dC/dK, with C(K) priced at vol(K). atmVol sets the width of the price grid.
+func SmileDistribution(S, T, r, q, atmVol float64, vol func(strike float64) float64) Distribution {
+	if S <= 0 || T <= 0 || atmVol <= 0 {
+		return Distribution{Prices: []float64{S}, Probs: []float64{1}}
+	}
+
+	// Grid covers +/- 5 standard deviations in log space
+	width := 5 * atmVol * math.Sqrt(T)
+	lo, hi := S*math.Exp(-width), S*math.Exp(width)
+	step := (hi - lo) / float64(DistributionPoints)
+
+	call := func(K float64) float64 {
+		sigma := vol(K)
+		if sigma <= 0 {
+			sigma = atmVol
+		}
+		price, _, _, _, _ := CalculateOptionPriceWithYield(Call, S, K, T, r, q, sigma)
+		return price
+	}
+
+	// Probability of finishing above each grid node, forced to be non-increasing
+	above := make([]float64, DistributionPoints+1)
+	h := step / 2
+	prev := 1.0
+	for i := range above {
+		K := lo + float64(i)*step
+		p := -math.Exp(r*T) * (call(K+h) - call(math.Max(K-h, 1e-9))) / (K + h - math.Max(K-h, 1e-9))
+		p = math.Max(0, math.Min(p, prev))
+		above[i] = p
+		prev = p
+	}
+
+	// Tails are lumped onto the first and last nodes
+	d := Distribution{}
+	d.Prices = append(d.Prices, lo)
+	d.Probs = append(d.Probs, 1-above[0])
+	for i := 0; i < DistributionPoints; i++ {
+		d.Prices = append(d.Prices, lo+(float64(i)+0.5)*step)
+		d.Probs = append(d.Probs, above[i]-above[i+1])
+	}
+	d.Prices = append(d.Prices, hi)
+	d.Probs = append(d.Probs, above[DistributionPoints])
+	return d
+}
+
+// Expect returns the expected value of f(S_T)
+func (d Distribution) Expect(f func(price float64) float64) float64 {
+	ev := 0.0
+	for i, p := range d.Prices {
+		if d.Probs[i] > 0 {
+			ev += d.Probs[i] * f(p)
+		}
+	}
+	return ev
+}
+
+// Prob returns the probability that pred(S_T) holds
+func (d Distribution) Prob(pred func(price float64) bool) float64 {
+	total := 0.0
+	for i, p := range d.Prices {
+		if d.Probs[i] > 0 && pred(p) {
+			total += d.Probs[i]
+		}
+	}
+	return total
+}
@@ -0,0 +1,132 @@
+package calculator
+
+import (
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// Dividend is a scheduled cash dividend
+type Dividend struct {
+	ExDate time.Time `json:"exDate"`
+	Amount float64   `json:"amount"`
+}
+
+// DividendLookup returns the continuous dividend yield and the known cash dividend schedule for a ticker
+type DividendLookup func(ticker string) (yield float64, schedule []Dividend, err error)
+
+// DividendCacheTTL is how long a ticker's dividend data is reused before the lookup is called again
+const DividendCacheTTL = 10 * time.Minute
+
+// Projection of a cash dividend schedule past its last ex-date, see DividendInputs
+const (
+	dividendProjectionYears = 3
+	defaultDividendInterval = 3 // Months, when the schedule has a single ex-date
+)
+
+// dividendEntry is one ticker's cached lookup result
+type dividendEntry struct {
+	yield    float64
+	schedule []Dividend
+	loadedAt time.Time
+}
+
+var (
+	dividendMu     sync.RWMutex
+	dividendLookup DividendLookup
+	dividendCache  = map[string]dividendEntry{}
+)
+
+// SetDividendLookup installs the source of per-ticker dividend data (e.g. the SQLite store)
+func SetDividendLookup(fn DividendLookup) {
+	dividendMu.Lock()
+	defer dividendMu.Unlock()
+	dividendLookup = fn
+	dividendCache = map[string]dividendEntry{}
+}
+
+// InvalidateDividends drops a ticker's cached dividend data, e.g. after its stored schedule changes
+func InvalidateDividends(ticker string) {
+	dividendMu.Lock()
+	defer dividendMu.Unlock()
+	delete(dividendCache, ticker)
+}
+
+// LookupDividends returns the dividend data for a ticker, or nothing if no source is configured.
+// Results are cached per ticker for DividendCacheTTL; errors are not cached.
+func LookupDividends(ticker string) (float64, []Dividend) {
+	dividendMu.RLock()
+	fn := dividendLookup
+	entry, ok := dividendCache[ticker]
+	dividendMu.RUnlock()
+
+	if fn == nil {
+		return 0, nil
+	}
+	if ok && time.Since(entry.loadedAt) < DividendCacheTTL {
+		return entry.yield, entry.schedule
+	}
+	yield, schedule, err := fn(ticker)
+	if err != nil {
+		log.Printf("Error loading dividends for %s: %v", ticker, err)
+		return 0, nil
+	}
+
+	dividendMu.Lock()
+	dividendCache[ticker] = dividendEntry{yield: yield, schedule: schedule, loadedAt: time.Now()}
+	dividendMu.Unlock()
+	return yield, schedule
+}
+
+// DividendInputs converts dividend data into pricing inputs relative to asOf.
+// A cash schedule takes precedence over the continuous yield, so the same dividend is never counted twice.
+// Past its last ex-date the schedule is projected forward, repeating the last amount at the interval
+// between the last two ex-dates (quarterly for a single one), so long-dated options still see dividends.
+// The yield is only used when no cash dividends are known.
+func DividendInputs(yield float64, schedule []Dividend, asOf time.Time) (q float64, cash []CashDividend) {
+	var last, prev time.Time
+	lastAmount := 0.0
+	for _, d := range schedule {
+		if d.Amount <= 0 {
+			continue
+		}
+		if t := d.ExDate.Sub(asOf).Hours() / 24 / 365.0; t > 0 {
+			cash = append(cash, CashDividend{T: t, Amount: d.Amount})
+		}
+		switch {
+		case d.ExDate.After(last):
+			prev, last, lastAmount = last, d.ExDate, d.Amount
+		case d.ExDate.After(prev) && d.ExDate.Before(last):
+			prev = d.ExDate
+		}
+	}
+	if lastAmount == 0 {
+		return yield, nil
+	}
+
+	months := defaultDividendInterval
+	if !prev.IsZero() {
+		// Whole months between the last two, ignoring specials paid close together
+		if m := int(math.Round(last.Sub(prev).Hours() / 24 / 30.44)); m >= 1 && m <= 12 {
+			months = m
+		}
+	}
+	horizon := asOf.AddDate(dividendProjectionYears, 0, 0)
+	for i := 1; ; i++ {
+		ex := last.AddDate(0, months*i, 0)
+		if ex.After(horizon) {
+			break
+		}
+		if t := ex.Sub(asOf).Hours() / 24 / 365.0; t > 0 {
+			cash = append(cash, CashDividend{T: t, Amount: lastAmount})
+		}
+	}
+	return 0, cash
+}
+
+// tickerDividendInputs looks up a ticker's dividends and converts them relative to asOf
+func tickerDividendInputs(ticker string, asOf time.Time) (float64, []CashDividend) {
+	yield, schedule := LookupDividends(ticker)
+	return DividendInputs(yield, schedule, asOf)
+}
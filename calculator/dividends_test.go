@@ -0,0 +1,88 @@
+package calculator
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDividendInputsYieldOnly(t *testing.T) {
+	q, cash := DividendInputs(0.02, nil, time.Now())
+	if q != 0.02 || cash != nil {
+		t.Errorf("yield only gave q=%v cash=%v", q, cash)
+	}
+}
+
+func TestDividendInputsProjectsSchedule(t *testing.T) {
+	asOf := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedule := []Dividend{
+		{ExDate: time.Date(2030, 2, 10, 0, 0, 0, 0, time.UTC), Amount: 0.25},
+		{ExDate: time.Date(2029, 11, 10, 0, 0, 0, 0, time.UTC), Amount: 0.24}, // Paid, but sets the cadence
+	}
+	q, cash := DividendInputs(0.02, schedule, asOf)
+	if q != 0 {
+		t.Errorf("q = %v, the yield must not be counted on top of cash dividends", q)
+	}
+
+	// 2030-02-10 plus quarterly projections of 0.25 to three years out
+	if len(cash) != 12 {
+		t.Fatalf("%d dividends, want 12: %v", len(cash), cash)
+	}
+	for i, d := range cash {
+		if d.Amount != 0.25 {
+			t.Errorf("dividend %d = %v, want 0.25", i, d.Amount)
+		}
+		if i > 0 && math.Abs((d.T-cash[i-1].T)*12-3) > 0.1 {
+			t.Errorf("dividends %d and %d are %.2f years apart, want a quarter", i-1, i, d.T-cash[i-1].T)
+		}
+	}
+
+	// A two-year option now sees eight dividends rather than one
+	in := PricingInput{Type: Call, S: 100, K: 100, T: 2, R: 0.04, Sigma: 0.25, Dividends: cash}
+	if pv := in.pvDividends(0); pv < 1.8 || pv > 2 {
+		t.Errorf("PV of dividends over two years = %.2f, want about 8 x 0.25 discounted", pv)
+	}
+}
+
+func TestDividendInputsSingleAndMonthly(t *testing.T) {
+	asOf := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// A lone ex-date is assumed quarterly, even once it has passed
+	_, cash := DividendInputs(0, []Dividend{{ExDate: time.Date(2029, 12, 15, 0, 0, 0, 0, time.UTC), Amount: 1}}, asOf)
+	if len(cash) != 12 || math.Abs(cash[0].T*365-(31+28+15)) > 1 {
+		t.Errorf("lone ex-date projected to %d dividends, first at %.0f days", len(cash), cash[0].T*365)
+	}
+
+	monthly := []Dividend{
+		{ExDate: time.Date(2030, 1, 15, 0, 0, 0, 0, time.UTC), Amount: 0.1},
+		{ExDate: time.Date(2030, 2, 15, 0, 0, 0, 0, time.UTC), Amount: 0.1},
+	}
+	if _, cash := DividendInputs(0, monthly, asOf); len(cash) != 36 {
+		t.Errorf("monthly schedule projected to %d dividends, want 36", len(cash))
+	}
+}
+
+func TestLookupDividendsCaches(t *testing.T) {
+	defer SetDividendLookup(nil)
+
+	calls := 0
+	SetDividendLookup(func(ticker string) (float64, []Dividend, error) {
+		calls++
+		return 0.01, nil, nil
+	})
+	for i := 0; i < 3; i++ {
+		if yield, _ := LookupDividends("XYZ"); yield != 0.01 {
+			t.Fatalf("yield = %v", yield)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("lookup called %d times, want 1", calls)
+	}
+
+	InvalidateDividends("XYZ")
+	LookupDividends("XYZ")
+	LookupDividends("ABC")
+	if calls != 3 {
+		t.Errorf("lookup called %d times after invalidating, want 3", calls)
+	}
+}
@@ -0,0 +1,99 @@
+package calculator
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Limits for GetFullChain, keeping a full fetch within Yahoo's rate limits
+const (
+	FullChainWorkers         = 4                      // Expirations fetched in parallel
+	FullChainRequestInterval = 250 * time.Millisecond // Minimum gap between chain requests across all workers
+)
+
+// GetFullChain fetches every expiration between minDTE and maxDTE days out and merges them, ordered by expiry.
+// If the configured provider fails, the fallback provider (if any) is used instead.
+func GetFullChain(ticker string, minDTE, maxDTE int) ([]OptionContract, error) {
+	primary, fallback := Providers()
+
+	chain, err := GetFullChainFrom(primary, ticker, minDTE, maxDTE)
+	if err != nil && fallback != nil {
+		log.Printf("Error fetching full chain for %s: %v. Falling back to %T.", ticker, err, fallback)
+		return GetFullChainFrom(fallback, ticker, minDTE, maxDTE)
+	}
+	return chain, err
+}
+
+// GetFullChainFrom is GetFullChain against a single provider.
+// Expirations that fail are logged and skipped; an error is returned only if none could be fetched.
+func GetFullChainFrom(provider MarketDataProvider, ticker string, minDTE, maxDTE int) ([]OptionContract, error) {
+	expirations, err := provider.Expirations(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, minDTE).Truncate(24 * time.Hour).Unix()
+	to := now.AddDate(0, 0, maxDTE).Unix()
+
+	var window []int64
+	for _, expiry := range expirations {
+		if expiry >= from && expiry <= to {
+			window = append(window, expiry)
+		}
+	}
+	if len(window) == 0 {
+		return nil, fmt.Errorf("no expirations for %s between %d and %d days out", ticker, minDTE, maxDTE)
+	}
+	sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+
+	type result struct {
+		chain []OptionContract
+		err   error
+	}
+	results := make([]result, len(window))
+
+	jobs := make(chan int)
+	limiter := time.NewTicker(FullChainRequestInterval)
+	defer limiter.Stop()
+
+	var wg sync.WaitGroup
+	for w := 0; w < FullChainWorkers && w < len(window); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				<-limiter.C
+				chain, err := provider.Chain(ticker, window[i])
+				results[i] = result{chain, err}
+			}
+		}()
+	}
+	for i := range window {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var merged []OptionContract
+	var firstErr error
+	for i, res := range results {
+		if res.err != nil {
+			log.Printf("Skipping %s expiry %d: %v", ticker, window[i], res.err)
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			continue
+		}
+		merged = append(merged, res.chain...)
+	}
+	if len(merged) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+
+	log.Printf("Full chain for %s: %d expirations, %d contracts", ticker, len(window), len(merged))
+	return merged, nil
+}
@@ -0,0 +1,313 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// IVStatus explains why an implied volatility could not be found
+type IVStatus string
+
+const (
+	IVInvalidInput    IVStatus = "invalid_input"     // Non-positive price, spot, strike or time
+	IVBelowIntrinsic  IVStatus = "below_intrinsic"   // Price is under the no-arbitrage lower bound
+	IVAboveUpperBound IVStatus = "above_upper_bound" // Price is over the no-arbitrage upper bound
+	IVNoConvergence   IVStatus = "no_convergence"    // Price is within bounds but no vol in range reproduces it
+)
+
+// IV search range
+const (
+	MinIV = 0.001
+	MaxIV = 5.0
+)
+
+// IVError is returned by SolveIV when no volatility reproduces the price
+type IVError struct {
+	Status IVStatus
+	Price  float64
+	Lower  float64 // No-arbitrage lower bound for the price
+	Upper  float64 // No-arbitrage upper bound for the price
+}
+
+func (e *IVError) Error() string {
+	return fmt.Sprintf("implied volatility: %s (price %.4f, bounds %.4f-%.4f)", e.Status, e.Price, e.Lower, e.Upper)
+}
+
+// Sources recorded in OptionContract.IVSource
+const (
+	IVSourceMid      = "mid"      // Solved from the bid/ask mid
+	IVSourceParity   = "parity"   // Solved from the OTM side against the put-call parity forward
+	IVSourceLast     = "last"     // Solved from the last trade (no two-sided quote)
+	IVSourceNeighbor = "neighbor" // Copied from the nearest strike that solved
+	IVSourceVendor   = "vendor"   // Data vendor's figure, used only when nothing else worked
+	IVSourceDefault  = "default"  // DefaultIV; nothing in the expiry could be solved
+)
+
+// DefaultIV is the last-resort volatility when an expiry has no usable quotes at all
+const DefaultIV = 0.5
+
+// priceBounds returns the European no-arbitrage bounds for an option price
+func priceBounds(optType OptionType, S, K, T, r, q float64) (lower, upper float64) {
+	fwdS := S * math.Exp(-q*T)
+	pvK := K * math.Exp(-r*T)
+	if optType == Call {
+		return math.Max(0, fwdS-pvK), fwdS
+	}
+	return math.Max(0, pvK-fwdS), pvK
+}
+
+// SolveIV finds the volatility that reproduces marketPrice under Black-Scholes-Merton.
+// Newton-Raphson is tried first; if it leaves the search range or stalls, Brent's method
+// takes over on the bracket [MinIV, MaxIV]. Returns an *IVError when no solution exists.
+func SolveIV(marketPrice float64, optType OptionType, S, K, T, r, q float64) (float64, error) {
+	if marketPrice <= 0 || S <= 0 || K <= 0 || T <= 0 {
+		return 0, &IVError{Status: IVInvalidInput, Price: marketPrice}
+	}
+
+	lower, upper := priceBounds(optType, S, K, T, r, q)
+	if marketPrice < lower-1e-8 {
+		return 0, &IVError{Status: IVBelowIntrinsic, Price: marketPrice, Lower: lower, Upper: upper}
+	}
+	if marketPrice >= upper {
+		return 0, &IVError{Status: IVAboveUpperBound, Price: marketPrice, Lower: lower, Upper: upper}
+	}
+
+	f := func(sigma float64) float64 {
+		price, _, _, _, _ := CalculateOptionPriceWithYield(optType, S, K, T, r, q, sigma)
+		return price - marketPrice
+	}
+
+	fLo, fHi := f(MinIV), f(MaxIV)
+	if fLo > 0 || fHi < 0 {
+		return 0, &IVError{Status: IVNoConvergence, Price: marketPrice, Lower: lower, Upper: upper}
+	}
+
+	if sigma, ok := newtonIV(f, optType, S, K, T, r, q); ok {
+		return sigma, nil
+	}
+	if sigma, ok := brent(f, MinIV, MaxIV, fLo, fHi); ok {
+		return sigma, nil
+	}
+	return 0, &IVError{Status: IVNoConvergence, Price: marketPrice, Lower: lower, Upper: upper}
+}
+
+// newtonIV runs a bounded Newton-Raphson from the Brenner-Subrahmanyam ATM guess
+func newtonIV(f func(float64) float64, optType OptionType, S, K, T, r, q float64) (float64, bool) {
+	sigma := math.Sqrt(2*math.Abs(math.Log(S/K)+(r-q)*T)/T) + 0.1
+	sigma = math.Min(math.Max(sigma, 0.05), 2)
+
+	for i := 0; i < 20; i++ {
+		diff := f(sigma)
+		if math.Abs(diff) < 1e-6 {
+			return sigma, true
+		}
+
+		_, _, _, _, vega := CalculateOptionPriceWithYield(optType, S, K, T, r, q, sigma)
+		rawVega := vega * 100 // CalculateOptionPriceWithYield scales vega per 1 vol point
+		if rawVega < 1e-8 {
+			return 0, false
+		}
+
+		sigma -= diff / rawVega
+		if sigma < MinIV || sigma > MaxIV || math.IsNaN(sigma) {
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// brent finds a root of f in [a, b] given f(a) and f(b) of opposite sign
+func brent(f func(float64) float64, a, b, fa, fb float64) (float64, bool) {
+	const tol = 1e-8
+	if math.Abs(fa) < math.Abs(fb) {
+		a, b, fa, fb = b, a, fb, fa
+	}
+	c, fc := a, fa
+	d := b - a
+	mflag := true
+
+	for i := 0; i < 100; i++ {
+		if math.Abs(fb) < 1e-8 || math.Abs(b-a) < tol {
+			return b, true
+		}
+
+		var s float64
+		if fa != fc && fb != fc {
+			// Inverse quadratic interpolation
+			s = a*fb*fc/((fa-fb)*(fa-fc)) + b*fa*fc/((fb-fa)*(fb-fc)) + c*fa*fb/((fc-fa)*(fc-fb))
+		} else {
+			// Secant
+			s = b - fb*(b-a)/(fb-fa)
+		}
+
+		// Fall back to bisection when interpolation is not making progress
+		lo, hi := (3*a+b)/4, b
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if s < lo || s > hi ||
+			(mflag && math.Abs(s-b) >= math.Abs(b-c)/2) ||
+			(!mflag && math.Abs(s-b) >= math.Abs(c-d)/2) {
+			s = (a + b) / 2
+			mflag = true
+		} else {
+			mflag = false
+		}
+
+		fs := f(s)
+		d, c, fc = c, b, fb
+		if fa*fs < 0 {
+			b, fb = s, fs
+		} else {
+			a, fa = s, fs
+		}
+		if math.Abs(fa) < math.Abs(fb) {
+			a, b, fa, fb = b, a, fb, fa
+		}
+	}
+	return b, math.Abs(fb) < 1e-6
+}
+
+// ParityIV infers the forward from a call/put pair at the same strike (C - P = (F - K)e^(-rT)),
+// then solves the out-of-the-money side against that forward. Because the forward already reflects
+// dividends and borrow, no dividend input is needed.
+func ParityIV(callMid, putMid, K, T, r float64) (iv float64, forward float64, err error) {
+	if callMid <= 0 || putMid <= 0 || T <= 0 {
+		return 0, 0, &IVError{Status: IVInvalidInput, Price: math.Min(callMid, putMid)}
+	}
+
+	forward = K + (callMid-putMid)*math.Exp(r*T)
+	if forward <= 0 {
+		return 0, 0, &IVError{Status: IVInvalidInput, Price: callMid}
+	}
+
+	// Black-76 is Black-Scholes on the discounted forward with no yield
+	spot := forward * math.Exp(-r*T)
+	if K >= forward {
+		iv, err = SolveIV(callMid, Call, spot, K, T, r, 0)
+	} else {
+		iv, err = SolveIV(putMid, Put, spot, K, T, r, 0)
+	}
+	return iv, forward, err
+}
+
+// ivQuote is the price information for one contract that IV solving works from
+type ivQuote struct {
+	Type   OptionType
+	Strike float64
+	Bid    float64
+	Ask    float64
+	Last   float64
+	Vendor float64
+}
+
+func (q ivQuote) mid() (float64, string) {
+	if q.Bid > 0 && q.Ask > 0 && q.Ask >= q.Bid {
+		return (q.Bid + q.Ask) / 2, IVSourceMid
+	}
+	if q.Last > 0 {
+		return q.Last, IVSourceLast
+	}
+	return 0, ""
+}
+
+type ivKey struct {
+	Type   OptionType
+	Strike float64
+}
+
+type ivResult struct {
+	IV     float64
+	Source string
+}
+
+// solveChainIVs recomputes IV for every contract of a single expiry.
+// Strikes quoted on both sides use put-call parity; the rest are solved from their own mid
+// (or last). Anything that still fails borrows the nearest solved strike of the same type,
+// and only then the vendor's figure.
+func solveChainIVs(quotes []ivQuote, S, T, r, q float64, dividends []CashDividend) map[ivKey]ivResult {
+	results := make(map[ivKey]ivResult)
+
+	byStrike := make(map[float64]map[OptionType]ivQuote)
+	for _, c := range quotes {
+		if byStrike[c.Strike] == nil {
+			byStrike[c.Strike] = make(map[OptionType]ivQuote)
+		}
+		byStrike[c.Strike][c.Type] = c
+	}
+
+	escrowed := PricingInput{S: S, T: T, R: r, Dividends: dividends}.escrowedSpot()
+
+	for strike, sides := range byStrike {
+		call, hasCall := sides[Call]
+		put, hasPut := sides[Put]
+
+		if hasCall && hasPut {
+			callMid, callSrc := call.mid()
+			putMid, putSrc := put.mid()
+			if callSrc == IVSourceMid && putSrc == IVSourceMid {
+				if iv, _, err := ParityIV(callMid, putMid, strike, T, r); err == nil {
+					results[ivKey{Call, strike}] = ivResult{iv, IVSourceParity}
+					results[ivKey{Put, strike}] = ivResult{iv, IVSourceParity}
+					continue
+				}
+			}
+		}
+
+		for _, c := range sides {
+			price, src := c.mid()
+			if src == "" {
+				continue
+			}
+			if iv, err := SolveIV(price, c.Type, escrowed, strike, T, r, q); err == nil {
+				results[ivKey{c.Type, strike}] = ivResult{iv, src}
+			}
+		}
+	}
+
+	// Fill the gaps from the nearest solved strike of the same type
+	for _, optType := range []OptionType{Call, Put} {
+		var solved []float64
+		for k := range results {
+			if k.Type == optType {
+				solved = append(solved, k.Strike)
+			}
+		}
+		sort.Float64s(solved)
+
+		for _, c := range quotes {
+			key := ivKey{c.Type, c.Strike}
+			if c.Type != optType {
+				continue
+			}
+			if _, ok := results[key]; ok {
+				continue
+			}
+			if nearest, ok := nearestStrike(solved, c.Strike); ok {
+				results[key] = ivResult{results[ivKey{optType, nearest}].IV, IVSourceNeighbor}
+			} else if c.Vendor >= MinIV && c.Vendor <= MaxIV {
+				results[key] = ivResult{c.Vendor, IVSourceVendor}
+			}
+		}
+	}
+
+	return results
+}
+
+func nearestStrike(sorted []float64, strike float64) (float64, bool) {
+	if len(sorted) == 0 {
+		return 0, false
+	}
+	i := sort.SearchFloat64s(sorted, strike)
+	if i == 0 {
+		return sorted[0], true
+	}
+	if i == len(sorted) {
+		return sorted[len(sorted)-1], true
+	}
+	if strike-sorted[i-1] <= sorted[i]-strike {
+		return sorted[i-1], true
+	}
+	return sorted[i], true
+}
@@ -0,0 +1,143 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSolveIVRoundTrip(t *testing.T) {
+	for _, optType := range []OptionType{Call, Put} {
+		for _, K := range []float64{60, 90, 100, 110, 160} {
+			for _, T := range []float64{0.02, 0.25, 1, 2} {
+				for _, sigma := range []float64{0.05, 0.2, 0.6, 2} {
+					for _, q := range []float64{0, 0.03} {
+						price, _, _, _, vega := CalculateOptionPriceWithYield(optType, 100, K, T, 0.04, q, sigma)
+						// Far out of the money the price carries no information about vol
+						if vega < 1e-4 {
+							continue
+						}
+
+						iv, err := SolveIV(price, optType, 100, K, T, 0.04, q)
+						if err != nil {
+							t.Errorf("%s K=%v T=%v sigma=%v q=%v: %v", optType, K, T, sigma, q, err)
+							continue
+						}
+						if math.Abs(iv-sigma) > 1e-4 {
+							t.Errorf("%s K=%v T=%v sigma=%v q=%v: solved %.6f", optType, K, T, sigma, q, iv)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSolveIVErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		price  float64
+		typ    OptionType
+		K, T   float64
+		status IVStatus
+	}{
+		{"zero price", 0, Call, 100, 0.5, IVInvalidInput},
+		{"expired", 5, Call, 100, 0, IVInvalidInput},
+		{"call under intrinsic", 15, Call, 80, 0.5, IVBelowIntrinsic},
+		{"put under intrinsic", 15, Put, 120, 0.5, IVBelowIntrinsic},
+		{"call over spot", 101, Call, 100, 0.5, IVAboveUpperBound},
+		{"put over strike", 100, Put, 100, 0.5, IVAboveUpperBound},
+	}
+	for _, tt := range tests {
+		_, err := SolveIV(tt.price, tt.typ, 100, tt.K, tt.T, 0.04, 0)
+		var ivErr *IVError
+		if !errors.As(err, &ivErr) {
+			t.Errorf("%s: expected an *IVError, got %v", tt.name, err)
+			continue
+		}
+		if ivErr.Status != tt.status {
+			t.Errorf("%s: status %s, want %s", tt.name, ivErr.Status, tt.status)
+		}
+	}
+
+	if iv := CalculateIV(15, Call, 100, 80, 0.5, 0.04); iv != 0 {
+		t.Errorf("CalculateIV below intrinsic = %v, want 0", iv)
+	}
+}
+
+func TestParityIV(t *testing.T) {
+	// Dividend yield the solver is not told about: the parity forward has to absorb it
+	const S, r, q, sigma, T = 100.0, 0.04, 0.03, 0.3, 0.5
+	wantForward := S * math.Exp((r-q)*T)
+
+	for _, K := range []float64{85, 100, 115} {
+		call, _, _, _, _ := CalculateOptionPriceWithYield(Call, S, K, T, r, q, sigma)
+		put, _, _, _, _ := CalculateOptionPriceWithYield(Put, S, K, T, r, q, sigma)
+
+		iv, forward, err := ParityIV(call, put, K, T, r)
+		if err != nil {
+			t.Fatalf("K=%v: %v", K, err)
+		}
+		if math.Abs(forward-wantForward) > 1e-6 {
+			t.Errorf("K=%v: forward %.6f, want %.6f", K, forward, wantForward)
+		}
+		if math.Abs(iv-sigma) > 1e-4 {
+			t.Errorf("K=%v: IV %.6f, want %.6f", K, iv, sigma)
+		}
+	}
+}
+
+func TestBrent(t *testing.T) {
+	f := func(x float64) float64 { return x*x*x - 2*x - 5 }
+	root, ok := brent(f, 2, 3, f(2), f(3))
+	if !ok || math.Abs(root-2.0945514815) > 1e-7 {
+		t.Errorf("brent = %v, %v; want 2.0945514815", root, ok)
+	}
+}
+
+func TestSolveChainIVsSources(t *testing.T) {
+	const S, T, r, sigma = 100.0, 0.25, 0.04, 0.25
+	price := func(typ OptionType, K float64) float64 {
+		p, _, _, _, _ := CalculateOptionPriceWithYield(typ, S, K, T, r, 0, sigma)
+		return p
+	}
+
+	quotes := []ivQuote{
+		// Two-sided on both sides: put-call parity
+		{Type: Call, Strike: 100, Bid: price(Call, 100), Ask: price(Call, 100)},
+		{Type: Put, Strike: 100, Bid: price(Put, 100), Ask: price(Put, 100)},
+		// Call only, last trade
+		{Type: Call, Strike: 105, Last: price(Call, 105)},
+		// No usable price: borrows the nearest solved call
+		{Type: Call, Strike: 110},
+		// Put with nothing solved on its side but 100: neighbor too
+		{Type: Put, Strike: 90},
+	}
+	ivs := solveChainIVs(quotes, S, T, r, 0, nil)
+
+	want := map[ivKey]string{
+		{Call, 100}: IVSourceParity,
+		{Put, 100}:  IVSourceParity,
+		{Call, 105}: IVSourceLast,
+		{Call, 110}: IVSourceNeighbor,
+		{Put, 90}:   IVSourceNeighbor,
+	}
+	for key, src := range want {
+		got, ok := ivs[key]
+		if !ok {
+			t.Errorf("%s %.0f: not solved", key.Type, key.Strike)
+			continue
+		}
+		if got.Source != src {
+			t.Errorf("%s %.0f: source %s, want %s", key.Type, key.Strike, got.Source, src)
+		}
+		if math.Abs(got.IV-sigma) > 1e-3 {
+			t.Errorf("%s %.0f: IV %.4f, want %.4f", key.Type, key.Strike, got.IV, sigma)
+		}
+	}
+
+	vendorOnly := solveChainIVs([]ivQuote{{Type: Call, Strike: 100, Vendor: 0.4}}, S, T, r, 0, nil)
+	if got := vendorOnly[ivKey{Call, 100}]; got.Source != IVSourceVendor || got.IV != 0.4 {
+		t.Errorf("vendor fallback = %+v", got)
+	}
+}
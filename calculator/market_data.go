@@ -8,6 +8,7 @@ import (
 	"math"
 	"net/http"
 	"net/http/cookiejar"
+	"strikelogic/rates"
 	"strings"
 	"sync"
 	"time"
@@ -42,6 +43,8 @@ type YahooOptionContract struct {
 	Expiration        int64   `json:"expiration"`
 	ImpliedVolatility float64 `json:"impliedVolatility"`
 	InTheMoney        bool    `json:"inTheMoney"`
+	Volume            int64   `json:"volume"`
+	OpenInterest      int64   `json:"openInterest"`
 }
 
 // Global Yahoo Session Variables
@@ -104,18 +107,17 @@ func ensureSession() {
 	clientOnce.Do(InitYahooSession)
 }
 
-// GetQuote fetches the current price for a ticker using the shared Yahoo session.
+// GetQuote fetches the current price for a ticker from the configured market data provider,
+// falling back to the fallback provider if one is configured.
 func GetQuote(ticker string) (float64, error) {
-	// Reusing fetchYahooOptions to get the quote as it includes it in the response.
-	// This avoids maintaining a separate Quote struct/request logic for now,
-	// and ensures we use the authenticated client.
-	metaChain, err := fetchYahooOptions(ticker, 0)
-	if err != nil || len(metaChain.OptionChain.Result) == 0 {
-		log.Printf("Error fetching quote for %s: %v. Using mock data.", ticker, err)
-		return getMockQuote(ticker)
-	}
+	primary, fallback := Providers()
 
-	return metaChain.OptionChain.Result[0].Quote.RegularMarketPrice, nil
+	price, err := primary.Quote(ticker)
+	if err != nil && fallback != nil {
+		log.Printf("Error fetching quote for %s: %v. Using fallback provider.", ticker, err)
+		return fallback.Quote(ticker)
+	}
+	return price, err
 }
 
 func getMockQuote(ticker string) (float64, error) {
@@ -139,7 +141,20 @@ func getMockQuote(ticker string) (float64, error) {
 }
 
 // GetOptionsChain fetches the option chain for a ticker, targeting a specific date if provided.
+// If the configured provider fails, the fallback provider (if any) is used instead.
 func GetOptionsChain(ticker string, targetDateStr string) ([]OptionContract, error) {
+	primary, fallback := Providers()
+
+	chain, err := GetOptionsChainFrom(primary, ticker, targetDateStr)
+	if err != nil && fallback != nil {
+		log.Printf("Error fetching chain for %s: %v. Falling back to %T.", ticker, err, fallback)
+		return GetOptionsChainFrom(fallback, ticker, targetDateStr)
+	}
+	return chain, err
+}
+
+// GetOptionsChainFrom is GetOptionsChain against a single provider
+func GetOptionsChainFrom(provider MarketDataProvider, ticker string, targetDateStr string) ([]OptionContract, error) {
 	// Parse target date
 	var targetDate time.Time
 	if targetDateStr != "" {
@@ -150,83 +165,26 @@ func GetOptionsChain(ticker string, targetDateStr string) ([]OptionContract, err
 	}
 
 	// Step 1: Fetch Meta-Data (The Menu)
-	// Make an initial request to https://query2.finance.yahoo.com/v7/finance/options/{ticker}.
-	// Do not look at the calls or puts yet.
-	metaChain, err := fetchYahooOptions(ticker, 0)
+	expirationDates, err := provider.Expirations(ticker)
 	if err != nil {
-		log.Printf("Error fetching metadata: %v. Falling back to mock.", err)
-		return GetMockChain(ticker), nil
-	}
-
-	// Safety check
-	if len(metaChain.OptionChain.Result) == 0 {
-		return GetMockChain(ticker), nil
+		return nil, err
 	}
 
-	result := metaChain.OptionChain.Result[0]
-	expirationDates := result.ExpirationDates
-
 	// Step 2: The Timestamp Matcher
-	// Iterate through expirationDates. Find the timestamp that is closest to the user's targetDate.
-	var matchTimestamp int64
-
-	if !targetDate.IsZero() {
-		minDiff := math.MaxFloat64
-		for _, ts := range expirationDates {
-			expiryTime := time.Unix(ts, 0)
-			// Compare dates (truncate time by using math.Abs of hours diff)
-			diff := math.Abs(expiryTime.Sub(targetDate).Hours())
-
-			if diff < minDiff {
-				minDiff = diff
-				matchTimestamp = ts
-			}
-		}
-
-		if matchTimestamp == 0 && len(expirationDates) > 0 {
-			matchTimestamp = expirationDates[0]
-		}
-	} else {
-		// Default to first available if no target is specified
-		if len(expirationDates) > 0 {
-			matchTimestamp = expirationDates[0]
-		}
-	}
-
+	// Find the timestamp that is closest to the user's targetDate (or the first one if none given).
+	matchTimestamp := closestExpiration(expirationDates, targetDate)
 	if matchTimestamp == 0 {
-		log.Println("No expiration dates found in metadata.")
-		return GetMockChain(ticker), nil
+		return nil, fmt.Errorf("no expiration dates found for %s", ticker)
 	}
 
 	// Step 3: The Targeted Fetch (The Order)
-	// Make a SECOND request to the API: https://query2.finance.yahoo.com/v7/finance/options/{ticker}?date={MATCHED_TIMESTAMP}.
-	finalChainData, err := fetchYahooOptions(ticker, matchTimestamp)
+	chain, err := provider.Chain(ticker, matchTimestamp)
 	if err != nil {
-		log.Printf("Error fetching targeted chain: %v", err)
 		return nil, err
 	}
 
-	if len(finalChainData.OptionChain.Result) == 0 || len(finalChainData.OptionChain.Result[0].Options) == 0 {
-		return nil, fmt.Errorf("no options data found for timestamp %d", matchTimestamp)
-	}
-
-	data := finalChainData.OptionChain.Result[0]
-	optData := data.Options[0] // contracts are here
-	contractsCount := len(optData.Calls) + len(optData.Puts)
-
 	// Step 4: Debug Log
-	log.Printf("Target: %s | Found Timestamp: %d | Contracts Fetched: %d", targetDateStr, matchTimestamp, contractsCount)
-
-	// Convert to internal format
-	currentPrice := data.Quote.RegularMarketPrice
-	var chain []OptionContract
-
-	for _, call := range optData.Calls {
-		chain = append(chain, convertYahooToContract(call, currentPrice, Call, ticker))
-	}
-	for _, put := range optData.Puts {
-		chain = append(chain, convertYahooToContract(put, currentPrice, Put, ticker))
-	}
+	log.Printf("Target: %s | Found Timestamp: %d | Contracts Fetched: %d", targetDateStr, matchTimestamp, len(chain))
 
 	return chain, nil
 }
@@ -271,56 +229,70 @@ func fetchYahooOptions(ticker string, date int64) (YahooOptionsResponse, error)
 		return YahooOptionsResponse{}, fmt.Errorf("yahoo api returned status: %s", resp.Status)
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return YahooOptionsResponse{}, err
+	}
+
 	var res YahooOptionsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+	if err := json.Unmarshal(body, &res); err != nil {
 		return YahooOptionsResponse{}, err
 	}
 
+	// Keep the raw payload so replays see every field Yahoo sent, not just the ones we decode today
+	recordSnapshot(ticker, date, body)
+
 	return res, nil
 }
 
-func convertYahooToContract(c YahooOptionContract, currentPrice float64, optType OptionType, ticker string) OptionContract {
+// convertYahooToContract maps a Yahoo contract to our format, computing greeks as of asOf with the resolved IV
+func convertYahooToContract(c YahooOptionContract, currentPrice float64, optType OptionType, ticker string, asOf time.Time, q float64, dividends []CashDividend, iv ivResult) OptionContract {
 	// Convert Expiration (Unix timestamp) to string
 	expiryTime := time.Unix(c.Expiration, 0)
 	expiryStr := expiryTime.Format("2006-01-02")
+	T := yearsToExpiry(expiryTime, asOf)
 
-	// Calculate time to expiry in years
-	daysUntil := time.Until(expiryTime).Hours() / 24
-	T := daysUntil / 365.0
-	if T < 0.001 {
-		T = 0.001
-	}
+	// Calculate Greeks using the configured pricing model
+	r := rates.RateFor(T)
+	model := DefaultModel()
+	in := PricingInput{Type: optType, S: currentPrice, K: c.Strike, T: T, R: r, Sigma: iv.IV, Q: q, Dividends: dividends}
+	res := model.Price(in)
 
-	iv := c.ImpliedVolatility
-	if iv == 0 {
-		iv = 0.5 // Fallback
+	return OptionContract{
+		Strike:       c.Strike,
+		Expiry:       expiryStr,
+		Type:         optType,
+		Bid:          c.Bid,
+		Ask:          c.Ask,
+		Last:         c.LastPrice,
+		Volume:       c.Volume,
+		OpenInterest: c.OpenInterest,
+		Vol:          iv.IV,
+		IVSource:     iv.Source,
+		Delta:        math.Round(res.Delta*1000) / 1000,
+		Gamma:        math.Round(res.Gamma*1000) / 1000,
+		Theta:        math.Round(res.Theta*1000) / 1000,
+		Vega:         math.Round(res.Vega*1000) / 1000,
+		Underlying:   ticker,
+
+		EarlyExercisePremium: EarlyExercisePremium(model, in, res.Price),
 	}
+}
 
-	// Calculate Greeks using Black-Scholes
-	r := 0.05
-	_, delta, gamma, theta := BlackScholes(currentPrice, c.Strike, T, r, iv)
-
-	return OptionContract{
-		Strike:     c.Strike,
-		Expiry:     expiryStr,
-		Type:       optType,
-		Bid:        c.Bid,
-		Ask:        c.Ask,
-		Last:       c.LastPrice,
-		Vol:        iv,
-		Delta:      math.Round(delta*1000) / 1000,
-		Gamma:      math.Round(gamma*1000) / 1000,
-		Theta:      math.Round(theta*1000) / 1000,
-		Underlying: ticker,
+// yearsToExpiry returns the time from asOf to expiry in years, floored so pricing never sees T <= 0
+func yearsToExpiry(expiry, asOf time.Time) float64 {
+	T := expiry.Sub(asOf).Hours() / 24 / 365.0
+	if T < 0.001 {
+		T = 0.001
 	}
+	return T
 }
 
 // GetMockChain returns a consolidated mock chain for multiple expiries
 func GetMockChain(ticker string) []OptionContract {
 	var fullChain []OptionContract
 	// Generate for a few key expiries to simulate a full chain
-	expiries := []int{7, 14, 30, 60, 90, 180, 365}
-	for _, days := range expiries {
+	for _, days := range mockExpiryDays {
 		fullChain = append(fullChain, GetChainForExpiry(ticker, days)...)
 	}
 	return fullChain
@@ -0,0 +1,125 @@
+package calculator
+
+import (
+	"time"
+	_ "time/tzdata" // The exchange zone must load even on images without zoneinfo
+)
+
+// MarketLocation is the exchange time zone of US listed options
+var MarketLocation = loadMarketLocation()
+
+func loadMarketLocation() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.FixedZone("EST", -5*60*60)
+	}
+	return loc
+}
+
+// Regular session hours in MarketLocation. Early closes (1pm before some holidays) are not modelled.
+const (
+	SessionOpenMinute  = 9*60 + 30
+	SessionCloseMinute = 16 * 60
+)
+
+// IsTradingDay reports whether the calendar date of d, read in d's own location, is an NYSE trading day:
+// a weekday that is not an exchange holiday. Pass SessionDate(t) or t.In(MarketLocation) for an instant.
+func IsTradingDay(d time.Time) bool {
+	if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		return false
+	}
+	year, month, day := d.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	for _, h := range exchangeHolidays(year) {
+		if h.Equal(date) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsMarketOpen reports whether t falls within the regular session of a trading day
+func IsMarketOpen(t time.Time) bool {
+	local := t.In(MarketLocation)
+	if !IsTradingDay(local) {
+		return false
+	}
+	minute := local.Hour()*60 + local.Minute()
+	return minute >= SessionOpenMinute && minute < SessionCloseMinute
+}
+
+// SessionDate is the date of the latest regular session to have opened by t, as midnight UTC like the
+// other stored dates. Overnight, before the open and on closed days it is the previous trading day.
+func SessionDate(t time.Time) time.Time {
+	local := t.In(MarketLocation)
+	year, month, day := local.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if local.Hour()*60+local.Minute() < SessionOpenMinute {
+		date = date.AddDate(0, 0, -1)
+	}
+	for !IsTradingDay(date) {
+		date = date.AddDate(0, 0, -1)
+	}
+	return date
+}
+
+// exchangeHolidays lists the NYSE full-day closures of year, as observed
+func exchangeHolidays(year int) []time.Time {
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	// The nth weekday of a month; n < 0 counts from the end
+	nth := func(month time.Month, weekday time.Weekday, n int) time.Time {
+		if n < 0 {
+			last := date(month+1, 0)
+			return last.AddDate(0, 0, -((int(last.Weekday()) - int(weekday) + 7) % 7))
+		}
+		first := date(month, 1)
+		return first.AddDate(0, 0, (int(weekday)-int(first.Weekday())+7)%7+7*(n-1))
+	}
+	// Weekend holidays move to the adjacent weekday
+	observed := func(d time.Time) time.Time {
+		switch d.Weekday() {
+		case time.Saturday:
+			return d.AddDate(0, 0, -1)
+		case time.Sunday:
+			return d.AddDate(0, 0, 1)
+		}
+		return d
+	}
+
+	holidays := []time.Time{
+		nth(time.January, time.Monday, 3),    // Martin Luther King Jr. Day
+		nth(time.February, time.Monday, 3),   // Washington's Birthday
+		easter(year).AddDate(0, 0, -2),       // Good Friday
+		nth(time.May, time.Monday, -1),       // Memorial Day
+		observed(date(time.July, 4)),         // Independence Day
+		nth(time.September, time.Monday, 1),  // Labor Day
+		nth(time.November, time.Thursday, 4), // Thanksgiving
+		observed(date(time.December, 25)),    // Christmas
+	}
+	// A Saturday New Year's Day is not made up on the Friday before
+	if newYear := date(time.January, 1); newYear.Weekday() != time.Saturday {
+		holidays = append(holidays, observed(newYear))
+	}
+	if year >= 2022 {
+		holidays = append(holidays, observed(date(time.June, 19))) // Juneteenth
+	}
+	return holidays
+}
+
+// easter is Easter Sunday of year in the Gregorian calendar (anonymous Gregorian algorithm)
+func easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
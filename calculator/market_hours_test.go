@@ -0,0 +1,71 @@
+package calculator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExchangeHolidays(t *testing.T) {
+	// NYSE published calendars
+	for _, s := range []string{
+		"2025-01-01", "2025-01-20", "2025-02-17", "2025-04-18", "2025-05-26", "2025-06-19", "2025-07-04", "2025-09-01", "2025-11-27", "2025-12-25",
+		"2026-01-01", "2026-01-19", "2026-02-16", "2026-04-03", "2026-05-25", "2026-06-19", "2026-07-03", "2026-09-07", "2026-11-26", "2026-12-25",
+		"2022-06-20", "2022-12-26",
+	} {
+		d, _ := time.Parse("2006-01-02", s)
+		if IsTradingDay(d) {
+			t.Errorf("%s should be a holiday", s)
+		}
+	}
+
+	// A Saturday New Year's Day is not observed on the Friday before; Juneteenth starts in 2022
+	for _, s := range []string{"2021-12-31", "2021-06-18", "2026-07-06", "2026-10-16"} {
+		d, _ := time.Parse("2006-01-02", s)
+		if !IsTradingDay(d) {
+			t.Errorf("%s should be a trading day", s)
+		}
+	}
+}
+
+func TestIsMarketOpen(t *testing.T) {
+	tests := []struct {
+		at   string
+		open bool
+	}{
+		{"2026-10-16T13:30:00Z", true},  // 9:30 EDT
+		{"2026-10-16T13:29:00Z", false}, // Before the open
+		{"2026-10-16T19:59:00Z", true},
+		{"2026-10-16T20:00:00Z", false}, // 16:00 EDT
+		{"2026-12-16T14:45:00Z", true},  // 9:45 EST
+		{"2026-12-16T14:15:00Z", false}, // 9:15 EST
+		{"2026-10-17T15:00:00Z", false}, // Saturday
+		{"2026-11-26T15:00:00Z", false}, // Thanksgiving
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		if got := IsMarketOpen(at); got != tt.open {
+			t.Errorf("IsMarketOpen(%s) = %v, want %v", tt.at, got, tt.open)
+		}
+	}
+}
+
+func TestSessionDate(t *testing.T) {
+	tests := []struct{ at, want string }{
+		{"2026-10-16T15:00:00Z", "2026-10-16"}, // Friday during the session
+		{"2026-10-17T01:00:00Z", "2026-10-16"}, // Friday evening in New York
+		{"2026-10-18T15:00:00Z", "2026-10-16"}, // Sunday
+		{"2026-10-19T12:00:00Z", "2026-10-16"}, // Monday before the open
+		{"2026-10-19T13:30:00Z", "2026-10-19"}, // Monday at the open
+		{"2026-11-27T12:00:00Z", "2026-11-25"}, // Before the open the day after Thanksgiving
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		d := SessionDate(at)
+		if got := d.Format("2006-01-02"); got != tt.want {
+			t.Errorf("SessionDate(%s) = %s, want %s", tt.at, got, tt.want)
+		}
+		if d.Location() != time.UTC || d.Hour() != 0 {
+			t.Errorf("SessionDate should be midnight UTC, got %v", d)
+		}
+	}
+}
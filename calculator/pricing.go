@@ -0,0 +1,395 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// PricingInput holds everything a pricing model needs to value one option
+// S: Current Price, K: Strike Price, T: Time to Expiry (years), R: Risk-Free Rate, Sigma: Implied Volatility,
+// Q: Continuous Dividend Yield, Dividends: Discrete cash dividends (escrowed out of the spot price)
+type PricingInput struct {
+	Type      OptionType
+	S         float64
+	K         float64
+	T         float64
+	R         float64
+	Sigma     float64
+	Q         float64
+	Dividends []CashDividend
+}
+
+// CashDividend is a discrete dividend paid T years from the valuation date
+type CashDividend struct {
+	T      float64 `json:"t"`
+	Amount float64 `json:"amount"`
+}
+
+// pvDividends is the value at time t of the dividends paid after t and no later than expiry
+func (in PricingInput) pvDividends(t float64) float64 {
+	pv := 0.0
+	for _, d := range in.Dividends {
+		if d.T > t && d.T <= in.T {
+			pv += d.Amount * math.Exp(-in.R*(d.T-t))
+		}
+	}
+	return pv
+}
+
+// escrowedSpot removes the present value of dividends paid before expiry (escrowed dividend model)
+func (in PricingInput) escrowedSpot() float64 {
+	return math.Max(in.S-in.pvDividends(0), in.S*0.01)
+}
+
+// advance moves the valuation date forward by dt years, dropping dividends that have been paid
+func (in PricingInput) advance(dt float64) PricingInput {
+	out := in
+	out.T = in.T - dt
+	out.Dividends = nil
+	for _, d := range in.Dividends {
+		if d.T > dt {
+			out.Dividends = append(out.Dividends, CashDividend{T: d.T - dt, Amount: d.Amount})
+		}
+	}
+	return out
+}
+
+// PricingResult is a model price with its greeks, using the same conventions as CalculateOptionPrice:
+// Theta is per calendar day, Vega is per 1 vol point.
+type PricingResult struct {
+	Price float64
+	Delta float64
+	Gamma float64
+	Theta float64
+	Vega  float64
+}
+
+// PricingModel values a single option
+type PricingModel interface {
+	Name() string
+	// American reports whether the model accounts for early exercise
+	American() bool
+	Price(in PricingInput) PricingResult
+}
+
+// Model names accepted by ModelByName
+const (
+	ModelBlackScholes = "black-scholes"
+	ModelBinomial     = "binomial"
+	ModelBAW          = "baw"
+)
+
+var (
+	modelMu      sync.RWMutex
+	defaultModel PricingModel = BlackScholesModel{}
+)
+
+// SetDefaultModel changes the model used when callers don't pick one
+func SetDefaultModel(m PricingModel) {
+	modelMu.Lock()
+	defer modelMu.Unlock()
+	defaultModel = m
+}
+
+// DefaultModel returns the model used when callers don't pick one
+func DefaultModel() PricingModel {
+	modelMu.RLock()
+	defer modelMu.RUnlock()
+	return defaultModel
+}
+
+// ModelByName resolves a model name; an empty name returns the default model
+func ModelByName(name string) (PricingModel, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return DefaultModel(), nil
+	case ModelBlackScholes, "bs", "european":
+		return BlackScholesModel{}, nil
+	case ModelBinomial, "crr":
+		return BinomialModel{Steps: DefaultBinomialSteps}, nil
+	case ModelBAW, "barone-adesi-whaley":
+		return BAWModel{}, nil
+	}
+	return nil, fmt.Errorf("unknown pricing model: %s", name)
+}
+
+// EarlyExercisePremium is the value an American model adds over the European price
+func EarlyExercisePremium(model PricingModel, in PricingInput, americanPrice float64) float64 {
+	if !model.American() {
+		return 0
+	}
+	european := BlackScholesModel{}.Price(in).Price
+	premium := americanPrice - european
+	if premium < 0.005 {
+		return 0
+	}
+	return math.Round(premium*100) / 100
+}
+
+// intrinsic is the exercise value of an option
+func intrinsic(optType OptionType, S, K float64) float64 {
+	if optType == Call {
+		return math.Max(0, S-K)
+	}
+	return math.Max(0, K-S)
+}
+
+// expiredResult values an option with no time left
+func expiredResult(in PricingInput) PricingResult {
+	res := PricingResult{Price: intrinsic(in.Type, in.S, in.K)}
+	if in.Type == Call && in.S > in.K {
+		res.Delta = 1
+	} else if in.Type == Put && in.S < in.K {
+		res.Delta = -1
+	}
+	return res
+}
+
+// BlackScholesModel is the European closed form
+type BlackScholesModel struct{}
+
+func (BlackScholesModel) Name() string   { return ModelBlackScholes }
+func (BlackScholesModel) American() bool { return false }
+
+func (BlackScholesModel) Price(in PricingInput) PricingResult {
+	if in.T <= 0 || in.Sigma <= 0 {
+		return expiredResult(in)
+	}
+	price, delta, gamma, theta, vega := CalculateOptionPriceWithYield(in.Type, in.escrowedSpot(), in.K, in.T, in.R, in.Q, in.Sigma)
+	return PricingResult{Price: price, Delta: delta, Gamma: gamma, Theta: theta, Vega: vega}
+}
+
+// DefaultBinomialSteps balances accuracy (~1 cent on typical equity options) against speed
+const DefaultBinomialSteps = 200
+
+// BinomialModel is a Cox-Ross-Rubinstein tree with early exercise at every node
+type BinomialModel struct {
+	Steps int
+}
+
+func (BinomialModel) Name() string   { return ModelBinomial }
+func (BinomialModel) American() bool { return true }
+
+func (m BinomialModel) Price(in PricingInput) PricingResult {
+	if in.T <= 0 || in.Sigma <= 0 {
+		return expiredResult(in)
+	}
+
+	steps := m.Steps
+	if steps < 3 {
+		steps = DefaultBinomialSteps
+	}
+
+	price, delta, gamma, theta := crrTree(in, steps)
+
+	// The tree doesn't give vega directly, so bump volatility by one point either side
+	up, down := in, in
+	up.Sigma += 0.01
+	down.Sigma = math.Max(0.0001, in.Sigma-0.01)
+	upPrice, _, _, _ := crrTree(up, steps)
+	downPrice, _, _, _ := crrTree(down, steps)
+	vega := (upPrice - downPrice) / ((up.Sigma - down.Sigma) * 100)
+
+	return PricingResult{Price: price, Delta: delta, Gamma: gamma, Theta: theta, Vega: vega}
+}
+
+// crrTree prices the option and reads delta, gamma and theta off the first two levels of the tree.
+// The tree is built on the escrowed spot; exercise values add back the dividends still to be paid,
+// which is what makes early exercise of calls just before an ex-date show up.
+func crrTree(in PricingInput, steps int) (price, delta, gamma, theta float64) {
+	dt := in.T / float64(steps)
+	u := math.Exp(in.Sigma * math.Sqrt(dt))
+	d := 1 / u
+	p := (math.Exp((in.R-in.Q)*dt) - d) / (u - d)
+	disc := math.Exp(-in.R * dt)
+	s0 := in.escrowedSpot()
+
+	// Option values at expiry
+	values := make([]float64, steps+1)
+	for i := 0; i <= steps; i++ {
+		st := s0 * math.Pow(u, float64(i)) * math.Pow(d, float64(steps-i))
+		values[i] = intrinsic(in.Type, st, in.K)
+	}
+
+	var level1, level2 []float64
+	for step := steps - 1; step >= 0; step-- {
+		pending := in.pvDividends(float64(step) * dt)
+		for i := 0; i <= step; i++ {
+			st := s0*math.Pow(u, float64(i))*math.Pow(d, float64(step-i)) + pending
+			cont := disc * (p*values[i+1] + (1-p)*values[i])
+			values[i] = math.Max(cont, intrinsic(in.Type, st, in.K))
+		}
+		if step == 2 {
+			level2 = append([]float64(nil), values[:3]...)
+		}
+		if step == 1 {
+			level1 = append([]float64(nil), values[:2]...)
+		}
+	}
+	price = values[0]
+
+	delta = (level1[1] - level1[0]) / (s0*u - s0*d)
+
+	sUU, sUD, sDD := s0*u*u, s0, s0*d*d
+	deltaUp := (level2[2] - level2[1]) / (sUU - sUD)
+	deltaDown := (level2[1] - level2[0]) / (sUD - sDD)
+	gamma = (deltaUp - deltaDown) / (0.5 * (sUU - sDD))
+
+	// The middle node two steps in has the same spot, so the change is pure time decay
+	theta = (level2[1] - price) / (2 * dt) / 365
+
+	return price, delta, gamma, theta
+}
+
+// BAWModel is the Barone-Adesi-Whaley quadratic approximation for American options.
+// Cash dividends are only escrowed out of the spot, so early exercise just before an ex-date
+// is captured by BinomialModel but not here; a continuous yield is handled exactly.
+type BAWModel struct{}
+
+func (BAWModel) Name() string   { return ModelBAW }
+func (BAWModel) American() bool { return true }
+
+func (BAWModel) Price(in PricingInput) PricingResult {
+	if in.T <= 0 || in.Sigma <= 0 {
+		return expiredResult(in)
+	}
+	return finiteDifferenceGreeks(in, func(x PricingInput) float64 {
+		return bawPrice(x.Type, x.escrowedSpot(), x.K, x.T, x.R, x.R-x.Q, x.Sigma)
+	})
+}
+
+// finiteDifferenceGreeks derives greeks from any pricing function by bumping spot, time and vol
+func finiteDifferenceGreeks(in PricingInput, price func(PricingInput) float64) PricingResult {
+	res := PricingResult{Price: price(in)}
+
+	dS := in.S * 0.001
+	up, down := in, in
+	up.S += dS
+	down.S -= dS
+	pUp, pDown := price(up), price(down)
+	res.Delta = (pUp - pDown) / (2 * dS)
+	res.Gamma = (pUp - 2*res.Price + pDown) / (dS * dS)
+
+	oneDay := 1.0 / 365
+	later := in.advance(oneDay)
+	if later.T > 0 {
+		res.Theta = price(later) - res.Price
+	} else {
+		res.Theta = intrinsic(in.Type, in.S, in.K) - res.Price
+	}
+
+	volUp, volDown := in, in
+	volUp.Sigma += 0.01
+	volDown.Sigma = math.Max(0.0001, in.Sigma-0.01)
+	res.Vega = (price(volUp) - price(volDown)) / ((volUp.Sigma - volDown.Sigma) * 100)
+
+	return res
+}
+
+// generalizedBS is Black-Scholes with cost of carry b (b = r for non-dividend stocks)
+func generalizedBS(optType OptionType, S, K, T, r, b, sigma float64) float64 {
+	d1 := (math.Log(S/K) + (b+0.5*sigma*sigma)*T) / (sigma * math.Sqrt(T))
+	d2 := d1 - sigma*math.Sqrt(T)
+	if optType == Call {
+		return S*math.Exp((b-r)*T)*cdf(d1) - K*math.Exp(-r*T)*cdf(d2)
+	}
+	return K*math.Exp(-r*T)*cdf(-d2) - S*math.Exp((b-r)*T)*cdf(-d1)
+}
+
+// bawPrice implements Barone-Adesi & Whaley (1987) with cost of carry b
+func bawPrice(optType OptionType, S, K, T, r, b, sigma float64) float64 {
+	european := generalizedBS(optType, S, K, T, r, b, sigma)
+
+	// Without a dividend (b >= r) an American call is never exercised early
+	if optType == Call && b >= r {
+		return european
+	}
+	// With no interest to earn on the strike (r <= 0) neither is an American put
+	if optType == Put && r <= 0 {
+		return european
+	}
+
+	sqrtT := math.Sqrt(T)
+	N := 2 * b / (sigma * sigma)
+	carry := math.Exp((b - r) * T)
+
+	// M / (1 - e^-rT), taking its limit 2 / (sigma^2 T) at r = 0 where both terms vanish
+	mOverK := 2 / (sigma * sigma * T)
+	if r != 0 {
+		mOverK = 2 * r / (sigma * sigma) / (1 - math.Exp(-r*T))
+	}
+
+	d1 := func(x float64) float64 {
+		return (math.Log(x/K) + (b+0.5*sigma*sigma)*T) / (sigma * sqrtT)
+	}
+
+	if optType == Call {
+		q2 := (-(N - 1) + math.Sqrt((N-1)*(N-1)+4*mOverK)) / 2
+		sStar := bawCriticalCall(K, T, r, b, sigma, q2)
+		if S >= sStar {
+			return S - K
+		}
+		a2 := (sStar / q2) * (1 - carry*cdf(d1(sStar)))
+		return european + a2*math.Pow(S/sStar, q2)
+	}
+
+	q1 := (-(N - 1) - math.Sqrt((N-1)*(N-1)+4*mOverK)) / 2
+	sStar := bawCriticalPut(K, T, r, b, sigma, q1)
+	if S <= sStar {
+		return K - S
+	}
+	a1 := -(sStar / q1) * (1 - carry*cdf(-d1(sStar)))
+	return european + a1*math.Pow(S/sStar, q1)
+}
+
+// bawCriticalCall solves for the spot above which immediate exercise is optimal (Newton-Raphson, Haug's seed)
+func bawCriticalCall(K, T, r, b, sigma, q2 float64) float64 {
+	sqrtT := math.Sqrt(T)
+	N := 2 * b / (sigma * sigma)
+	M := 2 * r / (sigma * sigma)
+	carry := math.Exp((b - r) * T)
+
+	q2Inf := (-(N - 1) + math.Sqrt((N-1)*(N-1)+4*M)) / 2
+	sInf := K / (1 - 1/q2Inf)
+	h2 := -(b*T + 2*sigma*sqrtT) * K / (sInf - K)
+	si := K + (sInf-K)*(1-math.Exp(h2))
+
+	for i := 0; i < 100; i++ {
+		d1 := (math.Log(si/K) + (b+0.5*sigma*sigma)*T) / (sigma * sqrtT)
+		lhs := si - K
+		rhs := generalizedBS(Call, si, K, T, r, b, sigma) + (1-carry*cdf(d1))*si/q2
+		if math.Abs(lhs-rhs)/K < 1e-6 {
+			break
+		}
+		bi := carry*cdf(d1)*(1-1/q2) + (1-carry*pdf(d1)/(sigma*sqrtT))/q2
+		si = (K + rhs - bi*si) / (1 - bi)
+	}
+	return si
+}
+
+// bawCriticalPut solves for the spot below which immediate exercise is optimal
+func bawCriticalPut(K, T, r, b, sigma, q1 float64) float64 {
+	sqrtT := math.Sqrt(T)
+	N := 2 * b / (sigma * sigma)
+	M := 2 * r / (sigma * sigma)
+	carry := math.Exp((b - r) * T)
+
+	q1Inf := (-(N - 1) - math.Sqrt((N-1)*(N-1)+4*M)) / 2
+	sInf := K / (1 - 1/q1Inf)
+	h1 := (b*T - 2*sigma*sqrtT) * K / (K - sInf)
+	si := sInf + (K-sInf)*math.Exp(h1)
+
+	for i := 0; i < 100; i++ {
+		d1 := (math.Log(si/K) + (b+0.5*sigma*sigma)*T) / (sigma * sqrtT)
+		lhs := K - si
+		rhs := generalizedBS(Put, si, K, T, r, b, sigma) - (1-carry*cdf(-d1))*si/q1
+		if math.Abs(lhs-rhs)/K < 1e-6 {
+			break
+		}
+		bi := -carry*cdf(-d1)*(1-1/q1) - (1+carry*pdf(-d1)/(sigma*sqrtT))/q1
+		si = (K - rhs + bi*si) / (1 + bi)
+	}
+	return si
+}
@@ -0,0 +1,128 @@
+package calculator
+
+import (
+	"math"
+	"testing"
+)
+
+// Barone-Adesi-Whaley values from Haug, The Complete Guide to Option Pricing Formulas (2nd ed.), Table 3-1:
+// K = 100, r = 0.10, b = 0. Haug's table is printed to 4 decimals from a looser critical-price
+// solve, so deep in-the-money values differ in the third decimal.
+var haugBAW = []struct {
+	T, sigma    float64
+	calls, puts [3]float64 // S = 90, 100, 110
+}{
+	{0.1, 0.15, [3]float64{0.0206, 1.8771, 10.0089}, [3]float64{10.0000, 1.8770, 0.0410}},
+	{0.1, 0.25, [3]float64{0.3159, 3.1280, 10.3919}, [3]float64{10.2533, 3.1277, 0.4562}},
+	{0.1, 0.35, [3]float64{0.9495, 4.3777, 11.1679}, [3]float64{10.8787, 4.3777, 1.2402}},
+	{0.5, 0.15, [3]float64{0.8208, 4.0842, 10.8087}, [3]float64{10.5595, 4.0842, 1.0822}},
+	{0.5, 0.25, [3]float64{2.7437, 6.8015, 13.0170}, [3]float64{12.4419, 6.8014, 3.3226}},
+	{0.5, 0.35, [3]float64{5.0063, 9.5106, 15.5689}, [3]float64{14.6945, 9.5104, 5.8823}},
+}
+
+func TestBAWMatchesHaug(t *testing.T) {
+	for _, row := range haugBAW {
+		for i, S := range []float64{90, 100, 110} {
+			for _, c := range []struct {
+				typ  OptionType
+				want float64
+			}{{Call, row.calls[i]}, {Put, row.puts[i]}} {
+				// b = r - q = 0
+				in := PricingInput{Type: c.typ, S: S, K: 100, T: row.T, R: 0.10, Q: 0.10, Sigma: row.sigma}
+				got := BAWModel{}.Price(in).Price
+				if math.Abs(got-c.want) > 0.005 {
+					t.Errorf("BAW %s S=%v T=%v sigma=%v = %.4f, want %.4f", c.typ, S, row.T, row.sigma, got, c.want)
+				}
+			}
+		}
+	}
+}
+
+func TestBAWZeroAndNegativeRates(t *testing.T) {
+	for _, r := range []float64{0, -0.005} {
+		for _, q := range []float64{0, 0.03} {
+			for _, typ := range []OptionType{Call, Put} {
+				in := PricingInput{Type: typ, S: 100, K: 100, T: 0.5, R: r, Q: q, Sigma: 0.2}
+				res := BAWModel{}.Price(in)
+				for name, v := range map[string]float64{"price": res.Price, "delta": res.Delta, "gamma": res.Gamma, "theta": res.Theta, "vega": res.Vega} {
+					if math.IsNaN(v) || math.IsInf(v, 0) {
+						t.Errorf("BAW %s r=%v q=%v: %s is %v", typ, r, q, name, v)
+					}
+				}
+
+				european := BlackScholesModel{}.Price(in).Price
+				if typ == Put && math.Abs(res.Price-european) > 1e-9 {
+					t.Errorf("BAW put at r=%v should equal the European price %.4f, got %.4f", r, european, res.Price)
+				}
+				if res.Price < european-1e-9 {
+					t.Errorf("BAW %s r=%v q=%v = %.4f is below the European %.4f", typ, r, q, res.Price, european)
+				}
+			}
+		}
+	}
+
+	// A zero rate is the continuous limit of small positive rates
+	in := PricingInput{Type: Call, S: 100, K: 100, T: 0.5, Q: 0.03, Sigma: 0.2}
+	atZero := BAWModel{}.Price(in).Price
+	in.R = 1e-7
+	near := BAWModel{}.Price(in).Price
+	if math.Abs(atZero-near) > 1e-4 {
+		t.Errorf("BAW call at r=0 is %.6f but %.6f at r=1e-7", atZero, near)
+	}
+}
+
+func TestBinomialConvergesToBlackScholes(t *testing.T) {
+	// Without dividends an American call is never exercised early, so CRR must converge to the closed form
+	for _, in := range []PricingInput{
+		{Type: Call, S: 100, K: 100, T: 0.5, R: 0.05, Sigma: 0.25},
+		{Type: Call, S: 90, K: 100, T: 1, R: 0.03, Sigma: 0.35},
+		{Type: Call, S: 120, K: 100, T: 0.25, R: 0.04, Sigma: 0.2},
+	} {
+		want := BlackScholesModel{}.Price(in).Price
+		coarse := math.Abs(BinomialModel{Steps: 50}.Price(in).Price - want)
+		fine := math.Abs(BinomialModel{Steps: 1000}.Price(in).Price - want)
+		if fine > 0.01 {
+			t.Errorf("CRR(1000) for S=%v K=%v differs from Black-Scholes %.4f by %.4f", in.S, in.K, want, fine)
+		}
+		if fine > coarse {
+			t.Errorf("CRR error grew from %.5f at 50 steps to %.5f at 1000", coarse, fine)
+		}
+	}
+}
+
+func TestBinomialGreeksMatchBlackScholes(t *testing.T) {
+	in := PricingInput{Type: Call, S: 100, K: 100, T: 0.5, R: 0.05, Sigma: 0.25}
+	bs := BlackScholesModel{}.Price(in)
+	crr := BinomialModel{Steps: 500}.Price(in)
+
+	if math.Abs(crr.Delta-bs.Delta) > 0.01 {
+		t.Errorf("delta %.4f, Black-Scholes %.4f", crr.Delta, bs.Delta)
+	}
+	if math.Abs(crr.Gamma-bs.Gamma) > 0.002 {
+		t.Errorf("gamma %.4f, Black-Scholes %.4f", crr.Gamma, bs.Gamma)
+	}
+	if math.Abs(crr.Vega-bs.Vega) > 0.01 {
+		t.Errorf("vega %.4f, Black-Scholes %.4f", crr.Vega, bs.Vega)
+	}
+	if math.Abs(crr.Theta-bs.Theta) > 0.005 {
+		t.Errorf("theta %.4f, Black-Scholes %.4f", crr.Theta, bs.Theta)
+	}
+}
+
+func TestAmericanPutEarlyExercise(t *testing.T) {
+	// BAW is an approximation whose error grows with maturity, so keep the expiry short
+	in := PricingInput{Type: Put, S: 90, K: 100, T: 0.25, R: 0.08, Sigma: 0.2}
+	european := BlackScholesModel{}.Price(in).Price
+	crr := BinomialModel{Steps: 500}.Price(in).Price
+	baw := BAWModel{}.Price(in).Price
+
+	if crr <= european || baw <= european {
+		t.Errorf("American put (CRR %.4f, BAW %.4f) should exceed the European %.4f", crr, baw, european)
+	}
+	if math.Abs(crr-baw) > 0.05 {
+		t.Errorf("CRR %.4f and BAW %.4f disagree", crr, baw)
+	}
+	if p := EarlyExercisePremium(BinomialModel{Steps: 500}, in, crr); p <= 0 {
+		t.Errorf("EarlyExercisePremium = %v, want > 0", p)
+	}
+}
@@ -1,7 +1,10 @@
 package calculator
 
 import (
+	"fmt"
 	"math"
+	"sort"
+	"strikelogic/rates"
 	"time"
 )
 
@@ -10,12 +13,57 @@ type MatrixPoint struct {
 	Date   string  `json:"date"`
 	Price  float64 `json:"price"`
 	Profit float64 `json:"profit"`
+	Value  float64 `json:"value"` // The point in the requested output mode
 	ZScore float64 `json:"zScore"`
 }
 
-// MatrixResponse holds the grid of profit points
+// MatrixResponse holds the grid of profit points and its axes.
+// Grid is ordered by date, then price, so Grid[i*len(Prices)+j] is Dates[i], Prices[j].
 type MatrixResponse struct {
-	Grid []MatrixPoint `json:"grid"`
+	Grid   []MatrixPoint `json:"grid"`
+	Dates  []string      `json:"dates"`
+	Prices []float64     `json:"prices"`
+	Output string        `json:"output"` // Units of MatrixPoint.Value (see Matrix* output constants)
+	Model  string        `json:"model"`  // Pricing model used to value open legs
+}
+
+// Price range modes for MatrixConfig.RangeMode
+const (
+	RangePercent  = "percent"  // Spot +/- Range percent
+	RangeAbsolute = "absolute" // MinPrice to MaxPrice
+	RangeStdDev   = "stddev"   // Spot +/- Range standard deviations of the volatility argument to the last date
+)
+
+// Output modes for MatrixConfig.Output
+const (
+	MatrixPnL         = "pnl"         // Dollar P&L
+	MatrixPercentRisk = "percentRisk" // P&L as a percent of MaxRisk
+	MatrixValue       = "value"       // Dollar mark value of the position
+)
+
+// Matrix defaults, matching the original fixed grid
+const (
+	DefaultMatrixRange      = 20.0 // Percent
+	DefaultMatrixStdDevs    = 2.0
+	DefaultMatrixPriceSteps = 21
+	DefaultMatrixDateSteps  = 8
+	MaxMatrixSteps          = 200
+)
+
+// MatrixConfig sets the matrix axes and output; the zero value gives 8 dates to expiry and 21 prices within +/-20%
+type MatrixConfig struct {
+	RangeMode  string  `json:"rangeMode"`  // percent (default), absolute or stddev
+	Range      float64 `json:"range"`      // Percent or standard deviations
+	MinPrice   float64 `json:"minPrice"`   // Absolute range only
+	MaxPrice   float64 `json:"maxPrice"`   // Absolute range only
+	PriceSteps int     `json:"priceSteps"` // Number of prices
+
+	Dates     []string `json:"dates"`     // Explicit dates (YYYY-MM-DD); overrides the steps below
+	DateSteps int      `json:"dateSteps"` // Number of dates, evenly spaced from tomorrow to the last expiry
+	StepDays  float64  `json:"stepDays"`  // Days between dates instead of DateSteps; fractions give intraday steps
+
+	Output  string  `json:"output"`  // pnl (default), percentRisk or value
+	MaxRisk float64 `json:"maxRisk"` // Denominator for percentRisk; zero uses the largest loss in the grid
 }
 
 // LegInput defines the necessary parameters for a strategy leg to be priced
@@ -27,16 +75,34 @@ type LegInput struct {
 	Expiry     time.Time
 	IV         float64 // Implied Volatility of this specific leg
 	EntryPrice float64 // The price per share paid/received for this leg
+	IsStock    bool    // Shares of the underlying: Quantity is a share count and only EntryPrice matters
+}
+
+// VolSurface supplies implied volatility by strike and years to expiry (see the volsurface package)
+type VolSurface interface {
+	Vol(strike, T float64) float64
 }
 
 // StrategyInput captures the strategy details for the matrix calculation
 type StrategyInput struct {
 	Legs         []LegInput
-	InitialDebit float64 // Deprecated in favor of per-leg EntryPrice, but kept for compatibility
+	InitialDebit float64      // Deprecated in favor of per-leg EntryPrice, but kept for compatibility
+	Model        PricingModel // Model used to value legs before expiry; nil uses DefaultModel()
+	Surface      VolSurface   // Vols for legs and scenarios; nil uses each leg's IV, then the volatility argument
+
+	// Dividends of the underlying, see DividendInputs
+	DividendYield float64
+	Dividends     []Dividend
 }
 
-// CalculateProfitMatrix generates a heatmap of theoretical profit/loss over time and price
+// CalculateProfitMatrix generates a heatmap of theoretical profit/loss over time and price on the default axes.
+// With a Surface, legs are revalued at the surface vol for their strike and remaining time (sticky strike).
 func CalculateProfitMatrix(strategy StrategyInput, currentPrice float64, volatility float64) (MatrixResponse, error) {
+	return CalculateProfitMatrixWith(strategy, currentPrice, volatility, MatrixConfig{})
+}
+
+// CalculateProfitMatrixWith is CalculateProfitMatrix with configurable axes and output
+func CalculateProfitMatrixWith(strategy StrategyInput, currentPrice float64, volatility float64, cfg MatrixConfig) (MatrixResponse, error) {
 	// 1. Identify Time Horizon
 	var expiryDate time.Time
 	for _, leg := range strategy.Legs {
@@ -49,47 +115,38 @@ func CalculateProfitMatrix(strategy StrategyInput, currentPrice float64, volatil
 		expiryDate = time.Now().AddDate(0, 0, 30) // Fallback
 	}
 
-	startDate := time.Now().AddDate(0, 0, 1) // "Tomorrow"
-
-	if startDate.After(expiryDate) {
-		startDate = time.Now()
+	dates, intraday, err := matrixDates(cfg, expiryDate)
+	if err != nil {
+		return MatrixResponse{}, err
 	}
 
-	totalDays := expiryDate.Sub(startDate).Hours() / 24
-	if totalDays <= 0 {
-		totalDays = 0.01
+	// 2. Price axis
+	prices, err := matrixPrices(cfg, currentPrice, volatility, dates[len(dates)-1])
+	if err != nil {
+		return MatrixResponse{}, err
 	}
 
-	// Generate 8 Time Slices
-	timeSlices := 8
-	var dates []time.Time
-
-	stepDays := totalDays / float64(timeSlices-1)
-	if timeSlices == 1 {
-		dates = append(dates, expiryDate)
-	} else {
-		for i := 0; i < timeSlices; i++ {
-			d := startDate.Add(time.Duration(float64(i)*stepDays*24) * time.Hour)
-			if d.After(expiryDate) {
-				d = expiryDate
-			}
-			dates = append(dates, d)
-		}
+	output := cfg.Output
+	switch output {
+	case "":
+		output = MatrixPnL
+	case MatrixPnL, MatrixPercentRisk, MatrixValue:
+	default:
+		return MatrixResponse{}, fmt.Errorf("unknown matrix output: %s", output)
 	}
 
-	// 2. Generate 21 Price Points (-20% to +20%)
-	pricePoints := 21
-	minPrice := currentPrice * 0.80
-	maxPrice := currentPrice * 1.20
-	priceStep := (maxPrice - minPrice) / float64(pricePoints-1)
-
-	var prices []float64
-	for i := 0; i < pricePoints; i++ {
-		prices = append(prices, minPrice+float64(i)*priceStep)
+	dateFormat := "2006-01-02"
+	if intraday {
+		dateFormat = "2006-01-02 15:04"
 	}
 
 	grid := []MatrixPoint{}
-	riskFreeRate := 0.05
+	var marks []float64 // Position value per point, for the value output
+
+	model := strategy.Model
+	if model == nil {
+		model = DefaultModel()
+	}
 
 	// 3. Calculation Loop
 	for _, d := range dates {
@@ -99,14 +156,31 @@ func CalculateProfitMatrix(strategy StrategyInput, currentPrice float64, volatil
 			timeToSimDate = 0.0001
 		}
 
+		// Dividends are measured from the simulated date, so ones already paid drop out
+		q, dividends := DividendInputs(strategy.DividendYield, strategy.Dividends, d)
+
 		for _, p := range prices {
 			totalPnL := 0.0
+			mark := 0.0
 
 			for _, leg := range strategy.Legs {
 				// Time remaining for this leg from simulated date 'd'
 				T_rem := leg.Expiry.Sub(d).Hours() / 24 / 365.0
 
 				var optionValue float64
+				if leg.IsStock {
+					legProfit := (p - leg.EntryPrice) * leg.Quantity
+					if leg.Action != "Buy" {
+						legProfit = -legProfit
+					}
+					totalPnL += legProfit
+					if leg.Action == "Buy" {
+						mark += p * leg.Quantity
+					} else {
+						mark -= p * leg.Quantity
+					}
+					continue
+				}
 				if T_rem <= 0 {
 					// Expired Value
 					if leg.Type == Call {
@@ -115,14 +189,10 @@ func CalculateProfitMatrix(strategy StrategyInput, currentPrice float64, volatil
 						optionValue = math.Max(0, leg.Strike-p)
 					}
 				} else {
-					// Black-Scholes Value
-					sigma := leg.IV
-					if sigma <= 0 {
-						sigma = volatility
-					}
-					// CalculateTheoreticalPrice
-					price, _, _, _, _ := CalculateOptionPrice(leg.Type, p, leg.Strike, T_rem, riskFreeRate, sigma)
-					optionValue = price
+					// Theoretical Value
+					sigma := strategy.legVol(leg, T_rem, volatility)
+					res := model.Price(PricingInput{Type: leg.Type, S: p, K: leg.Strike, T: T_rem, R: rates.RateFor(T_rem), Sigma: sigma, Q: q, Dividends: dividends})
+					optionValue = res.Price
 				}
 
 				// The PnL Formula (Per Leg)
@@ -144,16 +214,24 @@ func CalculateProfitMatrix(strategy StrategyInput, currentPrice float64, volatil
 				if leg.Action == "Buy" {
 					// Long: Profit = Exit - Entry
 					legProfit = (exitVal - entryVal) * leg.Quantity
+					mark += exitVal * leg.Quantity
 				} else {
 					// Short: Profit = Entry - Exit
 					legProfit = (entryVal - exitVal) * leg.Quantity
+					mark -= exitVal * leg.Quantity
 				}
 
 				totalPnL += legProfit
 			}
 
-			// 4. Probability Layer (Z-Score)
-			denom := currentPrice * volatility * math.Sqrt(timeToSimDate)
+			// 4. Probability Layer (Z-Score), using the at-the-money vol to the simulated date
+			scenarioVol := volatility
+			if strategy.Surface != nil {
+				if v := strategy.Surface.Vol(currentPrice, timeToSimDate); v > 0 {
+					scenarioVol = v
+				}
+			}
+			denom := currentPrice * scenarioVol * math.Sqrt(timeToSimDate)
 			zScore := 0.0
 			if denom > 0 {
 				zScore = (p - currentPrice) / denom
@@ -161,13 +239,171 @@ func CalculateProfitMatrix(strategy StrategyInput, currentPrice float64, volatil
 
 			// Add to grid
 			grid = append(grid, MatrixPoint{
-				Date:   d.Format("2006-01-02"),
+				Date:   d.Format(dateFormat),
 				Price:  math.Round(p*100) / 100,
 				Profit: math.Round(totalPnL*100) / 100,
 				ZScore: math.Round(zScore*1000) / 1000,
 			})
+			marks = append(marks, mark)
+		}
+	}
+
+	// 5. Output mode
+	maxRisk := cfg.MaxRisk
+	if output == MatrixPercentRisk && maxRisk <= 0 {
+		for _, pt := range grid {
+			maxRisk = math.Max(maxRisk, -pt.Profit)
+		}
+	}
+	for i := range grid {
+		switch output {
+		case MatrixPnL:
+			grid[i].Value = grid[i].Profit
+		case MatrixPercentRisk:
+			if maxRisk > 0 {
+				grid[i].Value = math.Round(grid[i].Profit/maxRisk*1000) / 10
+			}
+		case MatrixValue:
+			grid[i].Value = math.Round(marks[i]*100) / 100
 		}
 	}
 
-	return MatrixResponse{Grid: grid}, nil
+	resp := MatrixResponse{Grid: grid, Output: output, Model: model.Name()}
+	for _, d := range dates {
+		resp.Dates = append(resp.Dates, d.Format(dateFormat))
+	}
+	for _, p := range prices {
+		resp.Prices = append(resp.Prices, math.Round(p*100)/100)
+	}
+	return resp, nil
+}
+
+// legVol is the vol an open leg is valued at: the surface's, else the leg's IV, else the fallback volatility
+func (strategy StrategyInput) legVol(leg LegInput, T, volatility float64) float64 {
+	if strategy.Surface != nil {
+		if v := strategy.Surface.Vol(leg.Strike, T); v > 0 {
+			return v
+		}
+	}
+	if leg.IV > 0 {
+		return leg.IV
+	}
+	return volatility
+}
+
+// matrixDates builds the time axis, ending at expiryDate. intraday reports whether steps are shorter than a day.
+func matrixDates(cfg MatrixConfig, expiryDate time.Time) (dates []time.Time, intraday bool, err error) {
+	if len(cfg.Dates) > 0 {
+		if len(cfg.Dates) > MaxMatrixSteps {
+			return nil, false, fmt.Errorf("at most %d matrix dates", MaxMatrixSteps)
+		}
+		for _, s := range cfg.Dates {
+			d, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid matrix date %s: %v", s, err)
+			}
+			dates = append(dates, d)
+		}
+		// The last date is the horizon for the price range, so order them
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+		return dates, false, nil
+	}
+
+	startDate := time.Now().AddDate(0, 0, 1) // "Tomorrow"
+
+	if startDate.After(expiryDate) {
+		startDate = time.Now()
+	}
+
+	totalDays := expiryDate.Sub(startDate).Hours() / 24
+	if totalDays <= 0 {
+		totalDays = 0.01
+	}
+
+	timeSlices := cfg.DateSteps
+	stepDays := cfg.StepDays
+	switch {
+	case stepDays > 0:
+		// Check before converting: a tiny step overflows int
+		if totalDays/stepDays >= MaxMatrixSteps {
+			return nil, false, fmt.Errorf("at most %d matrix dates", MaxMatrixSteps)
+		}
+		timeSlices = int(math.Floor(totalDays/stepDays)) + 1
+		if float64(timeSlices-1)*stepDays < totalDays {
+			timeSlices++ // Always end on the expiry
+		}
+	case timeSlices <= 0:
+		timeSlices = DefaultMatrixDateSteps
+		fallthrough
+	default:
+		stepDays = totalDays / math.Max(float64(timeSlices-1), 1)
+	}
+	if timeSlices > MaxMatrixSteps {
+		return nil, false, fmt.Errorf("at most %d matrix dates", MaxMatrixSteps)
+	}
+
+	if timeSlices == 1 {
+		dates = append(dates, expiryDate)
+	} else {
+		for i := 0; i < timeSlices; i++ {
+			d := startDate.Add(time.Duration(float64(i)*stepDays*24) * time.Hour)
+			if d.After(expiryDate) {
+				d = expiryDate
+			}
+			dates = append(dates, d)
+		}
+	}
+	if len(dates) == 0 {
+		return nil, false, fmt.Errorf("no matrix dates")
+	}
+	return dates, stepDays < 1, nil
+}
+
+// matrixPrices builds the price axis. Standard deviation ranges are measured to lastDate.
+func matrixPrices(cfg MatrixConfig, currentPrice, volatility float64, lastDate time.Time) ([]float64, error) {
+	pricePoints := cfg.PriceSteps
+	if pricePoints <= 0 {
+		pricePoints = DefaultMatrixPriceSteps
+	}
+	if pricePoints < 2 || pricePoints > MaxMatrixSteps {
+		return nil, fmt.Errorf("matrix price steps must be between 2 and %d", MaxMatrixSteps)
+	}
+
+	var minPrice, maxPrice float64
+	switch cfg.RangeMode {
+	case "", RangePercent:
+		pct := cfg.Range
+		if pct <= 0 {
+			pct = DefaultMatrixRange
+		}
+		minPrice = currentPrice * (1 - pct/100)
+		maxPrice = currentPrice * (1 + pct/100)
+	case RangeAbsolute:
+		minPrice, maxPrice = cfg.MinPrice, cfg.MaxPrice
+	case RangeStdDev:
+		n := cfg.Range
+		if n <= 0 {
+			n = DefaultMatrixStdDevs
+		}
+		T := math.Max(lastDate.Sub(time.Now()).Hours()/24/365.0, 1.0/365/24)
+		if volatility <= 0 {
+			return nil, fmt.Errorf("volatility required for a standard deviation range")
+		}
+		move := n * volatility * math.Sqrt(T)
+		minPrice = currentPrice * math.Exp(-move)
+		maxPrice = currentPrice * math.Exp(move)
+	default:
+		return nil, fmt.Errorf("unknown matrix range mode: %s", cfg.RangeMode)
+	}
+	minPrice = math.Max(minPrice, 0)
+	if maxPrice <= minPrice {
+		return nil, fmt.Errorf("matrix price range is empty")
+	}
+
+	priceStep := (maxPrice - minPrice) / float64(pricePoints-1)
+	var prices []float64
+	for i := 0; i < pricePoints; i++ {
+		prices = append(prices, minPrice+float64(i)*priceStep)
+	}
+	return prices, nil
 }
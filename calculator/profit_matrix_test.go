@@ -0,0 +1,171 @@
+package calculator
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// longCall is one 100 strike call expiring in 30 days, bought at 3
+func longCall() StrategyInput {
+	return StrategyInput{
+		Legs:  []LegInput{{Strike: 100, Type: Call, Action: "Buy", Quantity: 1, Expiry: time.Now().AddDate(0, 0, 30), IV: 0.3, EntryPrice: 3}},
+		Model: BlackScholesModel{},
+	}
+}
+
+func TestMatrixDefaultAxes(t *testing.T) {
+	resp, err := CalculateProfitMatrix(longCall(), 100, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Dates) != DefaultMatrixDateSteps || len(resp.Prices) != DefaultMatrixPriceSteps {
+		t.Fatalf("axes are %d dates x %d prices, want %d x %d", len(resp.Dates), len(resp.Prices), DefaultMatrixDateSteps, DefaultMatrixPriceSteps)
+	}
+	if len(resp.Grid) != len(resp.Dates)*len(resp.Prices) {
+		t.Fatalf("grid has %d points, want %d", len(resp.Grid), len(resp.Dates)*len(resp.Prices))
+	}
+	if resp.Prices[0] != 80 || resp.Prices[len(resp.Prices)-1] != 120 {
+		t.Errorf("price axis %v..%v, want 80..120", resp.Prices[0], resp.Prices[len(resp.Prices)-1])
+	}
+	if resp.Output != MatrixPnL || resp.Model != (BlackScholesModel{}).Name() {
+		t.Errorf("output %q model %q", resp.Output, resp.Model)
+	}
+
+	// Grid[i*len(Prices)+j] is Dates[i], Prices[j]; at expiry the 120 call is worth about 20
+	last := resp.Grid[len(resp.Grid)-1]
+	if last.Date != resp.Dates[len(resp.Dates)-1] || last.Price != 120 {
+		t.Errorf("last point is %s %v", last.Date, last.Price)
+	}
+	if math.Abs(last.Profit-1700) > 1 {
+		t.Errorf("profit at expiry and 120 = %v, want about 1700", last.Profit)
+	}
+}
+
+func TestMatrixPriceRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      MatrixConfig
+		min, max float64
+	}{
+		{"percent", MatrixConfig{Range: 10, PriceSteps: 5}, 90, 110},
+		{"absolute", MatrixConfig{RangeMode: RangeAbsolute, MinPrice: 50, MaxPrice: 150, PriceSteps: 11}, 50, 150},
+		{"percent floored at zero", MatrixConfig{Range: 150, PriceSteps: 3}, 0, 250},
+	}
+	for _, tt := range tests {
+		resp, err := CalculateProfitMatrixWith(longCall(), 100, 0.3, tt.cfg)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(resp.Prices) != tt.cfg.PriceSteps {
+			t.Errorf("%s: %d prices, want %d", tt.name, len(resp.Prices), tt.cfg.PriceSteps)
+		}
+		if resp.Prices[0] != tt.min || resp.Prices[len(resp.Prices)-1] != tt.max {
+			t.Errorf("%s: prices %v..%v, want %v..%v", tt.name, resp.Prices[0], resp.Prices[len(resp.Prices)-1], tt.min, tt.max)
+		}
+	}
+
+	// One standard deviation to the last date, which is the 30 day expiry
+	resp, err := CalculateProfitMatrixWith(longCall(), 100, 0.3, MatrixConfig{RangeMode: RangeStdDev, Range: 1, PriceSteps: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	move := 0.3 * math.Sqrt(30.0/365)
+	if math.Abs(resp.Prices[0]-100*math.Exp(-move)) > 0.05 || math.Abs(resp.Prices[2]-100*math.Exp(move)) > 0.05 {
+		t.Errorf("stddev prices %v, want %.2f..%.2f", resp.Prices, 100*math.Exp(-move), 100*math.Exp(move))
+	}
+}
+
+func TestMatrixStepDays(t *testing.T) {
+	resp, err := CalculateProfitMatrixWith(longCall(), 100, 0.3, MatrixConfig{StepDays: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tomorrow plus four weeks, then the expiry
+	if len(resp.Dates) != 6 {
+		t.Errorf("%d dates with weekly steps, want 6: %v", len(resp.Dates), resp.Dates)
+	}
+	if want := longCall().Legs[0].Expiry.Format("2006-01-02"); resp.Dates[len(resp.Dates)-1] != want {
+		t.Errorf("last date %s, want the expiry %s", resp.Dates[len(resp.Dates)-1], want)
+	}
+
+	resp, err = CalculateProfitMatrixWith(longCall(), 100, 0.3, MatrixConfig{StepDays: 0.25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Intraday steps label dates with the time of day
+	if len(resp.Dates) < 100 || len(resp.Dates[0]) != len("2006-01-02 15:04") {
+		t.Errorf("quarter-day steps gave %d dates starting %v", len(resp.Dates), resp.Dates[0])
+	}
+	if _, err := CalculateProfitMatrixWith(longCall(), 100, 0.3, MatrixConfig{StepDays: 0.1}); err == nil {
+		t.Error("tenth-of-a-day steps over 30 days should exceed the date limit")
+	}
+}
+
+func TestMatrixRejectsBadAxes(t *testing.T) {
+	for name, cfg := range map[string]MatrixConfig{
+		"tiny step days":    {StepDays: 1e-300},
+		"too many dates":    {DateSteps: MaxMatrixSteps + 1},
+		"too many prices":   {PriceSteps: MaxMatrixSteps + 1},
+		"one price":         {PriceSteps: 1},
+		"bad date":          {Dates: []string{"2030-13-01"}},
+		"empty range":       {RangeMode: RangeAbsolute, MinPrice: 100, MaxPrice: 100},
+		"unknown range":     {RangeMode: "log"},
+		"unknown output":    {Output: "delta"},
+		"stddev without IV": {RangeMode: RangeStdDev},
+	} {
+		vol := 0.3
+		if name == "stddev without IV" {
+			vol = 0
+		}
+		if _, err := CalculateProfitMatrixWith(longCall(), 100, vol, cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMatrixExplicitDatesAreSorted(t *testing.T) {
+	now := time.Now()
+	later, sooner := now.AddDate(0, 0, 20).Format("2006-01-02"), now.AddDate(0, 0, 5).Format("2006-01-02")
+
+	resp, err := CalculateProfitMatrixWith(longCall(), 100, 0.3, MatrixConfig{Dates: []string{later, sooner}, RangeMode: RangeStdDev, PriceSteps: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Dates[0] != sooner || resp.Dates[1] != later {
+		t.Errorf("dates %v, want [%s %s]", resp.Dates, sooner, later)
+	}
+
+	// The stddev range runs to the latest date, not the last one given
+	move := 2 * 0.3 * math.Sqrt(now.AddDate(0, 0, 20).Sub(now).Hours()/24/365)
+	if got := resp.Prices[2]; got < 100*math.Exp(move)-0.5 {
+		t.Errorf("upper price %v is short of the 20 day 2-sigma move %.2f", got, 100*math.Exp(move))
+	}
+}
+
+func TestMatrixOutputs(t *testing.T) {
+	cfg := MatrixConfig{Range: 10, PriceSteps: 3, DateSteps: 2}
+
+	cfg.Output = MatrixValue
+	value, err := CalculateProfitMatrixWith(longCall(), 100, 0.3, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Output = MatrixPercentRisk
+	cfg.MaxRisk = 300
+	risk, err := CalculateProfitMatrixWith(longCall(), 100, 0.3, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, pt := range value.Grid {
+		// A long call's mark is its P&L plus the 300 paid
+		if math.Abs(pt.Value-(pt.Profit+300)) > 0.02 {
+			t.Errorf("value at %s %v = %v, want profit %v + 300", pt.Date, pt.Price, pt.Value, pt.Profit)
+		}
+		if want := math.Round(pt.Profit/300*1000) / 10; math.Abs(risk.Grid[i].Value-want) > 0.1 {
+			t.Errorf("percentRisk at %s %v = %v, want %v", pt.Date, pt.Price, risk.Grid[i].Value, want)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package calculator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strikelogic/rates"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MarketDataProvider is the source of quotes and option chains used by GetQuote and GetOptionsChain.
+// Expirations are Unix timestamps (seconds), matching the format Yahoo uses for its expiration menu.
+type MarketDataProvider interface {
+	// Quote returns the latest price of the underlying
+	Quote(ticker string) (float64, error)
+	// Expirations lists the available expiration timestamps for the ticker
+	Expirations(ticker string) ([]int64, error)
+	// Chain returns all calls and puts for a single expiration timestamp
+	Chain(ticker string, expiry int64) ([]OptionContract, error)
+}
+
+// Provider names accepted by NewProvider and the MARKET_DATA_PROVIDER env var
+const (
+	ProviderYahoo  = "yahoo"
+	ProviderMock   = "mock"
+	ProviderFile   = "file"
+	ProviderReplay = "replay"
+)
+
+var (
+	providerMu       sync.RWMutex
+	activeProvider   MarketDataProvider = YahooProvider{}
+	fallbackProvider MarketDataProvider = NewMockProvider(time.Now().UnixNano())
+)
+
+// SetProvider swaps the active market data provider.
+// fallback is used when the primary provider fails; pass nil to surface errors instead.
+func SetProvider(primary, fallback MarketDataProvider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	activeProvider = primary
+	fallbackProvider = fallback
+}
+
+// Providers returns the currently configured primary and fallback providers
+func Providers() (primary, fallback MarketDataProvider) {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	return activeProvider, fallbackProvider
+}
+
+// ProviderConfig holds the settings needed to construct any of the built-in providers
+type ProviderConfig struct {
+	Name string
+	Dir  string    // Data directory for the file and replay providers
+	Seed int64     // Seed for the mock provider
+	AsOf time.Time // Replay snapshots captured at or before this time (zero = latest)
+}
+
+// NewProvider builds a provider from its config
+func NewProvider(cfg ProviderConfig) (MarketDataProvider, error) {
+	switch strings.ToLower(cfg.Name) {
+	case "", ProviderYahoo:
+		return YahooProvider{}, nil
+	case ProviderMock:
+		return NewMockProvider(cfg.Seed), nil
+	case ProviderFile:
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("file provider requires a data directory")
+		}
+		return FileProvider{Dir: cfg.Dir}, nil
+	case ProviderReplay:
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("replay provider requires a snapshot directory")
+		}
+		return ReplayProvider{Dir: cfg.Dir, AsOf: cfg.AsOf}, nil
+	}
+	return nil, fmt.Errorf("unknown market data provider: %s", cfg.Name)
+}
+
+// ConfigureProviderFromEnv selects providers from environment variables:
+//
+//	MARKET_DATA_PROVIDER   yahoo (default), mock, file or replay
+//	MARKET_DATA_FALLBACK   provider used when the primary fails; defaults to mock, "none" disables it
+//	MARKET_DATA_DIR        directory for the file and replay providers
+//	MARKET_DATA_SEED       seed for the mock provider (random if unset)
+//	MARKET_DATA_AS_OF      replay snapshots as of this time (RFC 3339 or 2006-01-02)
+//	MARKET_DATA_RECORD_DIR record every Yahoo response into this directory
+func ConfigureProviderFromEnv() error {
+	cfg := ProviderConfig{
+		Name: os.Getenv("MARKET_DATA_PROVIDER"),
+		Dir:  os.Getenv("MARKET_DATA_DIR"),
+		Seed: time.Now().UnixNano(),
+	}
+	if s := os.Getenv("MARKET_DATA_SEED"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid MARKET_DATA_SEED: %v", err)
+		}
+		cfg.Seed = v
+	}
+	if s := os.Getenv("MARKET_DATA_AS_OF"); s != "" {
+		t, err := ParseAsOf(s)
+		if err != nil {
+			return fmt.Errorf("invalid MARKET_DATA_AS_OF: %v", err)
+		}
+		cfg.AsOf = t
+	}
+
+	primary, err := NewProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	var fallback MarketDataProvider
+	fallbackName := os.Getenv("MARKET_DATA_FALLBACK")
+	if fallbackName == "" {
+		fallbackName = ProviderMock
+	}
+	if fallbackName != "none" {
+		fallbackCfg := cfg
+		fallbackCfg.Name = fallbackName
+		fallback, err = NewProvider(fallbackCfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	SetProvider(primary, fallback)
+	SetRecordDir(os.Getenv("MARKET_DATA_RECORD_DIR"))
+	log.Printf("Market data provider: %T (fallback: %T)", primary, fallback)
+	return nil
+}
+
+// ParseAsOf accepts either a full RFC 3339 timestamp or a bare date (end of that day, UTC)
+func ParseAsOf(s string) (time.Time, error) {
+	t, dateOnly, err := parseTimeOrDate(s)
+	if err == nil && dateOnly {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, err
+}
+
+// ParseSince is ParseAsOf for the start of a range: a bare date is the start of that day, UTC
+func ParseSince(s string) (time.Time, error) {
+	t, _, err := parseTimeOrDate(s)
+	return t, err
+}
+
+// parseTimeOrDate parses an RFC 3339 timestamp or a bare date, reporting which it was
+func parseTimeOrDate(s string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	t, err = time.Parse("2006-01-02", s)
+	return t, true, err
+}
+
+// closestExpiration picks the timestamp nearest to targetDate, or the first one if targetDate is zero
+func closestExpiration(expirations []int64, targetDate time.Time) int64 {
+	if len(expirations) == 0 {
+		return 0
+	}
+	if targetDate.IsZero() {
+		return expirations[0]
+	}
+
+	match := expirations[0]
+	minDiff := math.MaxFloat64
+	for _, ts := range expirations {
+		diff := math.Abs(time.Unix(ts, 0).Sub(targetDate).Hours())
+		if diff < minDiff {
+			minDiff = diff
+			match = ts
+		}
+	}
+	return match
+}
+
+// YahooProvider serves live data from Yahoo's query2 options endpoint
+type YahooProvider struct{}
+
+func (YahooProvider) Quote(ticker string) (float64, error) {
+	// The options endpoint includes the quote, so we reuse it rather than
+	// maintaining a separate quote request.
+	res, err := fetchYahooOptions(ticker, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(res.OptionChain.Result) == 0 {
+		return 0, fmt.Errorf("no quote data for %s", ticker)
+	}
+	return res.OptionChain.Result[0].Quote.RegularMarketPrice, nil
+}
+
+func (YahooProvider) Expirations(ticker string) ([]int64, error) {
+	res, err := fetchYahooOptions(ticker, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.OptionChain.Result) == 0 {
+		return nil, fmt.Errorf("no options metadata for %s", ticker)
+	}
+	return res.OptionChain.Result[0].ExpirationDates, nil
+}
+
+func (YahooProvider) Chain(ticker string, expiry int64) ([]OptionContract, error) {
+	res, err := fetchYahooOptions(ticker, expiry)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.OptionChain.Result) == 0 || len(res.OptionChain.Result[0].Options) == 0 {
+		return nil, fmt.Errorf("no options data found for timestamp %d", expiry)
+	}
+	return convertYahooResult(res, ticker, time.Now()), nil
+}
+
+// convertYahooResult flattens the first expiration block of a Yahoo response into our contract format.
+// Yahoo's impliedVolatility is unreliable, so IV is re-solved from the quotes (see solveChainIVs).
+func convertYahooResult(res YahooOptionsResponse, ticker string, asOf time.Time) []OptionContract {
+	data := res.OptionChain.Result[0]
+	optData := data.Options[0]
+	currentPrice := data.Quote.RegularMarketPrice
+	q, dividends := tickerDividendInputs(ticker, asOf)
+
+	var quotes []ivQuote
+	expiration := optData.ExpirationDate
+	for _, c := range optData.Calls {
+		quotes = append(quotes, ivQuote{Type: Call, Strike: c.Strike, Bid: c.Bid, Ask: c.Ask, Last: c.LastPrice, Vendor: c.ImpliedVolatility})
+		expiration = c.Expiration
+	}
+	for _, c := range optData.Puts {
+		quotes = append(quotes, ivQuote{Type: Put, Strike: c.Strike, Bid: c.Bid, Ask: c.Ask, Last: c.LastPrice, Vendor: c.ImpliedVolatility})
+		expiration = c.Expiration
+	}
+
+	T := yearsToExpiry(time.Unix(expiration, 0), asOf)
+	ivs := solveChainIVs(quotes, currentPrice, T, rates.RateFor(T), q, dividends)
+	resolve := func(optType OptionType, strike float64) ivResult {
+		if iv, ok := ivs[ivKey{optType, strike}]; ok {
+			return iv
+		}
+		return ivResult{IV: DefaultIV, Source: IVSourceDefault}
+	}
+
+	var chain []OptionContract
+	for _, call := range optData.Calls {
+		chain = append(chain, convertYahooToContract(call, currentPrice, Call, ticker, asOf, q, dividends, resolve(Call, call.Strike)))
+	}
+	for _, put := range optData.Puts {
+		chain = append(chain, convertYahooToContract(put, currentPrice, Put, ticker, asOf, q, dividends, resolve(Put, put.Strike)))
+	}
+	return chain
+}
+
+// MockProvider generates synthetic Black-Scholes chains.
+// With a fixed seed the generated IVs (and therefore prices) are reproducible.
+type MockProvider struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// mockExpiryDays are the days-to-expiry the mock provider lists as expirations
+var mockExpiryDays = []int{7, 14, 30, 60, 90, 180, 365}
+
+func NewMockProvider(seed int64) *MockProvider {
+	return &MockProvider{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (*MockProvider) Quote(ticker string) (float64, error) {
+	return getMockQuote(ticker)
+}
+
+func (*MockProvider) Expirations(ticker string) ([]int64, error) {
+	var expirations []int64
+	for _, days := range mockExpiryDays {
+		expirations = append(expirations, mockExpiryTime(days).Unix())
+	}
+	return expirations, nil
+}
+
+func (m *MockProvider) Chain(ticker string, expiry int64) ([]OptionContract, error) {
+	daysOut := int(math.Round(time.Until(time.Unix(expiry, 0)).Hours() / 24))
+	if daysOut < 1 {
+		daysOut = 1
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return generateMockChain(ticker, daysOut, m.rng.Float64), nil
+}
+
+// mockExpiryTime returns midnight UTC of the date daysOut days from now, so the
+// timestamp round-trips through the "2006-01-02" expiry format.
+func mockExpiryTime(daysOut int) time.Time {
+	d := time.Now().AddDate(0, 0, daysOut)
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ChainFile is the on-disk format read by FileProvider, one file per ticker
+type ChainFile struct {
+	Ticker    string           `json:"ticker"`
+	Price     float64          `json:"price"`
+	Contracts []OptionContract `json:"contracts"`
+}
+
+// FileProvider serves fixed chains from {Dir}/{TICKER}.json.
+// Useful for tests and demos that must not depend on the network.
+type FileProvider struct {
+	Dir string
+}
+
+func (f FileProvider) load(ticker string) (ChainFile, error) {
+	if err := ValidateTicker(ticker); err != nil {
+		return ChainFile{}, err
+	}
+	path := filepath.Join(f.Dir, strings.ToUpper(ticker)+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ChainFile{}, err
+	}
+
+	var cf ChainFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return ChainFile{}, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return cf, nil
+}
+
+func (f FileProvider) Quote(ticker string) (float64, error) {
+	cf, err := f.load(ticker)
+	if err != nil {
+		return 0, err
+	}
+	return cf.Price, nil
+}
+
+func (f FileProvider) Expirations(ticker string) ([]int64, error) {
+	cf, err := f.load(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool)
+	var expirations []int64
+	for _, c := range cf.Contracts {
+		t, err := time.Parse("2006-01-02", c.Expiry)
+		if err != nil {
+			continue
+		}
+		if !seen[t.Unix()] {
+			seen[t.Unix()] = true
+			expirations = append(expirations, t.Unix())
+		}
+	}
+	sort.Slice(expirations, func(i, j int) bool { return expirations[i] < expirations[j] })
+	return expirations, nil
+}
+
+func (f FileProvider) Chain(ticker string, expiry int64) ([]OptionContract, error) {
+	cf, err := f.load(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	expiryStr := time.Unix(expiry, 0).UTC().Format("2006-01-02")
+	var chain []OptionContract
+	for _, c := range cf.Contracts {
+		if c.Expiry == expiryStr {
+			chain = append(chain, c)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no contracts for %s expiring %s in %s", ticker, expiryStr, f.Dir)
+	}
+	return chain, nil
+}
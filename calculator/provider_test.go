@@ -0,0 +1,105 @@
+package calculator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileProvider(t *testing.T) {
+	p := FileProvider{Dir: "testdata/chains"}
+
+	price, err := p.Quote("xyz")
+	if err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	if price != 100 {
+		t.Errorf("Quote = %v, want 100", price)
+	}
+
+	expirations, err := p.Expirations("XYZ")
+	if err != nil {
+		t.Fatalf("Expirations: %v", err)
+	}
+	jan := time.Date(2030, 1, 18, 0, 0, 0, 0, time.UTC).Unix()
+	mar := time.Date(2030, 3, 15, 0, 0, 0, 0, time.UTC).Unix()
+	if !reflect.DeepEqual(expirations, []int64{jan, mar}) {
+		t.Errorf("Expirations = %v, want [%d %d]", expirations, jan, mar)
+	}
+
+	chain, err := p.Chain("XYZ", jan)
+	if err != nil {
+		t.Fatalf("Chain: %v", err)
+	}
+	if len(chain) != 6 {
+		t.Errorf("Chain returned %d contracts, want 6", len(chain))
+	}
+	for _, c := range chain {
+		if c.Expiry != "2030-01-18" {
+			t.Errorf("Chain returned contract expiring %s", c.Expiry)
+		}
+	}
+
+	if _, err := p.Chain("XYZ", time.Date(2030, 6, 21, 0, 0, 0, 0, time.UTC).Unix()); err == nil {
+		t.Error("Chain for an unlisted expiry should fail")
+	}
+	if _, err := p.Quote("NOPE"); err == nil {
+		t.Error("Quote for a ticker without a file should fail")
+	}
+}
+
+func TestGetOptionsChainFromFile(t *testing.T) {
+	primary, fallback := Providers()
+	defer SetProvider(primary, fallback)
+	SetProvider(FileProvider{Dir: "testdata/chains"}, nil)
+
+	chain, err := GetOptionsChain("XYZ", "2030-03-01")
+	if err != nil {
+		t.Fatalf("GetOptionsChain: %v", err)
+	}
+	if len(chain) != 2 || chain[0].Expiry != "2030-03-15" {
+		t.Errorf("expected the 2 contracts of the closest expiry 2030-03-15, got %+v", chain)
+	}
+
+	if _, err := GetOptionsChain("NOPE", ""); err == nil {
+		t.Error("expected an error with no fallback provider")
+	}
+}
+
+func TestMockProviderDeterministic(t *testing.T) {
+	a, b := NewMockProvider(42), NewMockProvider(42)
+	expirations, err := a.Expirations("AAPL")
+	if err != nil || len(expirations) == 0 {
+		t.Fatalf("Expirations: %v %v", expirations, err)
+	}
+
+	chainA, _ := a.Chain("AAPL", expirations[2])
+	chainB, _ := b.Chain("AAPL", expirations[2])
+	if len(chainA) == 0 || !reflect.DeepEqual(chainA, chainB) {
+		t.Error("mock chains with the same seed differ")
+	}
+
+	chainC, _ := NewMockProvider(7).Chain("AAPL", expirations[2])
+	if reflect.DeepEqual(chainA, chainC) {
+		t.Error("mock chains with different seeds are identical")
+	}
+}
+
+func TestParseAsOfAndSince(t *testing.T) {
+	asOf, err := ParseAsOf("2030-01-18")
+	if err != nil || asOf.Format(time.RFC3339) != "2030-01-18T23:59:59Z" {
+		t.Errorf("ParseAsOf(date) = %v, %v; want the end of the day", asOf, err)
+	}
+	since, err := ParseSince("2030-01-18")
+	if err != nil || since.Format(time.RFC3339) != "2030-01-18T00:00:00Z" {
+		t.Errorf("ParseSince(date) = %v, %v; want the start of the day", since, err)
+	}
+	for _, parse := range []func(string) (time.Time, error){ParseAsOf, ParseSince} {
+		if ts, err := parse("2030-01-18T15:04:05-05:00"); err != nil || !ts.Equal(time.Date(2030, 1, 18, 20, 4, 5, 0, time.UTC)) {
+			t.Errorf("RFC 3339 timestamp parsed as %v, %v", ts, err)
+		}
+		if _, err := parse("18/01/2030"); err == nil {
+			t.Error("expected an error for an unknown format")
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"strikelogic/rates"
+	"time"
+)
+
+// VolShift moves the IVs of one expiry: Level vol points everywhere, plus Skew vol points per 10% of
+// log-moneyness (ln(K/S)), so a negative Skew lifts puts and lowers calls.
+type VolShift struct {
+	Level float64 `json:"level"`
+	Skew  float64 `json:"skew"`
+}
+
+// ScenarioConfig is a MatrixConfig with a third axis of IV shifts
+type ScenarioConfig struct {
+	MatrixConfig
+	VolShifts    []float64           `json:"volShifts"`    // Vol points added to every leg, e.g. [-10, -5, 0, 5, 10]; defaults to [0]
+	ExpiryShifts map[string]VolShift `json:"expiryShifts"` // Extra shifts by leg expiry (YYYY-MM-DD), e.g. crushing only the earnings month
+}
+
+// ScenarioPoint is the position's P&L and greeks at one price, date and IV shift.
+// Greeks are position totals in dollars: Delta in shares, Theta per day, Vega per vol point, Rho per 1% rate.
+type ScenarioPoint struct {
+	Date     string  `json:"date"`
+	Price    float64 `json:"price"`
+	VolShift float64 `json:"volShift"`
+	Profit   float64 `json:"profit"`
+	Delta    float64 `json:"delta"`
+	Gamma    float64 `json:"gamma"`
+	Theta    float64 `json:"theta"`
+	Vega     float64 `json:"vega"`
+	Rho      float64 `json:"rho"`
+}
+
+// ScenarioResponse holds the scenario cube and its axes.
+// Points are ordered by vol shift, then date, then price.
+type ScenarioResponse struct {
+	Points    []ScenarioPoint `json:"points"`
+	Dates     []string        `json:"dates"`
+	Prices    []float64       `json:"prices"`
+	VolShifts []float64       `json:"volShifts"`
+	Model     string          `json:"model"`
+}
+
+// MinScenarioVol floors shifted IVs so a large crush can't reach zero
+const MinScenarioVol = 0.01
+
+// rhoBump is the rate move used to measure rho by repricing
+const rhoBump = 0.0005
+
+// CalculateScenarios revalues the strategy over price, date and IV shift, reporting P&L and greeks at each point.
+// Legs are priced as in CalculateProfitMatrix before the shifts are applied.
+func CalculateScenarios(strategy StrategyInput, currentPrice, volatility float64, cfg ScenarioConfig) (ScenarioResponse, error) {
+	var expiryDate time.Time
+	for _, leg := range strategy.Legs {
+		if leg.Expiry.After(expiryDate) {
+			expiryDate = leg.Expiry
+		}
+	}
+	if expiryDate.IsZero() {
+		expiryDate = time.Now().AddDate(0, 0, 30)
+	}
+
+	dates, intraday, err := matrixDates(cfg.MatrixConfig, expiryDate)
+	if err != nil {
+		return ScenarioResponse{}, err
+	}
+	prices, err := matrixPrices(cfg.MatrixConfig, currentPrice, volatility, dates[len(dates)-1])
+	if err != nil {
+		return ScenarioResponse{}, err
+	}
+
+	shifts := cfg.VolShifts
+	if len(shifts) == 0 {
+		shifts = []float64{0}
+	}
+	if len(shifts)*len(dates)*len(prices) > MaxMatrixSteps*MaxMatrixSteps {
+		return ScenarioResponse{}, fmt.Errorf("scenario grid too large")
+	}
+
+	model := strategy.Model
+	if model == nil {
+		model = DefaultModel()
+	}
+
+	dateFormat := "2006-01-02"
+	if intraday {
+		dateFormat = "2006-01-02 15:04"
+	}
+
+	resp := ScenarioResponse{VolShifts: shifts, Model: model.Name()}
+	for _, shift := range shifts {
+		for _, d := range dates {
+			q, dividends := DividendInputs(strategy.DividendYield, strategy.Dividends, d)
+
+			for _, p := range prices {
+				pt := ScenarioPoint{Date: d.Format(dateFormat), Price: math.Round(p*100) / 100, VolShift: shift}
+
+				for _, leg := range strategy.Legs {
+					sign := 1.0
+					if leg.Action != "Buy" {
+						sign = -1
+					}
+
+					if leg.IsStock {
+						pt.Profit += sign * (p - leg.EntryPrice) * leg.Quantity
+						pt.Delta += sign * leg.Quantity
+						continue
+					}
+
+					mult := sign * leg.Quantity * 100
+					T := leg.Expiry.Sub(d).Hours() / 24 / 365.0
+					if T <= 0 {
+						intrinsic, delta := math.Max(0, p-leg.Strike), 0.0
+						if p > leg.Strike {
+							delta = 1
+						}
+						if leg.Type == Put {
+							intrinsic = math.Max(0, leg.Strike-p)
+							delta = 0
+							if p < leg.Strike {
+								delta = -1
+							}
+						}
+						pt.Profit += mult * (intrinsic - leg.EntryPrice)
+						pt.Delta += mult * delta
+						continue
+					}
+
+					sigma := strategy.legVol(leg, T, volatility) + shiftFor(cfg, shift, leg, currentPrice)/100
+					sigma = math.Max(sigma, MinScenarioVol)
+
+					in := PricingInput{Type: leg.Type, S: p, K: leg.Strike, T: T, R: rates.RateFor(T), Sigma: sigma, Q: q, Dividends: dividends}
+					res := model.Price(in)
+
+					up, down := in, in
+					up.R += rhoBump
+					down.R -= rhoBump
+					rho := (model.Price(up).Price - model.Price(down).Price) / (2 * rhoBump) / 100
+
+					pt.Profit += mult * (res.Price - leg.EntryPrice)
+					pt.Delta += mult * res.Delta
+					pt.Gamma += mult * res.Gamma
+					pt.Theta += mult * res.Theta
+					pt.Vega += mult * res.Vega
+					pt.Rho += mult * rho
+				}
+
+				pt.Profit = math.Round(pt.Profit*100) / 100
+				pt.Delta = math.Round(pt.Delta*100) / 100
+				pt.Gamma = math.Round(pt.Gamma*1000) / 1000
+				pt.Theta = math.Round(pt.Theta*100) / 100
+				pt.Vega = math.Round(pt.Vega*100) / 100
+				pt.Rho = math.Round(pt.Rho*100) / 100
+				resp.Points = append(resp.Points, pt)
+			}
+		}
+	}
+
+	for _, d := range dates {
+		resp.Dates = append(resp.Dates, d.Format(dateFormat))
+	}
+	for _, p := range prices {
+		resp.Prices = append(resp.Prices, math.Round(p*100)/100)
+	}
+	return resp, nil
+}
+
+// shiftFor is the total IV shift in vol points for a leg: the axis shift plus its expiry's level and skew
+func shiftFor(cfg ScenarioConfig, shift float64, leg LegInput, currentPrice float64) float64 {
+	es, ok := cfg.ExpiryShifts[leg.Expiry.Format("2006-01-02")]
+	if !ok {
+		return shift
+	}
+	total := shift + es.Level
+	if es.Skew != 0 && currentPrice > 0 && leg.Strike > 0 {
+		total += es.Skew * math.Log(leg.Strike/currentPrice) / 0.1
+	}
+	return total
+}
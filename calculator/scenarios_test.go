@@ -0,0 +1,49 @@
+package calculator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScenariosRejectBadDateAxes(t *testing.T) {
+	for name, cfg := range map[string]MatrixConfig{
+		"tiny step days": {StepDays: 1e-300},
+		"too many dates": {DateSteps: MaxMatrixSteps + 1},
+		"bad date":       {Dates: []string{"not a date"}},
+	} {
+		if _, err := CalculateScenarios(longCall(), 100, 0.3, ScenarioConfig{MatrixConfig: cfg}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestScenarioVolShifts(t *testing.T) {
+	cfg := ScenarioConfig{MatrixConfig: MatrixConfig{Range: 10, PriceSteps: 3, DateSteps: 2}, VolShifts: []float64{-10, 0, 10}}
+	resp, err := CalculateScenarios(longCall(), 100, 0.3, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Points) != 3*2*3 {
+		t.Fatalf("%d points, want 18", len(resp.Points))
+	}
+
+	// Points are ordered by shift, then date, then price: compare the first date at spot across shifts
+	at := func(shift int) ScenarioPoint { return resp.Points[shift*6+1] }
+	if !(at(0).Profit < at(1).Profit && at(1).Profit < at(2).Profit) {
+		t.Errorf("a long call should gain with IV: %v, %v, %v", at(0).Profit, at(1).Profit, at(2).Profit)
+	}
+	if at(1).Vega <= 0 || at(1).Theta >= 0 {
+		t.Errorf("long call vega %v and theta %v", at(1).Vega, at(1).Theta)
+	}
+
+	// Unshifted points agree with the profit matrix on the same axes
+	matrix, err := CalculateProfitMatrixWith(longCall(), 100, 0.3, cfg.MatrixConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, pt := range matrix.Grid {
+		if got := resp.Points[6+i].Profit; math.Abs(got-pt.Profit) > 0.02 {
+			t.Errorf("scenario at %s %v = %v, matrix %v", pt.Date, pt.Price, got, pt.Profit)
+		}
+	}
+}
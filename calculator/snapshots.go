@@ -0,0 +1,220 @@
+package calculator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot identifies one recorded Yahoo options response on disk.
+// Files live at {dir}/{TICKER}/{expiry}-{capturedUnix}.json, where expiry 0 is the
+// metadata request (quote + expiration menu) and any other value is a single-expiry chain.
+type Snapshot struct {
+	Ticker     string    `json:"ticker"`
+	Expiry     int64     `json:"expiry"`
+	CapturedAt time.Time `json:"capturedAt"`
+	Path       string    `json:"path"`
+}
+
+var (
+	recordMu  sync.RWMutex
+	recordDir string
+)
+
+// tickerPattern is what a ticker may contain before it is used in a file path, e.g. BRK.B or ^SPX
+var tickerPattern = regexp.MustCompile(`^[A-Z0-9.^-]+$`)
+
+// ValidateTicker rejects tickers that could escape a data directory, such as ones with slashes or "..".
+// The ticker is checked upper-cased, as it is stored.
+func ValidateTicker(ticker string) error {
+	ticker = strings.ToUpper(ticker)
+	if !tickerPattern.MatchString(ticker) || strings.Contains(ticker, "..") || ticker == "." {
+		return fmt.Errorf("invalid ticker %q", ticker)
+	}
+	return nil
+}
+
+// SetRecordDir turns on recording of every Yahoo response into dir. An empty dir disables recording.
+func SetRecordDir(dir string) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	recordDir = dir
+	if dir != "" {
+		log.Printf("Recording option chain snapshots to %s", dir)
+	}
+}
+
+func recordSnapshot(ticker string, expiry int64, body []byte) {
+	recordMu.RLock()
+	dir := recordDir
+	recordMu.RUnlock()
+
+	if dir == "" {
+		return
+	}
+	if _, err := SaveSnapshot(dir, ticker, expiry, time.Now(), body); err != nil {
+		log.Printf("Failed to record snapshot for %s (%d): %v", ticker, expiry, err)
+	}
+}
+
+// SaveSnapshot writes a raw Yahoo options payload to the snapshot directory
+func SaveSnapshot(dir, ticker string, expiry int64, capturedAt time.Time, body []byte) (Snapshot, error) {
+	if err := ValidateTicker(ticker); err != nil {
+		return Snapshot{}, err
+	}
+	ticker = strings.ToUpper(ticker)
+	tickerDir := filepath.Join(dir, ticker)
+	if err := os.MkdirAll(tickerDir, 0o755); err != nil {
+		return Snapshot{}, err
+	}
+
+	path := filepath.Join(tickerDir, fmt.Sprintf("%d-%d.json", expiry, capturedAt.Unix()))
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{Ticker: ticker, Expiry: expiry, CapturedAt: capturedAt, Path: path}, nil
+}
+
+// ListSnapshots returns every recorded snapshot for a ticker, oldest first
+func ListSnapshots(dir, ticker string) ([]Snapshot, error) {
+	if err := ValidateTicker(ticker); err != nil {
+		return nil, err
+	}
+	ticker = strings.ToUpper(ticker)
+	entries, err := os.ReadDir(filepath.Join(dir, ticker))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		var expiry, captured int64
+		if _, err := fmt.Sscanf(strings.TrimSuffix(e.Name(), ".json"), "%d-%d", &expiry, &captured); err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			Ticker:     ticker,
+			Expiry:     expiry,
+			CapturedAt: time.Unix(captured, 0),
+			Path:       filepath.Join(dir, ticker, e.Name()),
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CapturedAt.Before(snapshots[j].CapturedAt)
+	})
+	return snapshots, nil
+}
+
+// LoadSnapshot decodes a recorded payload
+func LoadSnapshot(s Snapshot) (YahooOptionsResponse, error) {
+	var res YahooOptionsResponse
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return res, err
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return res, fmt.Errorf("failed to parse snapshot %s: %v", s.Path, err)
+	}
+	return res, nil
+}
+
+// ReplayProvider serves recorded snapshots back through the MarketDataProvider interface.
+// For each request it uses the latest snapshot captured at or before AsOf (or the latest overall
+// if AsOf is zero), and computes greeks as of the capture time so output is reproducible.
+type ReplayProvider struct {
+	Dir  string
+	AsOf time.Time
+}
+
+// visible returns the snapshots captured no later than AsOf, oldest first
+func (p ReplayProvider) visible(ticker string) ([]Snapshot, error) {
+	all, err := ListSnapshots(p.Dir, ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, s := range all {
+		if p.AsOf.IsZero() || !s.CapturedAt.After(p.AsOf) {
+			snapshots = append(snapshots, s)
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshots for %s in %s", ticker, p.Dir)
+	}
+	return snapshots, nil
+}
+
+func (p ReplayProvider) Quote(ticker string) (float64, error) {
+	snapshots, err := p.visible(ticker)
+	if err != nil {
+		return 0, err
+	}
+
+	// Every payload carries the quote, so the most recent one of any kind will do
+	res, err := LoadSnapshot(snapshots[len(snapshots)-1])
+	if err != nil {
+		return 0, err
+	}
+	if len(res.OptionChain.Result) == 0 {
+		return 0, fmt.Errorf("snapshot for %s has no quote", ticker)
+	}
+	return res.OptionChain.Result[0].Quote.RegularMarketPrice, nil
+}
+
+// Expirations lists only the expirations that were actually recorded, since those are all we can serve
+func (p ReplayProvider) Expirations(ticker string) ([]int64, error) {
+	snapshots, err := p.visible(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool)
+	var expirations []int64
+	for _, s := range snapshots {
+		if s.Expiry == 0 || seen[s.Expiry] {
+			continue
+		}
+		seen[s.Expiry] = true
+		expirations = append(expirations, s.Expiry)
+	}
+	sort.Slice(expirations, func(i, j int) bool { return expirations[i] < expirations[j] })
+	return expirations, nil
+}
+
+func (p ReplayProvider) Chain(ticker string, expiry int64) ([]OptionContract, error) {
+	snapshots, err := p.visible(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	var match *Snapshot
+	for i := range snapshots {
+		if snapshots[i].Expiry == expiry {
+			match = &snapshots[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no snapshot for %s expiring %d", ticker, expiry)
+	}
+
+	res, err := LoadSnapshot(*match)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.OptionChain.Result) == 0 || len(res.OptionChain.Result[0].Options) == 0 {
+		return nil, fmt.Errorf("snapshot %s has no options data", match.Path)
+	}
+	return convertYahooResult(res, strings.ToUpper(ticker), match.CapturedAt), nil
+}
@@ -0,0 +1,150 @@
+package calculator
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const replayExpiry = 1894924800 // 2030-01-18
+
+var (
+	firstCapture  = time.Unix(1891004400, 0) // 2029-12-03 15:00 UTC, spot 100
+	secondCapture = time.Unix(1891609200, 0) // 2029-12-10 15:00 UTC, spot 104
+)
+
+func TestListSnapshots(t *testing.T) {
+	snapshots, err := ListSnapshots("testdata/snapshots", "xyz")
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snapshots) != 4 {
+		t.Fatalf("got %d snapshots, want 4", len(snapshots))
+	}
+	for i := 1; i < len(snapshots); i++ {
+		if snapshots[i].CapturedAt.Before(snapshots[i-1].CapturedAt) {
+			t.Errorf("snapshots not sorted oldest first: %v", snapshots)
+		}
+	}
+}
+
+func TestReplayProviderQuoteAsOf(t *testing.T) {
+	tests := []struct {
+		asOf time.Time
+		want float64
+	}{
+		{time.Time{}, 104},
+		{firstCapture, 100},
+		{secondCapture.Add(-time.Second), 100},
+		{secondCapture, 104},
+	}
+	for _, tt := range tests {
+		price, err := ReplayProvider{Dir: "testdata/snapshots", AsOf: tt.asOf}.Quote("XYZ")
+		if err != nil {
+			t.Fatalf("Quote as of %v: %v", tt.asOf, err)
+		}
+		if price != tt.want {
+			t.Errorf("Quote as of %v = %v, want %v", tt.asOf, price, tt.want)
+		}
+	}
+
+	if _, err := (ReplayProvider{Dir: "testdata/snapshots", AsOf: firstCapture.Add(-time.Hour)}).Quote("XYZ"); err == nil {
+		t.Error("Quote before the first capture should fail")
+	}
+}
+
+func TestReplayProviderChain(t *testing.T) {
+	p := ReplayProvider{Dir: "testdata/snapshots", AsOf: firstCapture}
+
+	expirations, err := p.Expirations("XYZ")
+	if err != nil {
+		t.Fatalf("Expirations: %v", err)
+	}
+	if !reflect.DeepEqual(expirations, []int64{replayExpiry}) {
+		t.Errorf("Expirations = %v, want [%d]", expirations, replayExpiry)
+	}
+
+	chain, err := p.Chain("XYZ", replayExpiry)
+	if err != nil {
+		t.Fatalf("Chain: %v", err)
+	}
+	if len(chain) != 3 {
+		t.Fatalf("got %d contracts, want 3", len(chain))
+	}
+	for _, c := range chain {
+		if c.Underlying != "XYZ" || c.Expiry != "2030-01-18" {
+			t.Errorf("unexpected contract %+v", c)
+		}
+		if c.Vol <= 0 || c.IVSource == IVSourceDefault {
+			t.Errorf("%s %.0f: IV not solved from the recorded quotes (%v, %s)", c.Type, c.Strike, c.Vol, c.IVSource)
+		}
+		if c.Strike == 100 && c.Type == Call && (c.Volume != 450 || c.OpenInterest != 2100) {
+			t.Errorf("volume and open interest not carried: %+v", c)
+		}
+	}
+
+	// Greeks are computed as of the capture time, so replaying is reproducible
+	again, _ := p.Chain("XYZ", replayExpiry)
+	if !reflect.DeepEqual(chain, again) {
+		t.Error("replaying the same snapshot gave different chains")
+	}
+
+	later, err := ReplayProvider{Dir: "testdata/snapshots"}.Chain("XYZ", replayExpiry)
+	if err != nil {
+		t.Fatalf("Chain (latest): %v", err)
+	}
+	if reflect.DeepEqual(chain, later) {
+		t.Error("latest replay should serve the second capture")
+	}
+
+	if _, err := p.Chain("XYZ", replayExpiry+86400); err == nil {
+		t.Error("Chain for an unrecorded expiry should fail")
+	}
+}
+
+func TestSaveSnapshotRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	body, err := os.ReadFile("testdata/snapshots/XYZ/0-1891004400.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := SaveSnapshot(dir, "xyz", 0, firstCapture, body)
+	if err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+	listed, err := ListSnapshots(dir, "XYZ")
+	if err != nil || len(listed) != 1 || listed[0].Path != s.Path || !listed[0].CapturedAt.Equal(firstCapture) {
+		t.Fatalf("ListSnapshots after save = %+v, %v", listed, err)
+	}
+
+	price, err := ReplayProvider{Dir: dir}.Quote("XYZ")
+	if err != nil || price != 100 {
+		t.Errorf("Quote from saved snapshot = %v, %v", price, err)
+	}
+}
+
+func TestValidateTicker(t *testing.T) {
+	for _, ticker := range []string{"SPY", "brk.b", "^SPX", "BF-B", "X1"} {
+		if err := ValidateTicker(ticker); err != nil {
+			t.Errorf("ValidateTicker(%q) = %v", ticker, err)
+		}
+	}
+
+	dir := t.TempDir()
+	for _, ticker := range []string{"", ".", "..", "../etc", "A/B", `A\B`, "SPY ", "A..B"} {
+		if err := ValidateTicker(ticker); err == nil {
+			t.Errorf("ValidateTicker(%q) should fail", ticker)
+		}
+		if _, err := SaveSnapshot(dir, ticker, 0, firstCapture, []byte("{}")); err == nil {
+			t.Errorf("SaveSnapshot accepted ticker %q", ticker)
+		}
+		if _, err := ListSnapshots(dir, ticker); err == nil {
+			t.Errorf("ListSnapshots accepted ticker %q", ticker)
+		}
+		if _, err := (FileProvider{Dir: "testdata/chains"}).Quote(ticker); err == nil {
+			t.Errorf("FileProvider accepted ticker %q", ticker)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package chainhistory
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strikelogic/calculator"
+	"strikelogic/storage"
+	"strings"
+	"time"
+)
+
+// MaxCaptureDTE bounds how far out expirations are captured, so a single run stays within Yahoo's rate limits
+const MaxCaptureDTE = 400
+
+// Watchlist returns the tickers to snapshot, read from the comma separated SNAPSHOT_WATCHLIST env var.
+// It is empty when the variable is not set, so nothing polls the provider unless asked to.
+func Watchlist() []string {
+	env := os.Getenv("SNAPSHOT_WATCHLIST")
+	if env == "" {
+		return nil
+	}
+
+	var tickers []string
+	for _, t := range strings.Split(env, ",") {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t != "" {
+			tickers = append(tickers, t)
+		}
+	}
+	return tickers
+}
+
+// CaptureChain stores the current quote and every expiration within MaxCaptureDTE for ticker.
+// Only the primary provider is used: fallback (mock) data must never end up in history.
+func CaptureChain(ticker string) error {
+	provider, _ := calculator.Providers()
+	capturedAt := time.Now()
+
+	price, err := provider.Quote(ticker)
+	if err != nil {
+		return fmt.Errorf("quote: %v", err)
+	}
+
+	chain, err := calculator.GetFullChainFrom(provider, ticker, 0, MaxCaptureDTE)
+	if err != nil {
+		return fmt.Errorf("chain: %v", err)
+	}
+	rows := ToOptionQuotes(chain, capturedAt)
+
+	quote := storage.UnderlyingQuote{Ticker: ticker, Price: price, CapturedAt: capturedAt}
+	if err := storage.SaveChainSnapshot(quote, rows); err != nil {
+		return err
+	}
+
+	log.Printf("Captured %d contracts for %s at %.2f", len(rows), ticker, price)
+	return nil
+}
+
+// Run captures the watchlist every interval while the market is open. It never returns.
+func Run(tickers []string, interval time.Duration) {
+	for {
+		if !calculator.IsMarketOpen(time.Now()) {
+			time.Sleep(interval)
+			continue
+		}
+		for _, ticker := range tickers {
+			if err := CaptureChain(ticker); err != nil {
+				log.Printf("Error capturing chain for %s: %v", ticker, err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// ToOptionQuotes converts live contracts into storage rows
+func ToOptionQuotes(chain []calculator.OptionContract, capturedAt time.Time) []storage.OptionQuote {
+	rows := make([]storage.OptionQuote, 0, len(chain))
+	for _, c := range chain {
+		rows = append(rows, storage.OptionQuote{
+			Ticker:     c.Underlying,
+			Expiry:     c.Expiry,
+			Type:       string(c.Type),
+			Strike:     c.Strike,
+			Bid:        c.Bid,
+			Ask:        c.Ask,
+			Last:       c.Last,
+			IV:         c.Vol,
+			Delta:      c.Delta,
+			Gamma:      c.Gamma,
+			Theta:      c.Theta,
+			Vega:       c.Vega,
+			CapturedAt: capturedAt,
+		})
+	}
+	return rows
+}
+
+// ToContracts converts stored rows back into the calculator format used by strategies
+func ToContracts(rows []storage.OptionQuote) []calculator.OptionContract {
+	chain := make([]calculator.OptionContract, 0, len(rows))
+	for _, r := range rows {
+		chain = append(chain, calculator.OptionContract{
+			Strike:     r.Strike,
+			Expiry:     r.Expiry,
+			Type:       calculator.OptionType(r.Type),
+			Bid:        r.Bid,
+			Ask:        r.Ask,
+			Last:       r.Last,
+			Vol:        r.IV,
+			Delta:      r.Delta,
+			Gamma:      r.Gamma,
+			Theta:      r.Theta,
+			Vega:       r.Vega,
+			Underlying: r.Ticker,
+		})
+	}
+	return chain
+}
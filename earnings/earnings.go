@@ -0,0 +1,361 @@
+package earnings
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strikelogic/calculator"
+	"strikelogic/storage"
+	"strikelogic/strategies"
+	"time"
+)
+
+// Analysis settings
+const (
+	HistoryYears = 3    // How far back past reports are compared
+	RichRatio    = 1.15 // Implied move above this multiple of the average realized move favors selling premium
+	CheapRatio   = 0.85 // Implied move below this multiple favors buying it
+)
+
+// Verdicts comparing the implied move with realized history
+const (
+	VerdictRich  = "rich"
+	VerdictCheap = "cheap"
+	VerdictFair  = "fair"
+)
+
+// ImpliedMove is the move priced into the ATM straddle of the first expiry after the report
+type ImpliedMove struct {
+	Expiry      string  `json:"expiry"`
+	Strike      float64 `json:"strike"`
+	CallMid     float64 `json:"callMid"`
+	PutMid      float64 `json:"putMid"`
+	Straddle    float64 `json:"straddle"`    // Dollars per share
+	MovePercent float64 `json:"movePercent"` // Straddle / spot
+	IV          float64 `json:"iv"`          // Average of the straddle legs' IVs
+}
+
+// Move is the realized move over one past report
+type Move struct {
+	Date        string  `json:"date"`
+	Timing      string  `json:"timing"`
+	Before      float64 `json:"before,omitempty"` // Close before the report
+	After       float64 `json:"after,omitempty"`  // Close after the report
+	MovePercent float64 `json:"movePercent"`      // Signed
+	Source      string  `json:"source"`           // "history" (stored closes) or "calendar" (the source's recorded move)
+}
+
+// History summarizes realized moves over past reports
+type History struct {
+	Moves     []Move  `json:"moves"`
+	AvgAbs    float64 `json:"avgAbs"`
+	MedianAbs float64 `json:"medianAbs"`
+	MaxAbs    float64 `json:"maxAbs"`
+}
+
+// Suggestion is an earnings structure built from the recipe machinery, with why it fits
+type Suggestion struct {
+	strategies.Trade
+	Rationale string `json:"rationale"`
+}
+
+// Analysis compares the implied earnings move with history and suggests structures
+type Analysis struct {
+	Event       storage.EarningsEvent `json:"event"`
+	Spot        float64               `json:"spot"`
+	Implied     ImpliedMove           `json:"implied"`
+	History     History               `json:"history"`
+	Ratio       float64               `json:"ratio,omitempty"` // Implied move / average realized move
+	Verdict     string                `json:"verdict,omitempty"`
+	Suggestions []Suggestion          `json:"suggestions"`
+}
+
+// Upcoming returns reports between today and days from now, by date
+func Upcoming(ticker string, days int) ([]storage.EarningsEvent, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return storage.GetEarnings(ticker, today, today.AddDate(0, 0, days))
+}
+
+// NextEvent returns the ticker's next report on or after today
+func NextEvent(ticker string) (storage.EarningsEvent, error) {
+	events, err := Upcoming(ticker, 365)
+	if err != nil {
+		return storage.EarningsEvent{}, err
+	}
+	if len(events) == 0 {
+		return storage.EarningsEvent{}, fmt.Errorf("no upcoming earnings for %s", ticker)
+	}
+	return events[0], nil
+}
+
+// Analyze prices the implied move for event, compares it with the ticker's past reports and builds
+// the earnings structures, ordered by how well they fit the comparison.
+// Quotes come from the configured provider only: an implied move priced off mock data means nothing.
+func Analyze(event storage.EarningsEvent) (Analysis, error) {
+	provider, _ := calculator.Providers()
+	spot, err := provider.Quote(event.Ticker)
+	if err != nil {
+		return Analysis{}, fmt.Errorf("quote: %v", err)
+	}
+
+	chain, err := eventChain(provider, event)
+	if err != nil {
+		return Analysis{}, err
+	}
+	implied, err := Implied(chain, spot)
+	if err != nil {
+		return Analysis{}, err
+	}
+
+	history, err := HistoricalMoves(event.Ticker, event.Date)
+	if err != nil {
+		return Analysis{}, err
+	}
+
+	a := Analysis{Event: event, Spot: spot, Implied: implied, History: history}
+	if history.AvgAbs > 0 {
+		a.Ratio = round(implied.MovePercent/history.AvgAbs, 100)
+		switch {
+		case a.Ratio >= RichRatio:
+			a.Verdict = VerdictRich
+		case a.Ratio <= CheapRatio:
+			a.Verdict = VerdictCheap
+		default:
+			a.Verdict = VerdictFair
+		}
+	}
+
+	a.Suggestions, err = suggest(a, chain)
+	if err != nil {
+		return Analysis{}, err
+	}
+	return a, nil
+}
+
+// eventChain fetches the expiries around the report, from its date to a few weeks after
+func eventChain(provider calculator.MarketDataProvider, event storage.EarningsEvent) ([]calculator.OptionContract, error) {
+	dte := int(math.Max(0, math.Floor(time.Until(event.Date).Hours()/24)))
+	chain, err := calculator.GetFullChainFrom(provider, event.Ticker, dte, dte+10)
+	if err != nil {
+		chain, err = calculator.GetFullChainFrom(provider, event.Ticker, dte, dte+45)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("chain: %v", err)
+	}
+
+	// Drop expiries the report doesn't fall before: after-close reports need the next day's expiry
+	first := event.Date.Format("2006-01-02")
+	var covering []calculator.OptionContract
+	for _, c := range chain {
+		if c.Expiry > first || (c.Expiry == first && event.Timing == storage.BeforeOpen) {
+			covering = append(covering, c)
+		}
+	}
+	if len(covering) == 0 {
+		return nil, fmt.Errorf("no expiry after the %s report on %s", event.Ticker, first)
+	}
+	return covering, nil
+}
+
+// Implied prices the ATM straddle of the chain's first expiry
+func Implied(chain []calculator.OptionContract, spot float64) (ImpliedMove, error) {
+	expiry := ""
+	for _, c := range chain {
+		if expiry == "" || c.Expiry < expiry {
+			expiry = c.Expiry
+		}
+	}
+
+	calls := map[float64]calculator.OptionContract{}
+	puts := map[float64]calculator.OptionContract{}
+	for _, c := range chain {
+		if c.Expiry != expiry {
+			continue
+		}
+		if c.Type == calculator.Call {
+			calls[c.Strike] = c
+		} else {
+			puts[c.Strike] = c
+		}
+	}
+
+	best := math.Inf(1)
+	var im ImpliedMove
+	for strike, call := range calls {
+		put, ok := puts[strike]
+		dist := math.Abs(strike - spot)
+		if !ok || dist > best || (dist == best && strike > im.Strike) {
+			continue
+		}
+		callMid, putMid := mid(call), mid(put)
+		if callMid <= 0 || putMid <= 0 {
+			continue
+		}
+		best = dist
+		im = ImpliedMove{Expiry: expiry, Strike: strike, CallMid: callMid, PutMid: putMid, IV: round((call.Vol+put.Vol)/2, 1000)}
+	}
+	if im.Expiry == "" {
+		return ImpliedMove{}, fmt.Errorf("no priced ATM straddle for %s", expiry)
+	}
+
+	im.Straddle = round(im.CallMid+im.PutMid, 100)
+	im.MovePercent = round(im.Straddle/spot*100, 100)
+	return im, nil
+}
+
+// mid is the quote midpoint, or the last price without a two-sided market
+func mid(c calculator.OptionContract) float64 {
+	if c.Bid > 0 && c.Ask > 0 {
+		return round((c.Bid+c.Ask)/2, 100)
+	}
+	return c.Last
+}
+
+// HistoricalMoves measures the ticker's realized moves over reports in the HistoryYears before `before`.
+// Moves come from stored daily closes around each report, falling back to the move recorded by the source.
+func HistoricalMoves(ticker string, before time.Time) (History, error) {
+	events, err := storage.GetEarnings(ticker, before.AddDate(-HistoryYears, 0, 0), before.AddDate(0, 0, -1))
+	if err != nil {
+		return History{}, err
+	}
+
+	h := History{Moves: []Move{}}
+	var abs []float64
+	for _, e := range events {
+		m, ok, err := realizedMove(e)
+		if err != nil {
+			return History{}, err
+		}
+		if !ok {
+			continue
+		}
+		h.Moves = append(h.Moves, m)
+		abs = append(abs, math.Abs(m.MovePercent))
+	}
+	if len(abs) == 0 {
+		return h, nil
+	}
+
+	sort.Float64s(abs)
+	sum := 0.0
+	for _, v := range abs {
+		sum += v
+	}
+	h.AvgAbs = round(sum/float64(len(abs)), 100)
+	h.MaxAbs = abs[len(abs)-1]
+	if n := len(abs); n%2 == 1 {
+		h.MedianAbs = abs[n/2]
+	} else {
+		h.MedianAbs = round((abs[n/2-1]+abs[n/2])/2, 100)
+	}
+	return h, nil
+}
+
+// realizedMove compares the closes either side of a report: before-open reports move from the prior close to the
+// report day's close, after-close reports from the report day's close to the next. Unknown timing spans both.
+func realizedMove(e storage.EarningsEvent) (Move, bool, error) {
+	m := Move{Date: e.Date.Format("2006-01-02"), Timing: e.Timing}
+
+	closes, err := storage.GetDailyCloses(e.Ticker, e.Date.AddDate(0, 0, -7), e.Date.AddDate(0, 0, 8))
+	if err != nil {
+		return m, false, err
+	}
+
+	day := e.Date.Format("2006-01-02")
+	var before, after float64
+	for _, c := range closes {
+		d := c.CapturedAt.UTC().Format("2006-01-02")
+		beforeReport := d < day || (d == day && e.Timing == storage.AfterClose)
+		afterReport := d > day || (d == day && e.Timing == storage.BeforeOpen)
+		if beforeReport {
+			before = c.Price // Latest close before the report
+		} else if afterReport && after == 0 {
+			after = c.Price // First close after it
+		}
+	}
+
+	if before > 0 && after > 0 {
+		m.Before, m.After = before, after
+		m.MovePercent = round((after/before-1)*100, 100)
+		m.Source = "history"
+		return m, true, nil
+	}
+	if e.Move != nil {
+		m.MovePercent = *e.Move
+		m.Source = "calendar"
+		return m, true, nil
+	}
+	return m, false, nil
+}
+
+// Specs are the earnings structures as recipes. The iron fly's wings sit one implied move from the body.
+func Specs(implied ImpliedMove) []strategies.RecipeSpec {
+	wing := math.Max(implied.Straddle, 0.01)
+	return []strategies.RecipeSpec{
+		{
+			Name:        "Earnings Short Iron Fly",
+			Description: "Sell ATM Straddle + Buy Wings One Implied Move Away",
+			Sentiment:   "Neutral",
+			Legs: []strategies.LegSpec{
+				{Action: strategies.Sell, Type: strategies.LegCall, Strike: strategies.StrikeSelector{By: strategies.SelectATM}},
+				{Action: strategies.Sell, Type: strategies.LegPut, Strike: strategies.StrikeSelector{By: strategies.SelectLeg, Leg: 1}},
+				{Action: strategies.Buy, Type: strategies.LegPut, Strike: strategies.StrikeSelector{By: strategies.SelectWidth, Value: wing, Leg: 2}},
+				{Action: strategies.Buy, Type: strategies.LegCall, Strike: strategies.StrikeSelector{By: strategies.SelectWidth, Value: wing, Leg: 1}},
+			},
+		},
+		{
+			Name:        "Earnings Long Straddle",
+			Description: "Buy ATM Call + Buy ATM Put Through the Report",
+			Sentiment:   "Neutral",
+			Legs: []strategies.LegSpec{
+				{Action: strategies.Buy, Type: strategies.LegCall, Strike: strategies.StrikeSelector{By: strategies.SelectATM}},
+				{Action: strategies.Buy, Type: strategies.LegPut, Strike: strategies.StrikeSelector{By: strategies.SelectLeg, Leg: 1}},
+			},
+		},
+		{
+			Name:          "Earnings Calendar",
+			Description:   "Sell the Event-Week ATM Call + Buy the Back-Month Call (Same Strike)",
+			Sentiment:     "Neutral",
+			BackMonthDays: 30,
+			Legs: []strategies.LegSpec{
+				{Action: strategies.Sell, Type: strategies.LegCall, Strike: strategies.StrikeSelector{By: strategies.SelectATM}},
+				{Action: strategies.Buy, Type: strategies.LegCall, Expiry: strategies.ExpiryBack, Strike: strategies.StrikeSelector{By: strategies.SelectLeg, Leg: 1}},
+			},
+		},
+	}
+}
+
+// suggest builds the earnings structures on the event expiry and orders them by the verdict
+func suggest(a Analysis, chain []calculator.OptionContract) ([]Suggestion, error) {
+	trades, err := strategies.GenerateAllStrategies(chain, a.Implied.Expiry, "", a.Spot, strategies.GenerateOptions{Specs: Specs(a.Implied)})
+	if err != nil {
+		return nil, err
+	}
+
+	vs := "No realized history to compare against"
+	if a.Verdict != "" {
+		vs = fmt.Sprintf("Implied move %.1f%% vs %.1f%% average realized", a.Implied.MovePercent, a.History.AvgAbs)
+	}
+	rationale := map[string]string{
+		"Earnings Short Iron Fly": vs + "; sells the straddle into the post-report IV crush with risk capped at the wings.",
+		"Earnings Long Straddle":  vs + "; profits if the stock moves more than the straddle costs.",
+		"Earnings Calendar":       vs + "; the event-week call loses its earnings premium faster than the back month.",
+	}
+	rank := map[string]int{"Earnings Short Iron Fly": 0, "Earnings Calendar": 1, "Earnings Long Straddle": 2}
+	if a.Verdict == VerdictCheap {
+		rank = map[string]int{"Earnings Long Straddle": 0, "Earnings Calendar": 1, "Earnings Short Iron Fly": 2}
+	}
+
+	suggestions := []Suggestion{}
+	for _, t := range trades {
+		suggestions = append(suggestions, Suggestion{Trade: t, Rationale: rationale[t.Name]})
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return rank[suggestions[i].Name] < rank[suggestions[j].Name]
+	})
+	return suggestions, nil
+}
+
+func round(v, scale float64) float64 {
+	return math.Round(v*scale) / scale
+}
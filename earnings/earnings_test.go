@@ -0,0 +1,118 @@
+package earnings
+
+import (
+	"strikelogic/calculator"
+	"strikelogic/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSV(t *testing.T) {
+	in := `# comment
+ticker,date,timing,move
+aapl,2024-05-02,AMC,6.0
+NVDA, 2024-05-22 ,,
+TSLA,2024-04-23
+`
+	events, err := parseCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("%d events, want 3", len(events))
+	}
+	first := events[0]
+	if first.Ticker != "AAPL" || first.Timing != storage.AfterClose || first.Move == nil || *first.Move != 6 {
+		t.Errorf("first event %+v", first)
+	}
+	if !first.Date.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("first date %v", first.Date)
+	}
+	if events[1].Move != nil || events[1].Timing != "" || events[2].Ticker != "TSLA" {
+		t.Errorf("optional fields: %+v, %+v", events[1], events[2])
+	}
+
+	// Without a header the first row is data
+	events, err = parseCSV(strings.NewReader("MSFT,2024-04-25,amc,\n"))
+	if err != nil || len(events) != 1 || events[0].Ticker != "MSFT" {
+		t.Errorf("headerless file gave %+v, %v", events, err)
+	}
+
+	for name, in := range map[string]string{
+		"bad move":   "AAPL,2024-05-02,amc,big\n",
+		"bad timing": "AAPL,2024-05-02,noon,\n",
+		"bad date":   "AAPL,May 2,amc,\n",
+	} {
+		if _, err := parseCSV(strings.NewReader(in)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestImplied(t *testing.T) {
+	chain := []calculator.OptionContract{
+		{Expiry: "2030-01-25", Type: calculator.Call, Strike: 100, Bid: 1, Ask: 1.2},
+		{Expiry: "2030-01-18", Type: calculator.Call, Strike: 95, Bid: 7, Ask: 7.4},
+		{Expiry: "2030-01-18", Type: calculator.Put, Strike: 95, Bid: 1, Ask: 1.2},
+		{Expiry: "2030-01-18", Type: calculator.Call, Strike: 100, Bid: 3.9, Ask: 4.1, Vol: 0.5},
+		{Expiry: "2030-01-18", Type: calculator.Put, Strike: 100, Last: 3.1, Vol: 0.6}, // No two-sided market
+		{Expiry: "2030-01-18", Type: calculator.Call, Strike: 105, Bid: 1.5, Ask: 1.7},
+	}
+
+	im, err := Implied(chain, 101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first expiry's straddle at the strike closest to spot
+	if im.Expiry != "2030-01-18" || im.Strike != 100 || im.CallMid != 4 || im.PutMid != 3.1 {
+		t.Errorf("implied %+v", im)
+	}
+	if im.Straddle != 7.1 || im.MovePercent != 7.03 || im.IV != 0.55 {
+		t.Errorf("straddle %v move %v%% IV %v, want 7.1, 7.03 and 0.55", im.Straddle, im.MovePercent, im.IV)
+	}
+
+	// The 105 call has no put to pair with
+	if _, err := Implied(chain[5:], 105); err == nil {
+		t.Error("a lone call: expected an error")
+	}
+}
+
+func TestRealizedMove(t *testing.T) {
+	t.Chdir(t.TempDir()) // InitDB opens ./strikelogic.db
+	storage.InitDB()
+	defer storage.DB.Close()
+
+	closes := map[string]float64{"2030-01-08": 100, "2030-01-09": 104, "2030-01-10": 98}
+	for day, price := range closes {
+		at, _ := time.Parse("2006-01-02", day)
+		if err := storage.SaveQuote(storage.UnderlyingQuote{Ticker: "XYZ", Price: price, CapturedAt: at.Add(20 * time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := time.Date(2030, 1, 9, 0, 0, 0, 0, time.UTC)
+	recorded := 12.5
+
+	tests := []struct {
+		name   string
+		event  storage.EarningsEvent
+		ok     bool
+		move   float64
+		source string
+	}{
+		{"before the open", storage.EarningsEvent{Ticker: "XYZ", Date: report, Timing: storage.BeforeOpen}, true, 4, "history"},
+		{"after the close", storage.EarningsEvent{Ticker: "XYZ", Date: report, Timing: storage.AfterClose}, true, -5.77, "history"},
+		{"unknown timing", storage.EarningsEvent{Ticker: "XYZ", Date: report}, true, -2, "history"},
+		{"no closes", storage.EarningsEvent{Ticker: "ABC", Date: report, Move: &recorded}, true, 12.5, "calendar"},
+		{"nothing known", storage.EarningsEvent{Ticker: "ABC", Date: report}, false, 0, ""},
+	}
+	for _, tt := range tests {
+		m, ok, err := realizedMove(tt.event)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if ok != tt.ok || m.MovePercent != tt.move || m.Source != tt.source {
+			t.Errorf("%s: move %v%% from %q (ok %v), want %v%% from %q", tt.name, m.MovePercent, m.Source, ok, tt.move, tt.source)
+		}
+	}
+}
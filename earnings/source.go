@@ -0,0 +1,166 @@
+package earnings
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strikelogic/storage"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source supplies earnings report dates, past and upcoming
+type Source interface {
+	Events() ([]storage.EarningsEvent, error)
+}
+
+var (
+	sourceMu sync.RWMutex
+	source   Source
+)
+
+// SetSource installs the earnings source used by Sync
+func SetSource(s Source) {
+	sourceMu.Lock()
+	defer sourceMu.Unlock()
+	source = s
+}
+
+// Sync copies every event from the configured source into SQLite, returning the number stored
+func Sync() (int, error) {
+	sourceMu.RLock()
+	s := source
+	sourceMu.RUnlock()
+
+	if s == nil {
+		return 0, fmt.Errorf("no earnings source configured")
+	}
+	events, err := s.Events()
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if err := storage.SaveEarnings(e); err != nil {
+			return 0, err
+		}
+	}
+	log.Printf("Synced %d earnings dates from %T", len(events), s)
+	return len(events), nil
+}
+
+// FileSource reads earnings dates from a .csv or .json file.
+//
+// CSV: a header row, then "ticker,date,timing,move" rows, e.g. "AAPL,2024-05-02,amc,6.0". Lines starting with # are skipped.
+// JSON: [{"ticker": "AAPL", "date": "2024-05-02", "timing": "amc", "move": 6.0}].
+// Timing (bmo or amc) and move (the realized move in percent, for past reports) are optional.
+type FileSource struct {
+	Path string
+}
+
+func (f FileSource) Events() ([]storage.EarningsEvent, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []storage.EarningsEvent
+	switch strings.ToLower(filepath.Ext(f.Path)) {
+	case ".csv":
+		events, err = parseCSV(file)
+	case ".json":
+		events, err = parseJSON(file)
+	default:
+		return nil, fmt.Errorf("unsupported earnings file format: %s", f.Path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", f.Path, err)
+	}
+	return events, nil
+}
+
+func parseCSV(r io.Reader) ([]storage.EarningsEvent, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []storage.EarningsEvent
+	for i, rec := range records {
+		if len(rec) < 2 || (i == 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "ticker")) {
+			continue // header
+		}
+		var timing string
+		if len(rec) > 2 {
+			timing = rec[2]
+		}
+		var move *float64
+		if len(rec) > 3 && strings.TrimSpace(rec[3]) != "" {
+			v, err := strconv.ParseFloat(strings.TrimSpace(rec[3]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid move %q", i+1, rec[3])
+			}
+			move = &v
+		}
+		e, err := NewEvent(rec[0], rec[1], timing, move)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i+1, err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func parseJSON(r io.Reader) ([]storage.EarningsEvent, error) {
+	var raw []struct {
+		Ticker string   `json:"ticker"`
+		Date   string   `json:"date"`
+		Timing string   `json:"timing"`
+		Move   *float64 `json:"move"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	var events []storage.EarningsEvent
+	for _, e := range raw {
+		event, err := NewEvent(e.Ticker, e.Date, e.Timing, e.Move)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// NewEvent validates and normalizes an event's fields
+func NewEvent(ticker, date, timing string, move *float64) (storage.EarningsEvent, error) {
+	e := storage.EarningsEvent{
+		Ticker: strings.ToUpper(strings.TrimSpace(ticker)),
+		Timing: strings.ToLower(strings.TrimSpace(timing)),
+		Move:   move,
+	}
+	if e.Ticker == "" {
+		return e, fmt.Errorf("ticker required")
+	}
+
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(date))
+	if err != nil {
+		return e, fmt.Errorf("invalid date %q", date)
+	}
+	e.Date = t
+
+	if e.Timing != "" && e.Timing != storage.BeforeOpen && e.Timing != storage.AfterClose {
+		return e, fmt.Errorf("invalid timing %q, expected bmo or amc", timing)
+	}
+	return e, nil
+}
@@ -24,7 +24,7 @@ const fetchMatrixData = async (trade: any, currentPrice: number, vol: number) =>
         }
 
         const data = await response.json();
-        return data; // Should match MatrixPoint[] structure from backend
+        return data.grid || []; // MatrixPoint[]; the response also carries the dates/prices axes
     } catch (e) {
         console.error("Error fetching matrix:", e);
         return [];
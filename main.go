@@ -1,16 +1,30 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strikelogic/backtest"
 	"strikelogic/calculator"
+	"strikelogic/chainhistory"
+	"strikelogic/earnings"
 	"strikelogic/news_engine"
 	"strikelogic/newsfeed"
+	"strikelogic/optimizer"
+	"strikelogic/portfolio"
+	"strikelogic/rates"
+	"strikelogic/simulation"
 	"strikelogic/storage"
 	"strikelogic/strategies"
+	"strikelogic/strategist"
+	"strikelogic/volatility"
+	"strikelogic/volsurface"
+	"strikelogic/whales"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -23,6 +37,64 @@ func main() {
 
 	storage.InitDB()
 
+	if err := calculator.ConfigureProviderFromEnv(); err != nil {
+		log.Fatalf("Invalid market data configuration: %v", err)
+	}
+
+	// Risk-free term structure
+	ratesFile := os.Getenv("RATES_FILE")
+	if ratesFile == "" {
+		ratesFile = "data/rates.csv"
+	}
+	if err := rates.LoadFile(ratesFile); err != nil {
+		log.Printf("Could not load rate curve (%v). Using flat %.2f%%.", err, rates.DefaultRate*100)
+	}
+
+	// Pricing reads per-ticker dividends from SQLite. The whole stored schedule is returned so past
+	// ex-dates set the projection cadence and replays at earlier dates still see their dividends.
+	calculator.SetDividendLookup(func(ticker string) (float64, []calculator.Dividend, error) {
+		yield, err := storage.GetDividendYield(ticker)
+		if err != nil {
+			return 0, nil, err
+		}
+		stored, err := storage.GetDividends(ticker, time.Time{})
+		if err != nil {
+			return 0, nil, err
+		}
+		var schedule []calculator.Dividend
+		for _, d := range stored {
+			schedule = append(schedule, calculator.Dividend{ExDate: d.ExDate, Amount: d.Amount})
+		}
+		return yield, schedule, nil
+	})
+
+	// Config-defined strategy recipes
+	recipesPath := os.Getenv("RECIPES_PATH")
+	if recipesPath == "" {
+		recipesPath = "data/recipes"
+	}
+	if _, err := strategies.LoadRecipes(recipesPath); err != nil {
+		log.Printf("Could not load custom recipes (%v). Using built-in recipes only.", err)
+	}
+
+	// Earnings dates, from a file until a live calendar source is plugged in (see earnings.FileSource for the format)
+	if earningsFile := os.Getenv("EARNINGS_FILE"); earningsFile != "" {
+		earnings.SetSource(earnings.FileSource{Path: earningsFile})
+		if _, err := earnings.Sync(); err != nil {
+			log.Printf("Could not load earnings dates (%v).", err)
+		}
+	} else {
+		log.Printf("Earnings calendar file disabled: EARNINGS_FILE is not set")
+	}
+
+	if name := os.Getenv("PRICING_MODEL"); name != "" {
+		model, err := calculator.ModelByName(name)
+		if err != nil {
+			log.Fatalf("Invalid pricing model: %v", err)
+		}
+		calculator.SetDefaultModel(model)
+	}
+
 	// Background news fetcher
 	go func() {
 		tickers := []string{"TSLA", "NVDA", "SPY"}
@@ -35,6 +107,28 @@ func main() {
 		}
 	}()
 
+	// Background option chain snapshots for historical queries, only for an explicit SNAPSHOT_WATCHLIST
+	if watchlist := chainhistory.Watchlist(); len(watchlist) > 0 {
+		go chainhistory.Run(watchlist, envDuration("SNAPSHOT_INTERVAL", 30*time.Minute))
+	} else {
+		log.Printf("Chain snapshots disabled: SNAPSHOT_WATCHLIST is not set")
+	}
+
+	// Background unusual options activity scan, only for an explicit WHALE_WATCHLIST or SNAPSHOT_WATCHLIST
+	if watchlist := whales.Watchlist(); len(watchlist) > 0 {
+		go whales.Run(watchlist, envDuration("WHALE_INTERVAL", 15*time.Minute))
+	} else {
+		log.Printf("Unusual activity scan disabled: WHALE_WATCHLIST is not set")
+	}
+
+	// Background daily OHLC bars and ATM 30-day IV for IV rank and realized volatility, built from the snapshots
+	if watchlist := chainhistory.Watchlist(); len(watchlist) > 0 {
+		go volatility.Run(watchlist, envDuration("VOL_INTERVAL", 24*time.Hour))
+	}
+
+	// Background mark-to-market of open portfolio positions
+	go portfolio.Run(envDuration("MARK_INTERVAL", 15*time.Minute))
+
 	http.HandleFunc("/chain", func(w http.ResponseWriter, r *http.Request) {
 		// Enable CORS for frontend development convenience
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -45,7 +139,12 @@ func main() {
 			ticker = "SPY" // Default ticker
 		}
 
-		chain := calculator.GetChain(ticker)
+		// Served from the configured provider so IVs are solved from live quotes; date picks the closest expiry
+		chain, err := calculator.GetOptionsChain(strings.ToUpper(ticker), r.URL.Query().Get("date"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to fetch chain: %v", err), http.StatusInternalServerError)
+			return
+		}
 
 		if err := json.NewEncoder(w).Encode(chain); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -53,10 +152,67 @@ func main() {
 		}
 	})
 
-	http.HandleFunc("/api/calculate", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/api/chain/history", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		ticker := strings.ToUpper(r.URL.Query().Get("ticker"))
+		if ticker == "" {
+			http.Error(w, "Ticker required", http.StatusBadRequest)
+			return
+		}
+
+		// asOf accepts RFC 3339 or a plain date (end of that day); defaults to now
+		asOf := time.Now()
+		if s := r.URL.Query().Get("asOf"); s != "" {
+			t, err := calculator.ParseAsOf(s)
+			if err != nil {
+				http.Error(w, "Invalid asOf, expected RFC 3339 or YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			asOf = t
+		}
+
+		quote, err := storage.GetQuoteAsOf(ticker, asOf)
+		if err == sql.ErrNoRows {
+			http.Error(w, fmt.Sprintf("No history for %s at %s", ticker, asOf.Format(time.RFC3339)), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		chain, err := storage.GetChainAsOf(ticker, asOf)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Optional single-expiry filter
+		if expiry := r.URL.Query().Get("expiry"); expiry != "" {
+			var filtered []storage.OptionQuote
+			for _, c := range chain {
+				if c.Expiry == expiry {
+					filtered = append(filtered, c)
+				}
+			}
+			chain = filtered
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ticker":    ticker,
+			"asOf":      asOf,
+			"quote":     quote,
+			"contracts": chain,
+		})
+	})
+
+	http.HandleFunc("/api/backtest", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -69,11 +225,11 @@ func main() {
 		}
 
 		var req struct {
-			Ticker       string  `json:"ticker"`
-			CurrentPrice float64 `json:"currentPrice"`
-			TargetPrice  float64 `json:"targetPrice"`
-			Date         string  `json:"date"`
-			Sentiment    string  `json:"sentiment"`
+			Ticker string         `json:"ticker"`
+			Recipe string         `json:"recipe"` // Empty runs all recipes
+			Start  string         `json:"start"`  // YYYY-MM-DD
+			End    string         `json:"end"`    // YYYY-MM-DD
+			Rules  backtest.Rules `json:"rules"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -81,126 +237,285 @@ func main() {
 			return
 		}
 
-		// Fetch Option Chain
-		chain, err := calculator.GetOptionsChain(req.Ticker, req.Date)
+		start, err := time.Parse("2006-01-02", req.Start)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to fetch options chain: %v", err), http.StatusInternalServerError)
+			http.Error(w, "Invalid start date", http.StatusBadRequest)
+			return
+		}
+		end, err := time.Parse("2006-01-02", req.End)
+		if err != nil {
+			http.Error(w, "Invalid end date", http.StatusBadRequest)
 			return
 		}
 
-		// Generate Strategies
-		// Pass sentiment from request
-		trades, err := strategies.GenerateAllStrategies(chain, req.Date, req.Sentiment, req.TargetPrice)
+		report, err := backtest.Run(backtest.Config{
+			Ticker: strings.ToUpper(req.Ticker),
+			Recipe: req.Recipe,
+			Start:  start,
+			End:    end.Add(24*time.Hour - time.Second),
+			Rules:  req.Rules,
+		})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		if err := json.NewEncoder(w).Encode(trades); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(report)
+	})
+
+	http.HandleFunc("/api/dividends", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		var ticker string
+		switch r.Method {
+		case http.MethodGet:
+			ticker = strings.ToUpper(r.URL.Query().Get("ticker"))
+		case http.MethodPost:
+			var req struct {
+				Ticker    string   `json:"ticker"`
+				Yield     *float64 `json:"yield"` // Omit to leave the stored yield unchanged
+				Dividends []struct {
+					ExDate string  `json:"exDate"` // YYYY-MM-DD
+					Amount float64 `json:"amount"`
+				} `json:"dividends"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			ticker = strings.ToUpper(req.Ticker)
+			if ticker == "" {
+				http.Error(w, "Ticker required", http.StatusBadRequest)
+				return
+			}
+
+			if req.Yield != nil {
+				if err := storage.SetDividendYield(ticker, *req.Yield); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+			for _, d := range req.Dividends {
+				exDate, err := time.Parse("2006-01-02", d.ExDate)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("Invalid exDate: %s", d.ExDate), http.StatusBadRequest)
+					return
+				}
+				if err := storage.SaveDividend(storage.Dividend{Ticker: ticker, ExDate: exDate, Amount: d.Amount}); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+			calculator.InvalidateDividends(ticker)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if ticker == "" {
+			http.Error(w, "Ticker required", http.StatusBadRequest)
 			return
 		}
+
+		yield, schedule := calculator.LookupDividends(ticker)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ticker":    ticker,
+			"yield":     yield,
+			"dividends": schedule,
+		})
 	})
 
-	http.HandleFunc("/api/quote", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/api/rates", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Content-Type", "application/json")
 
-		ticker := r.URL.Query().Get("ticker")
+		json.NewEncoder(w).Encode(rates.Current())
+	})
+
+	http.HandleFunc("/api/volsurface", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		ticker := strings.ToUpper(r.URL.Query().Get("ticker"))
 		if ticker == "" {
 			http.Error(w, "Ticker required", http.StatusBadRequest)
 			return
 		}
 
-		price, err := calculator.GetQuote(ticker)
+		// refresh=true skips the cache and refits from a fresh chain
+		load := volsurface.Load
+		if r.URL.Query().Get("refresh") == "true" {
+			load = volsurface.Fetch
+		}
+		surface, err := load(ticker)
 		if err != nil {
-			// Fallback
-			if ticker == "AAPL" {
-				price = 150.0
-			} else {
-				price = 100.0
-			}
+			http.Error(w, fmt.Sprintf("Failed to build vol surface: %v", err), http.StatusInternalServerError)
+			return
 		}
 
-		json.NewEncoder(w).Encode(map[string]float64{"price": price})
+		json.NewEncoder(w).Encode(struct {
+			*volsurface.Surface
+			Grid volsurface.Grid `json:"grid"`
+		}{surface, surface.Grid(volsurface.DefaultMoneyness())})
 	})
 
-	http.HandleFunc("/api/news/signals", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/api/rates/refresh", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.Header().Set("Content-Type", "application/json")
 
-		headlines, err := news_engine.FetchHeadlines()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
 			return
 		}
 
-		var signals []news_engine.Signal
-		for _, headline := range headlines {
-			sigs, err := news_engine.AnalyzeSentiment(headline)
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// With a body of {"asOf": "...", "yields": {"3M": 5.28, ...}} the curve is replaced directly,
+		// otherwise it is reloaded from the configured file.
+		var req struct {
+			AsOf   string             `json:"asOf"`
+			Yields map[string]float64 `json:"yields"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if len(req.Yields) > 0 {
+			asOf := time.Now()
+			if req.AsOf != "" {
+				t, err := time.Parse("2006-01-02", req.AsOf)
+				if err != nil {
+					http.Error(w, "Invalid asOf", http.StatusBadRequest)
+					return
+				}
+				asOf = t
+			}
+			curve, err := rates.FromPercent(asOf, req.Yields)
 			if err != nil {
-				log.Printf("Error analyzing sentiment for '%s': %v", headline, err)
-				continue
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
 			}
-			signals = append(signals, sigs...)
+			curve.Source = "api"
+			rates.SetCurve(curve)
+			json.NewEncoder(w).Encode(curve)
+			return
 		}
 
-		json.NewEncoder(w).Encode(signals)
+		curve, err := rates.Refresh()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(curve)
 	})
 
-	http.HandleFunc("/api/news/track", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/api/calculate", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
-		ticker := r.URL.Query().Get("ticker")
-		if ticker == "" {
-			http.Error(w, "Ticker required", http.StatusBadRequest)
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		go func() {
-			log.Printf("Manually fetching news for %s...", ticker)
-			newsfeed.FetchStockNews(ticker)
-		}()
+		var req struct {
+			Ticker       string  `json:"ticker"`
+			CurrentPrice float64 `json:"currentPrice"`
+			TargetPrice  float64 `json:"targetPrice"`
+			Date         string  `json:"date"`
+			Sentiment    string  `json:"sentiment"`
+			// Per-recipe strike selector overrides by leg, e.g. {"Bull Put Spread": [{"by": "delta", "value": 0.3}, {"by": "width", "value": 5, "leg": 1}]}
+			Selectors map[string][]strategies.StrikeSelector `json:"selectors"`
+			// Optional: "surface" computes probabilities from the vol surface instead of a lognormal
+			Distribution string `json:"distribution"`
+			// Optional: "credit" or "debit" lists those structures first; "auto" picks by the ticker's IV rank
+			Prefer string `json:"prefer"`
+		}
 
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"status": "fetching", "message": fmt.Sprintf("Started fetching news for %s", ticker)})
-	})
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-	http.HandleFunc("/api/news", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Content-Type", "application/json")
+		specs, err := strategies.ApplySelectors(strategies.RecipeSpecs(), req.Selectors)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		ticker := r.URL.Query().Get("ticker")
-		// if ticker is empty, we fetch all
+		// Fetch Option Chain
+		chain, err := calculator.GetOptionsChain(req.Ticker, req.Date)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to fetch options chain: %v", err), http.StatusInternalServerError)
+			return
+		}
 
-		limitStr := r.URL.Query().Get("limit")
-		limit := 20
-		if limitStr != "" {
-			if l, err := strconv.Atoi(limitStr); err == nil {
-				limit = l
+		opts := strategies.GenerateOptions{Specs: specs, Prefer: req.Prefer}
+		if req.Distribution == "surface" {
+			if vs, err := volsurface.Load(strings.ToUpper(req.Ticker)); err != nil {
+				log.Printf("Vol surface unavailable for %s, using lognormal probabilities: %v", req.Ticker, err)
+			} else {
+				opts.Surface = vs
 			}
 		}
 
-		articles, err := storage.GetLatestNews(ticker, limit)
+		if opts.Prefer == "auto" {
+			opts.Prefer = ""
+			if m, err := volatility.GetMetrics(strings.ToUpper(req.Ticker), 0); err != nil {
+				log.Printf("IV regime unavailable for %s, keeping recipe order: %v", req.Ticker, err)
+			} else {
+				opts.Prefer = m.Prefer
+			}
+		}
+
+		// Generate Strategies
+		// Pass sentiment from request
+		trades, err := strategies.GenerateAllStrategies(chain, req.Date, req.Sentiment, req.TargetPrice, opts)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		json.NewEncoder(w).Encode(articles)
+		if err := json.NewEncoder(w).Encode(trades); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	})
 
-	http.HandleFunc("/api/simulate/matrix", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/api/recipes", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		json.NewEncoder(w).Encode(strategies.ListRecipes())
+	})
+
+	http.HandleFunc("/api/recipes/reload", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -212,74 +527,1042 @@ func main() {
 			return
 		}
 
-		// Input struct matching JS Trade object structure somewhat
-		var req struct {
-			Strategies []strategies.Trade `json:"strategies"` // Or single trade? JS sends 'trade' object
-			Strategy   strategies.Trade   `json:"strategy"`
-			Price      float64            `json:"currentPrice"`
-			Vol        float64            `json:"volatility"`
+		// A bad file leaves the previously loaded recipes in place
+		if _, err := strategies.ReloadRecipes(); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload recipes: %v", err), http.StatusBadRequest)
+			return
 		}
 
-		// JS Code: fetchMatrixData(trade, currentPrice, vol)
-		// We'll likely send { strategy: trade, currentPrice: ..., volatility: ... }
+		json.NewEncoder(w).Encode(strategies.ListRecipes())
+	})
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+	http.HandleFunc("/api/strategist", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
 			return
 		}
 
-		// Map strategies.Trade to calculator.StrategyInput
-		var calcInput calculator.StrategyInput
-		calcInput.InitialDebit = req.Strategy.NetDebit
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-		for _, leg := range req.Strategy.Legs {
-			expiry, _ := time.Parse("2006-01-02", leg.Option.Expiry)
+		var req struct {
+			Ticker       string  `json:"ticker"`
+			CurrentPrice float64 `json:"currentPrice"` // Optional: fetched when zero
+			TargetPrice  float64 `json:"targetPrice"`
+			Date         string  `json:"date"`
+			RiskProfile  string  `json:"riskProfile"` // Optional: low, medium or degen
+		}
 
-			optType := calculator.Call
-			if leg.Option.Type == "Put" || leg.Option.Type == calculator.Put {
-				optType = calculator.Put
-			}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Ticker = strings.ToUpper(req.Ticker)
+		if req.Ticker == "" || req.TargetPrice <= 0 {
+			http.Error(w, "Ticker and targetPrice required", http.StatusBadRequest)
+			return
+		}
 
-			// Determine Entry Price based on Action
-			entryPrice := leg.Option.Ask
-			if leg.Action == "Sell" {
-				entryPrice = leg.Option.Bid
-			}
-			// Fallback if Ask/Bid are 0 (e.g. theoretical or off-hours)
-			if entryPrice == 0 {
-				entryPrice = leg.Option.Last
+		var profile strategist.RiskProfile
+		if req.RiskProfile != "" {
+			p, err := strategist.ParseRiskProfile(req.RiskProfile)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
 			}
+			profile = p
+		}
 
-			l := calculator.LegInput{
-				Strike:     leg.Option.Strike,
-				Type:       optType,
-				Action:     string(leg.Action),
-				Quantity:   float64(leg.Quantity),
-				Expiry:     expiry,
-				IV:         leg.Option.Vol,
-				EntryPrice: entryPrice,
+		if req.CurrentPrice <= 0 {
+			price, err := calculator.GetQuote(req.Ticker)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to get quote for %s: %v", req.Ticker, err), http.StatusInternalServerError)
+				return
 			}
-			calcInput.Legs = append(calcInput.Legs, l)
+			req.CurrentPrice = price
 		}
 
-		// If NetDebit is stored as per-share, total debit is NetDebit * 1 (standard 1 lot context) * 100?
-		// Wait, loop calculated leg value.
-		// calcInput.InitialDebit: logic in calculator uses this to subtract from final value.
-		// If leg quantity is 1, and InitialDebit is say $1.50 (per share).
-		// CombinedOptionValue will be (Price - K) * 100 * Qty. (e.g. $5 * 100 * 1 = $500).
-		// If we subtract $1.50, we get $498.50. This is wrong. It should be subtract $150.
-		// So InitialDebit should be scaled by 100 if it's per-share price.
-		// However, in strategies.go, NetDebit = Ask - Bid (per share).
-		// So we should multiply by 100 here.
-		calcInput.InitialDebit = calcInput.InitialDebit * 100
-
-		matrix, err := calculator.CalculateProfitMatrix(calcInput, req.Price, req.Vol)
+		trades, err := strategist.FindTrades(req.Ticker, req.CurrentPrice, req.TargetPrice, req.Date)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Failed to find trades: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		json.NewEncoder(w).Encode(matrix.Grid)
+		filtered := []strategies.Trade{}
+		for _, t := range trades {
+			if profile == "" || t.RiskProfile == string(profile) {
+				filtered = append(filtered, t)
+			}
+		}
+
+		if err := json.NewEncoder(w).Encode(filtered); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	http.HandleFunc("/api/optimize", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Ticker       string   `json:"ticker"`
+			CurrentPrice float64  `json:"currentPrice"` // Optional: fetched when zero
+			TargetPrice  float64  `json:"targetPrice"`
+			Date         string   `json:"date"`         // Expiry to search
+			TargetDate   string   `json:"targetDate"`   // Optional: date the target is reached, defaults to the expiry
+			TopN         int      `json:"topN"`         // Optional: defaults to 10
+			Structures   []string `json:"structures"`   // Optional: vertical, butterfly, condor, ratio
+			MaxLoss      float64  `json:"maxLoss"`      // Optional: dollars
+			SortBy       string   `json:"sortBy"`       // Optional: score, ev, target, pop or maxLoss
+			Distribution string   `json:"distribution"` // Optional: surface (default) or lognormal
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Ticker = strings.ToUpper(req.Ticker)
+		if req.Ticker == "" || req.TargetPrice <= 0 {
+			http.Error(w, "Ticker and targetPrice required", http.StatusBadRequest)
+			return
+		}
+
+		opts := optimizer.Options{
+			TargetPrice: req.TargetPrice,
+			TopN:        req.TopN,
+			Structures:  req.Structures,
+			MaxLoss:     req.MaxLoss,
+			SortBy:      req.SortBy,
+		}
+		if req.TargetDate != "" {
+			targetDate, err := time.Parse("2006-01-02", req.TargetDate)
+			if err != nil {
+				http.Error(w, "Invalid targetDate format (YYYY-MM-DD)", http.StatusBadRequest)
+				return
+			}
+			opts.TargetDate = targetDate
+		}
+
+		if req.CurrentPrice <= 0 {
+			price, err := calculator.GetQuote(req.Ticker)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to get quote for %s: %v", req.Ticker, err), http.StatusInternalServerError)
+				return
+			}
+			req.CurrentPrice = price
+		}
+
+		chain, err := calculator.GetOptionsChain(req.Ticker, req.Date)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to fetch options chain: %v", err), http.StatusInternalServerError)
+			return
+		}
+		chain = strategies.FilterChainByClosestDate(chain, req.Date)
+
+		dist, method, err := optimizer.ExpiryDistribution(req.Ticker, req.CurrentPrice, chain, req.Distribution)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		candidates, err := optimizer.Optimize(chain, req.CurrentPrice, dist, opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ticker":       req.Ticker,
+			"currentPrice": req.CurrentPrice,
+			"expiry":       chain[0].Expiry,
+			"distribution": method,
+			"candidates":   candidates,
+		})
+	})
+
+	http.HandleFunc("/api/quote", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		ticker := r.URL.Query().Get("ticker")
+		if ticker == "" {
+			http.Error(w, "Ticker required", http.StatusBadRequest)
+			return
+		}
+
+		price, err := calculator.GetQuote(ticker)
+		if err != nil {
+			// Fallback
+			if ticker == "AAPL" {
+				price = 150.0
+			} else {
+				price = 100.0
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]float64{"price": price})
+	})
+
+	http.HandleFunc("/api/news/signals", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		headlines, err := news_engine.FetchHeadlines()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var signals []news_engine.Signal
+		for _, headline := range headlines {
+			sigs, err := news_engine.AnalyzeSentiment(headline)
+			if err != nil {
+				log.Printf("Error analyzing sentiment for '%s': %v", headline, err)
+				continue
+			}
+			signals = append(signals, sigs...)
+		}
+
+		json.NewEncoder(w).Encode(signals)
+	})
+
+	http.HandleFunc("/api/news/track", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		ticker := r.URL.Query().Get("ticker")
+		if ticker == "" {
+			http.Error(w, "Ticker required", http.StatusBadRequest)
+			return
+		}
+
+		go func() {
+			log.Printf("Manually fetching news for %s...", ticker)
+			newsfeed.FetchStockNews(ticker)
+		}()
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "fetching", "message": fmt.Sprintf("Started fetching news for %s", ticker)})
+	})
+
+	http.HandleFunc("/api/news", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		ticker := r.URL.Query().Get("ticker")
+		// if ticker is empty, we fetch all
+
+		limitStr := r.URL.Query().Get("limit")
+		limit := 20
+		if limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil {
+				limit = l
+			}
+		}
+
+		articles, err := storage.GetLatestNews(ticker, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(articles)
+	})
+
+	http.HandleFunc("/api/simulate/matrix", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Input struct matching JS Trade object structure somewhat
+		var req struct {
+			Strategies              []strategies.Trade `json:"strategies"` // Or single trade? JS sends 'trade' object
+			Strategy                strategies.Trade   `json:"strategy"`
+			Price                   float64            `json:"currentPrice"`
+			Vol                     float64            `json:"volatility"`
+			Model                   string             `json:"model"`   // Optional: black-scholes, binomial or baw
+			Surface                 bool               `json:"surface"` // Optional: revalue legs on the vol surface (a cold cache fetches the full chain)
+			calculator.MatrixConfig                    // Optional: axes and output mode
+		}
+
+		// JS Code: fetchMatrixData(trade, currentPrice, vol)
+		// We'll likely send { strategy: trade, currentPrice: ..., volatility: ... }
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		// Map strategies.Trade to calculator.StrategyInput
+		calcInput := req.Strategy.MatrixInput()
+
+		model, err := calculator.ModelByName(req.Model)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		calcInput.Model = model
+
+		calcInput.DividendYield, calcInput.Dividends, calcInput.Surface = tradeMarketInputs(req.Strategy, req.Surface)
+
+		if req.Output == calculator.MatrixPercentRisk && req.MaxRisk <= 0 && req.Price > 0 {
+			req.Strategy.CalculateMetrics(req.Price)
+			req.MaxRisk = req.Strategy.MaxRisk
+		}
+
+		matrix, err := calculator.CalculateProfitMatrixWith(calcInput, req.Price, req.Vol, req.MatrixConfig)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(matrix)
+	})
+
+	http.HandleFunc("/api/simulate/scenarios", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// e.g. { strategy: trade, currentPrice: 100, volatility: 0.3, volShifts: [-10, 0, 10], expiryShifts: {"2025-01-17": {level: -15}} }
+		var req struct {
+			Strategy strategies.Trade `json:"strategy"`
+			Price    float64          `json:"currentPrice"`
+			Vol      float64          `json:"volatility"`
+			Model    string           `json:"model"`   // Optional: black-scholes, binomial or baw
+			Surface  bool             `json:"surface"` // Optional: revalue legs on the vol surface (a cold cache fetches the full chain)
+			calculator.ScenarioConfig
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		calcInput := req.Strategy.MatrixInput()
+
+		model, err := calculator.ModelByName(req.Model)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		calcInput.Model = model
+
+		calcInput.DividendYield, calcInput.Dividends, calcInput.Surface = tradeMarketInputs(req.Strategy, req.Surface)
+
+		scenarios, err := calculator.CalculateScenarios(calcInput, req.Price, req.Vol, req.ScenarioConfig)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(scenarios)
+	})
+
+	http.HandleFunc("/api/simulate/montecarlo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// e.g. { strategy: trade, currentPrice: 100, paths: 5000, dynamics: {jumpIntensity: 2}, rules: {profitTarget: 0.5, closeDTE: 21} }
+		var req struct {
+			Strategy strategies.Trade `json:"strategy"`
+			Price    float64          `json:"currentPrice"`
+			simulation.Config
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		calcInput := req.Strategy.MatrixInput()
+		calcInput.DividendYield, calcInput.Dividends, _ = tradeMarketInputs(req.Strategy, false)
+
+		if req.MaxProfit <= 0 && req.Price > 0 {
+			req.MaxProfit = simulation.MaxProfitFor(req.Strategy, req.Price)
+		}
+
+		result, err := simulation.Run(calcInput, req.Price, req.Config)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	})
+
+	http.HandleFunc("/api/portfolio/accounts", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			accounts, err := storage.GetAccounts()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(accounts)
+		case http.MethodPost:
+			var req struct {
+				Name string `json:"name"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if strings.TrimSpace(req.Name) == "" {
+				http.Error(w, "Name required", http.StatusBadRequest)
+				return
+			}
+			account, err := storage.CreateAccount(strings.TrimSpace(req.Name))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(account)
+		case http.MethodDelete:
+			id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid id", http.StatusBadRequest)
+				return
+			}
+			if err := storage.DeleteAccount(id); err == sql.ErrNoRows {
+				http.Error(w, "Account not found", http.StatusNotFound)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/api/portfolio/positions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			if s := r.URL.Query().Get("id"); s != "" {
+				id, err := strconv.ParseInt(s, 10, 64)
+				if err != nil {
+					http.Error(w, "Invalid id", http.StatusBadRequest)
+					return
+				}
+				position, err := storage.GetPosition(id)
+				if err == sql.ErrNoRows {
+					http.Error(w, "Position not found", http.StatusNotFound)
+					return
+				}
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				json.NewEncoder(w).Encode(position)
+				return
+			}
+
+			var accountID int64
+			if s := r.URL.Query().Get("account"); s != "" {
+				id, err := strconv.ParseInt(s, 10, 64)
+				if err != nil {
+					http.Error(w, "Invalid account", http.StatusBadRequest)
+					return
+				}
+				accountID = id
+			}
+			positions, err := storage.GetPositions(accountID, r.URL.Query().Get("status"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(positions)
+		case http.MethodPost:
+			// Either explicit legs, or a trade from /api/calculate filled at its entry prices:
+			// { accountId: 1, ticker: "SPY", name: "Iron Condor", legs: [{type: "Put", action: "Sell", strike: 380, expiry: "2024-06-21", quantity: 1, fillPrice: 2.1}] }
+			var req struct {
+				storage.Position
+				Trade *strategies.Trade `json:"trade"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			position := req.Position
+			position.Ticker = strings.ToUpper(position.Ticker)
+			if position.OpenedAt.IsZero() {
+				position.OpenedAt = time.Now()
+			}
+			if req.Trade != nil {
+				if position.Name == "" {
+					position.Name = req.Trade.Name
+				}
+				position.Legs = portfolio.LegsFromTrade(*req.Trade, position.OpenedAt)
+				if position.Ticker == "" {
+					for _, leg := range req.Trade.Legs {
+						if leg.Option.Underlying != "" {
+							position.Ticker = strings.ToUpper(leg.Option.Underlying)
+							break
+						}
+					}
+				}
+			}
+			if position.AccountID == 0 || position.Ticker == "" {
+				http.Error(w, "accountId and ticker required", http.StatusBadRequest)
+				return
+			}
+			if len(position.Legs) == 0 {
+				http.Error(w, "Position has no legs", http.StatusBadRequest)
+				return
+			}
+			for i := range position.Legs {
+				if position.Legs[i].FillTime.IsZero() {
+					position.Legs[i].FillTime = position.OpenedAt
+				}
+				if err := portfolio.ValidateLeg(position.Legs[i]); err != nil {
+					http.Error(w, fmt.Sprintf("Leg %d: %v", i+1, err), http.StatusBadRequest)
+					return
+				}
+			}
+
+			position, err := storage.CreatePosition(position)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(position)
+		case http.MethodPut:
+			// Rename or annotate a position, or close it with { id: 3, close: true, closePrices: {"7": 0.45} }
+			var req struct {
+				ID          int64              `json:"id"`
+				Name        *string            `json:"name"`
+				Notes       *string            `json:"notes"`
+				Close       bool               `json:"close"`
+				ClosePrices map[string]float64 `json:"closePrices"` // By leg id; omitted legs close at their last mark
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			position, err := storage.GetPosition(req.ID)
+			if err == sql.ErrNoRows {
+				http.Error(w, "Position not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if req.Name != nil || req.Notes != nil {
+				if req.Name != nil {
+					position.Name = *req.Name
+				}
+				if req.Notes != nil {
+					position.Notes = *req.Notes
+				}
+				if err := storage.UpdatePosition(position.ID, position.Name, position.Notes); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+			if req.Close {
+				closePrices := map[int64]float64{}
+				for k, v := range req.ClosePrices {
+					legID, err := strconv.ParseInt(k, 10, 64)
+					if err != nil {
+						http.Error(w, fmt.Sprintf("Invalid leg id: %s", k), http.StatusBadRequest)
+						return
+					}
+					closePrices[legID] = v
+				}
+				if err := storage.ClosePosition(position.ID, closePrices, time.Now()); err != nil {
+					http.Error(w, err.Error(), http.StatusConflict)
+					return
+				}
+			}
+
+			position, err = storage.GetPosition(req.ID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(position)
+		case http.MethodDelete:
+			id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid id", http.StatusBadRequest)
+				return
+			}
+			if err := storage.DeletePosition(id); err == sql.ErrNoRows {
+				http.Error(w, "Position not found", http.StatusNotFound)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/api/portfolio/marks", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Served from the background job unless a refresh is requested or none has run yet
+		report := portfolio.Latest()
+		if report == nil || r.URL.Query().Get("refresh") == "true" {
+			fresh, err := portfolio.Refresh()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			report = &fresh
+		}
+
+		if s := r.URL.Query().Get("account"); s != "" {
+			accountID, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid account", http.StatusBadRequest)
+				return
+			}
+			filtered := portfolio.Filter(*report, accountID)
+			report = &filtered
+		}
+
+		json.NewEncoder(w).Encode(report)
+	})
+
+	http.HandleFunc("/api/portfolio/risk", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Daily risk dashboard: book greeks, beta-weighted to SPY, by underlying and expiry bucket
+		report := portfolio.Latest()
+		if report == nil || r.URL.Query().Get("refresh") == "true" {
+			fresh, err := portfolio.Refresh()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			report = &fresh
+		}
+
+		if s := r.URL.Query().Get("account"); s != "" {
+			accountID, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid account", http.StatusBadRequest)
+				return
+			}
+			filtered := portfolio.Filter(*report, accountID)
+			report = &filtered
+		}
+
+		risk, err := portfolio.Risk(*report)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(risk)
+	})
+
+	http.HandleFunc("/api/earnings", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			// Manual entries, e.g. [{ticker: "AAPL", date: "2024-05-02", timing: "amc"}]
+			var req []struct {
+				Ticker string   `json:"ticker"`
+				Date   string   `json:"date"`
+				Timing string   `json:"timing"`
+				Move   *float64 `json:"move"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			for _, e := range req {
+				event, err := earnings.NewEvent(e.Ticker, e.Date, e.Timing, e.Move)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				if err := storage.SaveEarnings(event); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		days := 30
+		if s := r.URL.Query().Get("days"); s != "" {
+			if d, err := strconv.Atoi(s); err == nil && d > 0 {
+				days = d
+			}
+		}
+		events, err := earnings.Upcoming(strings.ToUpper(r.URL.Query().Get("ticker")), days)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(events)
+	})
+
+	http.HandleFunc("/api/earnings/reload", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		n, err := earnings.Sync()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload earnings: %v", err), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]int{"events": n})
+	})
+
+	http.HandleFunc("/api/earnings/analysis", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		ticker := strings.ToUpper(r.URL.Query().Get("ticker"))
+		if ticker == "" {
+			http.Error(w, "Ticker required", http.StatusBadRequest)
+			return
+		}
+
+		// The next report unless a date picks another one
+		var event storage.EarningsEvent
+		if s := r.URL.Query().Get("date"); s != "" {
+			date, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			events, err := storage.GetEarnings(ticker, date, date)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if len(events) == 0 {
+				http.Error(w, fmt.Sprintf("No earnings for %s on %s", ticker, s), http.StatusNotFound)
+				return
+			}
+			event = events[0]
+		} else {
+			next, err := earnings.NextEvent(ticker)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			event = next
+		}
+
+		analysis, err := earnings.Analyze(event)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(analysis)
+	})
+
+	http.HandleFunc("/api/whales", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		// Alerts from the last day unless since is given
+		since := time.Now().Add(-24 * time.Hour)
+		if s := r.URL.Query().Get("since"); s != "" {
+			t, err := calculator.ParseSince(s)
+			if err != nil {
+				http.Error(w, "Invalid since, expected RFC 3339 or YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			since = t
+		}
+
+		limit := 50
+		if s := r.URL.Query().Get("limit"); s != "" {
+			if l, err := strconv.Atoi(s); err == nil && l > 0 {
+				limit = l
+			}
+		}
+
+		alerts, err := storage.GetWhaleAlerts(strings.ToUpper(r.URL.Query().Get("ticker")), since, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(alerts)
+	})
+
+	http.HandleFunc("/api/whales/scan", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ticker := strings.ToUpper(r.URL.Query().Get("ticker"))
+		if ticker == "" {
+			http.Error(w, "Ticker required", http.StatusBadRequest)
+			return
+		}
+
+		alerts, err := whales.Scan(ticker)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(alerts)
+	})
+
+	http.HandleFunc("/api/whales/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			// Omitted fields keep their current value
+			t := whales.GetThresholds()
+			if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if t.MinVolume < 0 || t.MinVolumeOI < 0 || t.MinPremium < 0 {
+				http.Error(w, "Thresholds must not be negative", http.StatusBadRequest)
+				return
+			}
+			whales.SetThresholds(t)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		json.NewEncoder(w).Encode(whales.GetThresholds())
+	})
+
+	http.HandleFunc("/api/volatility", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		ticker := strings.ToUpper(r.URL.Query().Get("ticker"))
+		if ticker == "" {
+			http.Error(w, "Ticker required", http.StatusBadRequest)
+			return
+		}
+
+		window := volatility.DefaultHVWindow
+		if s := r.URL.Query().Get("window"); s != "" {
+			if n, err := strconv.Atoi(s); err == nil && n > 1 {
+				window = n
+			}
+		}
+
+		m, err := volatility.GetMetrics(ticker, window)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(m)
+	})
+
+	// Backfills daily history, e.g. from a vendor export, so IV rank and HV are usable before the job has run for a year
+	http.HandleFunc("/api/volatility/history", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Ticker string `json:"ticker"`
+			Days   []struct {
+				Date  string  `json:"date"`
+				Open  float64 `json:"open"`
+				High  float64 `json:"high"`
+				Low   float64 `json:"low"`
+				Close float64 `json:"close"`
+				IV    float64 `json:"iv"` // Optional ATM 30-day IV as a decimal
+			} `json:"days"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		ticker := strings.ToUpper(strings.TrimSpace(req.Ticker))
+		if ticker == "" {
+			http.Error(w, "Ticker required", http.StatusBadRequest)
+			return
+		}
+
+		bars, ivs := 0, 0
+		for _, d := range req.Days {
+			date, err := time.Parse("2006-01-02", d.Date)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid date %q", d.Date), http.StatusBadRequest)
+				return
+			}
+			if d.Close > 0 {
+				if d.Open <= 0 || d.High < d.Low || d.Low <= 0 {
+					http.Error(w, fmt.Sprintf("Invalid OHLC on %s", d.Date), http.StatusBadRequest)
+					return
+				}
+				if err := storage.SavePriceBar(storage.PriceBar{Ticker: ticker, Date: date, Open: d.Open, High: d.High, Low: d.Low, Close: d.Close}); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				bars++
+			}
+			if d.IV > 0 {
+				if err := storage.SaveIV(storage.IVPoint{Ticker: ticker, Date: date, IV: d.IV}); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				ivs++
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]int{"bars": bars, "ivs": ivs})
 	})
 
 	fmt.Println("Server starting on :8081...")
@@ -287,3 +1570,39 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// tradeMarketInputs looks up the dividends of the trade's underlying (its first leg with one) and, withSurface,
+// its vol surface. Without a surface legs are priced at their own IVs.
+func tradeMarketInputs(trade strategies.Trade, withSurface bool) (float64, []calculator.Dividend, calculator.VolSurface) {
+	for _, leg := range trade.Legs {
+		ticker := leg.Option.Underlying
+		if ticker == "" {
+			continue
+		}
+		yield, dividends := calculator.LookupDividends(ticker)
+		if !withSurface {
+			return yield, dividends, nil
+		}
+		surface, err := volsurface.Load(ticker)
+		if err != nil {
+			log.Printf("No vol surface for %s, using leg IVs: %v", ticker, err)
+			return yield, dividends, nil
+		}
+		return yield, dividends, surface
+	}
+	return 0, nil, nil
+}
+
+// envDuration reads a duration such as "15m" from the environment, logging and ignoring an invalid value
+func envDuration(name string, def time.Duration) time.Duration {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %v", name, s, def)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,256 @@
+package optimizer
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"sort"
+	"strikelogic/calculator"
+	"strikelogic/rates"
+	"strikelogic/strategies"
+	"strikelogic/volsurface"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Structures the optimizer enumerates
+const (
+	Vertical  = "vertical"  // Bull/bear call and put spreads
+	Butterfly = "butterfly" // Long call and put butterflies with equal wings
+	Condor    = "condor"    // Iron condors with equal wings
+	Ratio     = "ratio"     // 1x2 put ratio spreads
+)
+
+// Sort keys for Options.SortBy
+const (
+	SortScore   = "score"
+	SortEV      = "ev"
+	SortTarget  = "target"
+	SortPoP     = "pop"
+	SortMaxLoss = "maxLoss"
+)
+
+// Distribution methods
+const (
+	DistSurface   = "surface"
+	DistLognormal = "lognormal"
+)
+
+// Search limits
+const (
+	DefaultTopN    = 10
+	MaxStrikes     = 20 // Liquid strikes per option type, nearest the money first
+	MaxCondorWings = 4  // Widest condor wing, in strikes
+	MaxTargetROR   = 5  // Caps the target return's contribution to the score (500%), so lottery tickets don't dominate
+)
+
+// Score weights. Each term is a fraction: target P&L / max loss, EV / max loss and PoP.
+const (
+	WeightTarget = 0.5
+	WeightEV     = 0.3
+	WeightPoP    = 0.2
+)
+
+// Options controls a search
+type Options struct {
+	TargetPrice float64
+	TargetDate  time.Time // Defaults to the expiry
+	TopN        int       // Defaults to DefaultTopN
+	Structures  []string  // Defaults to all
+	MaxLoss     float64   // Dollars; 0 means no limit
+	SortBy      string    // Defaults to SortScore
+}
+
+// Candidate is a scored trade
+type Candidate struct {
+	strategies.Trade
+	Structure    string  `json:"structure"`
+	EVOnRisk     float64 `json:"evOnRisk"`     // Percent: EV / MaxRisk
+	TargetPnL    float64 `json:"targetPnL"`    // P&L at the target price on the target date, dollars
+	TargetReturn float64 `json:"targetReturn"` // Percent: TargetPnL / MaxRisk
+	Score        float64 `json:"score"`
+}
+
+// Optimize enumerates the requested structures within one expiry of chain and returns the best opts.TopN.
+// dist is the terminal distribution at that expiry, used for the trades' probabilities and EV (see ExpiryDistribution).
+func Optimize(chain []calculator.OptionContract, spot float64, dist calculator.Distribution, opts Options) ([]Candidate, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("empty option chain")
+	}
+	if opts.TargetPrice <= 0 {
+		return nil, fmt.Errorf("target price required")
+	}
+
+	expiry, err := time.Parse("2006-01-02", chain[0].Expiry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry %s: %v", chain[0].Expiry, err)
+	}
+	if opts.TargetDate.IsZero() || opts.TargetDate.After(expiry) {
+		opts.TargetDate = expiry
+	}
+	if opts.TopN <= 0 {
+		opts.TopN = DefaultTopN
+	}
+	less, err := sortFunc(opts.SortBy)
+	if err != nil {
+		return nil, err
+	}
+	structures := opts.Structures
+	if len(structures) == 0 {
+		structures = []string{Vertical, Butterfly, Condor, Ratio}
+	}
+
+	calls := liquidStrikes(chain, calculator.Call, spot)
+	puts := liquidStrikes(chain, calculator.Put, spot)
+
+	var trades []generated
+	for _, s := range structures {
+		switch strings.ToLower(s) {
+		case Vertical:
+			trades = append(trades, verticals(calls, puts)...)
+		case Butterfly:
+			trades = append(trades, butterflies(calls)...)
+			trades = append(trades, butterflies(puts)...)
+		case Condor:
+			trades = append(trades, condors(calls, puts, spot)...)
+		case Ratio:
+			trades = append(trades, ratios(puts)...)
+		default:
+			return nil, fmt.Errorf("unknown structure: %s", s)
+		}
+	}
+
+	daysToExpiry := math.Ceil(expiry.Sub(time.Now()).Hours() / 24)
+	expiryLabel := fmt.Sprintf("%s (%.0fd)", expiry.Format("Jan 02"), daysToExpiry)
+
+	// Scoring is CPU bound, so spread it over one worker per core
+	scored := make([]*Candidate, len(trades))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				scored[i] = score(trades[i], spot, dist, opts)
+			}
+		}()
+	}
+	for i := range trades {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var candidates []Candidate
+	for _, c := range scored {
+		if c != nil {
+			c.ExpiryLabel = expiryLabel
+			candidates = append(candidates, *c)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return less(candidates[i], candidates[j]) })
+
+	if len(candidates) > opts.TopN {
+		candidates = candidates[:opts.TopN]
+	}
+	return candidates, nil
+}
+
+// score computes the trade's metrics and score, or returns nil if it breaks the loss limit
+func score(g generated, spot float64, dist calculator.Distribution, opts Options) *Candidate {
+	trade := g.trade
+	trade.CalculatePayoff(spot)
+	// No possible loss only happens on stale or crossed quotes. Undefined risk has no loss to score against.
+	if trade.MaxRisk <= 0 || trade.UndefinedRisk || (opts.MaxLoss > 0 && trade.MaxRisk > opts.MaxLoss) {
+		return nil
+	}
+
+	trade.CalculateProbabilities(dist)
+	c := &Candidate{Trade: trade, Structure: g.structure}
+	c.TargetPnL = trade.PnLAt(opts.TargetPrice, opts.TargetDate)
+
+	evOnRisk := c.EV / trade.MaxRisk
+	targetROR := c.TargetPnL / trade.MaxRisk
+	c.EVOnRisk = math.Round(evOnRisk*1000) / 10
+	c.TargetReturn = math.Round(targetROR*1000) / 10
+	c.Score = WeightTarget*math.Min(targetROR, MaxTargetROR) + WeightEV*evOnRisk + WeightPoP*c.PoP/100
+	c.TargetPnL = math.Round(c.TargetPnL*100) / 100
+	c.Score = math.Round(c.Score*1000) / 1000
+	return c
+}
+
+// sortFunc orders candidates best first
+func sortFunc(by string) (func(a, b Candidate) bool, error) {
+	switch by {
+	case "", SortScore:
+		return func(a, b Candidate) bool { return a.Score > b.Score }, nil
+	case SortEV:
+		return func(a, b Candidate) bool { return a.EV > b.EV }, nil
+	case SortTarget:
+		return func(a, b Candidate) bool { return a.TargetReturn > b.TargetReturn }, nil
+	case SortPoP:
+		return func(a, b Candidate) bool { return a.PoP > b.PoP }, nil
+	case SortMaxLoss:
+		return func(a, b Candidate) bool { return a.MaxRisk < b.MaxRisk }, nil
+	}
+	return nil, fmt.Errorf("unknown sort: %s", by)
+}
+
+// ExpiryDistribution builds the terminal distribution for the chain's expiry.
+// DistSurface uses the ticker's fitted vol surface, falling back to a lognormal at the chain's ATM IV;
+// the method actually used is returned.
+func ExpiryDistribution(ticker string, spot float64, chain []calculator.OptionContract, method string) (calculator.Distribution, string, error) {
+	if len(chain) == 0 {
+		return calculator.Distribution{}, "", fmt.Errorf("empty option chain")
+	}
+	expiry, err := time.Parse("2006-01-02", chain[0].Expiry)
+	if err != nil {
+		return calculator.Distribution{}, "", fmt.Errorf("invalid expiry %s: %v", chain[0].Expiry, err)
+	}
+
+	T := math.Max(expiry.Sub(time.Now()).Hours()/24/365.0, 1.0/365)
+	r := rates.RateFor(T)
+	yield, _ := calculator.LookupDividends(ticker)
+
+	atmVol := atmIV(chain, spot)
+	if atmVol <= 0 {
+		return calculator.Distribution{}, "", fmt.Errorf("no implied volatility near the money")
+	}
+
+	switch method {
+	case "", DistSurface:
+		surface, err := volsurface.Load(ticker)
+		if err == nil {
+			return calculator.SmileDistribution(spot, T, r, yield, atmVol, func(K float64) float64 { return surface.Vol(K, T) }), DistSurface, nil
+		}
+		log.Printf("No vol surface for %s (%v), using lognormal", ticker, err)
+	case DistLognormal:
+	default:
+		return calculator.Distribution{}, "", fmt.Errorf("unknown distribution: %s", method)
+	}
+	return calculator.LognormalDistribution(spot, T, r, yield, atmVol), DistLognormal, nil
+}
+
+// atmIV averages the call and put IV at the strike closest to spot
+func atmIV(chain []calculator.OptionContract, spot float64) float64 {
+	best := math.MaxFloat64
+	for _, opt := range chain {
+		best = math.Min(best, math.Abs(opt.Strike-spot))
+	}
+
+	sum, n := 0.0, 0
+	for _, opt := range chain {
+		if math.Abs(opt.Strike-spot) == best && opt.Vol > 0 {
+			sum += opt.Vol
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
@@ -0,0 +1,166 @@
+package optimizer
+
+import (
+	"strikelogic/calculator"
+	"testing"
+	"time"
+)
+
+// testChain quotes calls and puts at strikes 90 to 110 by 5, 30 days out, at Black-Scholes prices +/- 5 cents
+func testChain(spot float64) []calculator.OptionContract {
+	expiry := time.Now().UTC().AddDate(0, 0, 30).Truncate(24 * time.Hour)
+	T := expiry.Sub(time.Now()).Hours() / 24 / 365
+	var chain []calculator.OptionContract
+	for _, typ := range []calculator.OptionType{calculator.Call, calculator.Put} {
+		for k := 90.0; k <= 110; k += 5 {
+			price, delta, _, _, _ := calculator.CalculateOptionPrice(typ, spot, k, T, 0.04, 0.3)
+			chain = append(chain, calculator.OptionContract{
+				Underlying: "XYZ", Expiry: expiry.Format("2006-01-02"), Type: typ, Strike: k,
+				Bid: price - 0.05, Ask: price + 0.05, Last: price, Vol: 0.3, Delta: delta,
+			})
+		}
+	}
+	return chain
+}
+
+func TestEnumeration(t *testing.T) {
+	chain := testChain(100)
+	calls := liquidStrikes(chain, calculator.Call, 100)
+	puts := liquidStrikes(chain, calculator.Put, 100)
+	if len(calls) != 5 || len(puts) != 5 {
+		t.Fatalf("%d calls and %d puts, want 5 each", len(calls), len(puts))
+	}
+
+	// Two verticals per strike pair and type: 10 pairs x 2 x 2
+	if n := len(verticals(calls, puts)); n != 40 {
+		t.Errorf("%d verticals, want 40", n)
+	}
+	// Equal wings around bodies 95 (1), 100 (2) and 105 (1)
+	if n := len(butterflies(calls)); n != 4 {
+		t.Errorf("%d call butterflies, want 4", n)
+	}
+	// Short puts 90 or 95 with a wing below, short calls above 100, wings of equal width
+	for _, g := range condors(calls, puts, 100) {
+		legs := g.trade.Legs
+		putWidth := legs[0].Option.Strike - legs[1].Option.Strike
+		callWidth := legs[3].Option.Strike - legs[2].Option.Strike
+		if putWidth != callWidth || legs[0].Option.Strike >= 100 || legs[2].Option.Strike <= 100 {
+			t.Errorf("bad condor: %s", g.trade.Description)
+		}
+	}
+	// One put ratio per strike pair, buying the higher strike
+	ratios := ratios(puts)
+	if len(ratios) != 10 {
+		t.Errorf("%d ratios, want 10", len(ratios))
+	}
+	for _, g := range ratios {
+		if g.trade.Legs[0].Option.Strike <= g.trade.Legs[1].Option.Strike || g.trade.Legs[1].Quantity != 2 {
+			t.Errorf("bad ratio: %s", g.trade.Description)
+		}
+	}
+}
+
+func TestLiquidStrikes(t *testing.T) {
+	var chain []calculator.OptionContract
+	for k := 50.0; k <= 150; k++ {
+		chain = append(chain, calculator.OptionContract{Type: calculator.Call, Strike: k, Bid: 1, Ask: 1.1})
+	}
+	chain = append(chain, calculator.OptionContract{Type: calculator.Call, Strike: 100.5, Ask: 1}) // One-sided
+
+	got := liquidStrikes(chain, calculator.Call, 100.2)
+	if len(got) != MaxStrikes {
+		t.Fatalf("%d strikes, want %d", len(got), MaxStrikes)
+	}
+	// The nearest MaxStrikes to spot, in strike order
+	if got[0].Strike != 91 || got[len(got)-1].Strike != 110 {
+		t.Errorf("strikes %v..%v, want 91..110", got[0].Strike, got[len(got)-1].Strike)
+	}
+	for _, c := range got {
+		if c.Strike == 100.5 {
+			t.Error("a one-sided quote was kept")
+		}
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	chain := testChain(100)
+	expiry, _ := time.Parse("2006-01-02", chain[0].Expiry)
+	dist := calculator.LognormalDistribution(100, expiry.Sub(time.Now()).Hours()/24/365, 0.04, 0, 0.3)
+
+	all, err := Optimize(chain, 100, dist, Options{TargetPrice: 108, TopN: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 {
+		t.Fatal("no candidates")
+	}
+	for i, c := range all {
+		if c.UndefinedRisk || c.MaxRisk <= 0 {
+			t.Errorf("%s %s kept with undefined or no risk", c.Name, c.Description)
+		}
+		if i > 0 && c.Score > all[i-1].Score {
+			t.Fatalf("not sorted by score at %d", i)
+		}
+	}
+
+	limited, err := Optimize(chain, 100, dist, Options{TargetPrice: 108, MaxLoss: 200, TopN: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) == 0 || len(limited) >= len(all) {
+		t.Errorf("max loss kept %d of %d candidates", len(limited), len(all))
+	}
+	for _, c := range limited {
+		if c.MaxRisk > 200 {
+			t.Errorf("%s risks %v over the 200 limit", c.Description, c.MaxRisk)
+		}
+	}
+
+	top, err := Optimize(chain, 100, dist, Options{TargetPrice: 108, TopN: 3, Structures: []string{Vertical}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 3 {
+		t.Fatalf("%d candidates, want 3", len(top))
+	}
+	for _, c := range top {
+		if c.Structure != Vertical || c.Sentiment != "Bullish" {
+			t.Errorf("a target above spot ranked %s %s", c.Sentiment, c.Name)
+		}
+	}
+
+	for _, opts := range []Options{{}, {TargetPrice: 108, Structures: []string{"strangle"}}, {TargetPrice: 108, SortBy: "delta"}} {
+		if _, err := Optimize(chain, 100, dist, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	a := Candidate{Score: 1, TargetReturn: 50, EVOnRisk: 1}
+	a.EV, a.PoP, a.MaxRisk = 10, 40, 500
+	b := Candidate{Score: 2, TargetReturn: 20, EVOnRisk: 2}
+	b.EV, b.PoP, b.MaxRisk = 5, 70, 100
+
+	tests := []struct {
+		by     string
+		aFirst bool
+		key    string
+	}{
+		{"", false, "score"},
+		{SortScore, false, "score"},
+		{SortEV, true, "EV"},
+		{SortTarget, true, "target return"},
+		{SortPoP, false, "PoP"},
+		{SortMaxLoss, false, "max loss"},
+	}
+	for _, tt := range tests {
+		less, err := sortFunc(tt.by)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.by, err)
+		}
+		if less(a, b) != tt.aFirst || less(b, a) == tt.aFirst {
+			t.Errorf("sort %q by %s: a first = %v, want %v", tt.by, tt.key, less(a, b), tt.aFirst)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strikelogic/calculator"
+	"strikelogic/strategies"
+	"strings"
+)
+
+// generated is an enumerated trade before scoring
+type generated struct {
+	structure string
+	trade     strategies.Trade
+}
+
+// liquidStrikes returns the two-sided contracts of one type, the MaxStrikes closest to spot, ordered by strike
+func liquidStrikes(chain []calculator.OptionContract, optType calculator.OptionType, spot float64) []calculator.OptionContract {
+	var out []calculator.OptionContract
+	for _, opt := range chain {
+		if opt.Type == optType && opt.Bid > 0 && opt.Ask > 0 {
+			out = append(out, opt)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return math.Abs(out[i].Strike-spot) < math.Abs(out[j].Strike-spot) })
+	if len(out) > MaxStrikes {
+		out = out[:MaxStrikes]
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Strike < out[j].Strike })
+	return out
+}
+
+type legSpec struct {
+	action   strategies.Action
+	quantity int
+	option   calculator.OptionContract
+}
+
+func newTrade(structure, name, sentiment string, legs ...legSpec) generated {
+	trade := strategies.Trade{Name: name, Sentiment: sentiment}
+	var parts []string
+	for _, l := range legs {
+		trade.Legs = append(trade.Legs, strategies.TradeLeg{Action: l.action, Quantity: l.quantity, Option: l.option})
+		parts = append(parts, fmt.Sprintf("%s %d %s %.2f", l.action, l.quantity, l.option.Type, l.option.Strike))
+	}
+	trade.Description = strings.Join(parts, ", ")
+	return generated{structure: structure, trade: trade}
+}
+
+func verticals(calls, puts []calculator.OptionContract) []generated {
+	var out []generated
+	for i := range calls {
+		for j := i + 1; j < len(calls); j++ {
+			lo, hi := calls[i], calls[j]
+			out = append(out,
+				newTrade(Vertical, "Bull Call Spread", "Bullish", legSpec{strategies.Buy, 1, lo}, legSpec{strategies.Sell, 1, hi}),
+				newTrade(Vertical, "Bear Call Spread", "Bearish", legSpec{strategies.Sell, 1, lo}, legSpec{strategies.Buy, 1, hi}),
+			)
+		}
+	}
+	for i := range puts {
+		for j := i + 1; j < len(puts); j++ {
+			lo, hi := puts[i], puts[j]
+			out = append(out,
+				newTrade(Vertical, "Bull Put Spread", "Bullish", legSpec{strategies.Sell, 1, hi}, legSpec{strategies.Buy, 1, lo}),
+				newTrade(Vertical, "Bear Put Spread", "Bearish", legSpec{strategies.Buy, 1, hi}, legSpec{strategies.Sell, 1, lo}),
+			)
+		}
+	}
+	return out
+}
+
+// butterflies are long butterflies on one option type: buy the wings, sell two of the body
+func butterflies(chain []calculator.OptionContract) []generated {
+	var out []generated
+	for j := 1; j < len(chain)-1; j++ {
+		body := chain[j]
+		for i := j - 1; i >= 0; i-- {
+			lower := chain[i]
+			upper := strikeAt(chain, 2*body.Strike-lower.Strike)
+			if upper == nil {
+				continue
+			}
+			name := fmt.Sprintf("%s Butterfly", body.Type)
+			out = append(out, newTrade(Butterfly, name, "Neutral",
+				legSpec{strategies.Buy, 1, lower}, legSpec{strategies.Sell, 2, body}, legSpec{strategies.Buy, 1, *upper}))
+		}
+	}
+	return out
+}
+
+// condors are iron condors with out-of-the-money short strikes and equal wing widths
+func condors(calls, puts []calculator.OptionContract, spot float64) []generated {
+	var out []generated
+	for j, shortPut := range puts {
+		if shortPut.Strike >= spot {
+			continue
+		}
+		for k, shortCall := range calls {
+			if shortCall.Strike <= spot {
+				continue
+			}
+			for w := 1; w <= MaxCondorWings && j-w >= 0; w++ {
+				longPut := puts[j-w]
+				longCall := strikeAt(calls[k+1:], shortCall.Strike+shortPut.Strike-longPut.Strike)
+				if longCall == nil {
+					continue
+				}
+				out = append(out, newTrade(Condor, "Iron Condor", "Neutral",
+					legSpec{strategies.Sell, 1, shortPut}, legSpec{strategies.Buy, 1, longPut},
+					legSpec{strategies.Sell, 1, shortCall}, legSpec{strategies.Buy, 1, *longCall}))
+			}
+		}
+	}
+	return out
+}
+
+// ratios buy one put and sell two further out of the money. Call ratios are left out: their second
+// short call is naked, so the loss is unbounded (see Trade.UndefinedRisk).
+func ratios(puts []calculator.OptionContract) []generated {
+	var out []generated
+	for i := range puts {
+		for j := i + 1; j < len(puts); j++ {
+			out = append(out, newTrade(Ratio, "Put Ratio Spread", "Bearish",
+				legSpec{strategies.Buy, 1, puts[j]}, legSpec{strategies.Sell, 2, puts[i]}))
+		}
+	}
+	return out
+}
+
+// strikeAt returns the contract at strike, or nil
+func strikeAt(chain []calculator.OptionContract, strike float64) *calculator.OptionContract {
+	for i := range chain {
+		if math.Abs(chain[i].Strike-strike) < 1e-6 {
+			return &chain[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,372 @@
+package portfolio
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strikelogic/calculator"
+	"strikelogic/storage"
+	"strikelogic/strategies"
+	"sync"
+	"time"
+)
+
+// LegMark is the current price and per-share greeks of one leg
+type LegMark struct {
+	LegID    int64   `json:"legId"`
+	Type     string  `json:"type"`
+	Action   string  `json:"action"`
+	Strike   float64 `json:"strike"`
+	Expiry   string  `json:"expiry"`
+	Quantity int     `json:"quantity"`
+	Mark     float64 `json:"mark"`
+	IV       float64 `json:"iv"`
+	Delta    float64 `json:"delta"`
+	Gamma    float64 `json:"gamma"`
+	Theta    float64 `json:"theta"`
+	Vega     float64 `json:"vega"`
+	Stale    bool    `json:"stale,omitempty"` // Could not be marked; Mark is the last saved one, or the fill price if none
+}
+
+// PositionMark is a position valued at current prices.
+// Amounts are in dollars; greeks are position totals (Delta in shares, Theta per day, Vega per vol point).
+type PositionMark struct {
+	PositionID    int64     `json:"positionId"`
+	AccountID     int64     `json:"accountId"`
+	Ticker        string    `json:"ticker"`
+	Name          string    `json:"name"`
+	Underlying    float64   `json:"underlying"`
+	Cost          float64   `json:"cost"`  // Net debit paid; negative for a credit
+	Value         float64   `json:"value"` // Net value at the marks
+	UnrealizedPnL float64   `json:"unrealizedPnl"`
+	Delta         float64   `json:"delta"`
+	Gamma         float64   `json:"gamma"`
+	Theta         float64   `json:"theta"`
+	Vega          float64   `json:"vega"`
+	DTE           int       `json:"dte"` // Days to the front option expiry
+	Legs          []LegMark `json:"legs"`
+	Error         string    `json:"error,omitempty"` // Set when some leg could not be marked; totals then use its last mark (or fill price)
+}
+
+// Report is the mark-to-market of every open position
+type Report struct {
+	MarkedAt      time.Time      `json:"markedAt"`
+	Positions     []PositionMark `json:"positions"`
+	Cost          float64        `json:"cost"`
+	Value         float64        `json:"value"`
+	UnrealizedPnL float64        `json:"unrealizedPnl"`
+	Delta         float64        `json:"delta"`
+	Gamma         float64        `json:"gamma"`
+	Theta         float64        `json:"theta"`
+	Vega          float64        `json:"vega"`
+}
+
+var (
+	latestMu sync.RWMutex
+	latest   *Report
+)
+
+// Latest returns the most recent report produced by Run or Refresh, or nil before the first one
+func Latest() *Report {
+	latestMu.RLock()
+	defer latestMu.RUnlock()
+	return latest
+}
+
+// Refresh marks every open position, saves the leg marks and makes the result the latest report.
+// During the session it also stores the underlying and Benchmark quotes, which Risk computes betas from.
+func Refresh() (Report, error) {
+	positions, err := storage.GetPositions(0, storage.PositionOpen)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := MarkToMarket(positions, time.Now())
+	for _, pm := range report.Positions {
+		for _, lm := range pm.Legs {
+			if lm.Stale {
+				continue
+			}
+			if err := storage.SaveLegMark(lm.LegID, lm.Mark, report.MarkedAt); err != nil {
+				log.Printf("Error saving mark for leg %d: %v", lm.LegID, err)
+			}
+		}
+	}
+	if len(report.Positions) > 0 && calculator.IsMarketOpen(report.MarkedAt) {
+		saveQuotes(report)
+	}
+
+	latestMu.Lock()
+	latest = &report
+	latestMu.Unlock()
+	return report, nil
+}
+
+// saveQuotes stores the report's underlying prices and a fresh Benchmark quote
+func saveQuotes(report Report) {
+	prices := map[string]float64{}
+	for _, pm := range report.Positions {
+		if pm.Underlying > 0 {
+			prices[pm.Ticker] = pm.Underlying
+		}
+	}
+	if _, ok := prices[Benchmark]; !ok {
+		provider, _ := calculator.Providers()
+		if price, err := provider.Quote(Benchmark); err == nil {
+			prices[Benchmark] = price
+		} else {
+			log.Printf("Error quoting %s: %v", Benchmark, err)
+		}
+	}
+
+	for ticker, price := range prices {
+		if err := storage.SaveQuote(storage.UnderlyingQuote{Ticker: ticker, Price: price, CapturedAt: report.MarkedAt}); err != nil {
+			log.Printf("Error saving %s quote: %v", ticker, err)
+		}
+	}
+}
+
+// Run refreshes the marks at startup and then every interval while the market is open. It never returns.
+func Run(interval time.Duration) {
+	for first := true; ; first = false {
+		if !first && !calculator.IsMarketOpen(time.Now()) {
+			time.Sleep(interval)
+			continue
+		}
+		report, err := Refresh()
+		if err != nil {
+			log.Printf("Error marking portfolio: %v", err)
+		} else {
+			log.Printf("Marked %d positions, unrealized P&L %.2f", len(report.Positions), report.UnrealizedPnL)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// chainKey identifies one fetched chain
+type chainKey struct {
+	ticker string
+	expiry string
+}
+
+// MarkToMarket values positions at current quotes. Each underlying's quote and each expiry's chain is fetched once.
+// Option legs are marked at the mid (last trade when there is no market); expired legs at intrinsic value.
+// Only the primary provider is used: a leg the provider can't quote stays stale rather than taking a mock mark.
+func MarkToMarket(positions []storage.Position, now time.Time) Report {
+	provider, _ := calculator.Providers()
+	quotes := map[string]float64{}
+	quoteErrs := map[string]error{}
+	chains := map[chainKey][]calculator.OptionContract{}
+	chainErrs := map[chainKey]error{}
+
+	quote := func(ticker string) (float64, error) {
+		if _, ok := quotes[ticker]; !ok && quoteErrs[ticker] == nil {
+			quotes[ticker], quoteErrs[ticker] = provider.Quote(ticker)
+		}
+		return quotes[ticker], quoteErrs[ticker]
+	}
+	chain := func(key chainKey) ([]calculator.OptionContract, error) {
+		if _, ok := chains[key]; !ok && chainErrs[key] == nil {
+			chains[key], chainErrs[key] = calculator.GetOptionsChainFrom(provider, key.ticker, key.expiry)
+		}
+		return chains[key], chainErrs[key]
+	}
+
+	report := Report{MarkedAt: now, Positions: []PositionMark{}}
+	for _, p := range positions {
+		report.Positions = append(report.Positions, markPosition(p, now, quote, chain))
+	}
+	report.total()
+	return report
+}
+
+// Filter returns the part of the report belonging to one account, with totals recomputed
+func Filter(report Report, accountID int64) Report {
+	filtered := Report{MarkedAt: report.MarkedAt, Positions: []PositionMark{}}
+	for _, pm := range report.Positions {
+		if pm.AccountID == accountID {
+			filtered.Positions = append(filtered.Positions, pm)
+		}
+	}
+	filtered.total()
+	return filtered
+}
+
+// total sums the position amounts and greeks into the report totals
+func (r *Report) total() {
+	r.Cost, r.Value, r.UnrealizedPnL, r.Delta, r.Gamma, r.Theta, r.Vega = 0, 0, 0, 0, 0, 0, 0
+	for _, pm := range r.Positions {
+		r.Cost += pm.Cost
+		r.Value += pm.Value
+		r.UnrealizedPnL += pm.UnrealizedPnL
+		r.Delta += pm.Delta
+		r.Gamma += pm.Gamma
+		r.Theta += pm.Theta
+		r.Vega += pm.Vega
+	}
+
+	r.Cost = round(r.Cost, 100)
+	r.Value = round(r.Value, 100)
+	r.UnrealizedPnL = round(r.UnrealizedPnL, 100)
+	r.Delta = round(r.Delta, 100)
+	r.Gamma = round(r.Gamma, 1000)
+	r.Theta = round(r.Theta, 100)
+	r.Vega = round(r.Vega, 100)
+}
+
+// markPosition values one position using the shared quote and chain lookups
+func markPosition(p storage.Position, now time.Time, quote func(string) (float64, error), chain func(chainKey) ([]calculator.OptionContract, error)) PositionMark {
+	pm := PositionMark{PositionID: p.ID, AccountID: p.AccountID, Ticker: p.Ticker, Name: p.Name, DTE: -1, Legs: []LegMark{}}
+
+	spot, err := quote(p.Ticker)
+	if err != nil {
+		pm.Error = fmt.Sprintf("quote: %v", err)
+	}
+	pm.Underlying = spot
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, leg := range p.Legs {
+		sign := 1.0
+		if leg.Action == "Sell" {
+			sign = -1
+		}
+		lm := LegMark{LegID: leg.ID, Type: leg.Type, Action: leg.Action, Strike: leg.Strike, Expiry: leg.Expiry, Quantity: leg.Quantity, Mark: leg.Mark}
+		if leg.MarkedAt == nil {
+			lm.Mark = leg.FillPrice // Never marked: a stale leg is carried at cost, not zero
+		}
+
+		mult := float64(leg.Quantity) * 100
+		if leg.Type == "Stock" {
+			mult = float64(leg.Quantity)
+			if spot > 0 {
+				lm.Mark, lm.Delta = spot, 1
+			} else {
+				lm.Stale = true
+			}
+		} else if err := markOption(&lm, p.Ticker, spot, today, chain); err != nil {
+			lm.Stale = true
+			if pm.Error == "" {
+				pm.Error = err.Error()
+			}
+		} else {
+			expiry, _ := time.Parse("2006-01-02", leg.Expiry)
+			dte := int(math.Max(0, expiry.Sub(today).Hours()/24))
+			if pm.DTE < 0 || dte < pm.DTE {
+				pm.DTE = dte
+			}
+		}
+
+		pm.Cost += sign * leg.FillPrice * mult
+		pm.Value += sign * lm.Mark * mult
+		pm.Delta += sign * lm.Delta * mult
+		pm.Gamma += sign * lm.Gamma * mult
+		pm.Theta += sign * lm.Theta * mult
+		pm.Vega += sign * lm.Vega * mult
+		pm.Legs = append(pm.Legs, lm)
+	}
+	if pm.DTE < 0 {
+		pm.DTE = 0
+	}
+
+	pm.UnrealizedPnL = round(pm.Value-pm.Cost, 100)
+	pm.Cost = round(pm.Cost, 100)
+	pm.Value = round(pm.Value, 100)
+	pm.Delta = round(pm.Delta, 100)
+	pm.Gamma = round(pm.Gamma, 1000)
+	pm.Theta = round(pm.Theta, 100)
+	pm.Vega = round(pm.Vega, 100)
+	return pm
+}
+
+// markOption fills in an option leg's mark and greeks from its expiry's chain
+func markOption(lm *LegMark, ticker string, spot float64, today time.Time, chain func(chainKey) ([]calculator.OptionContract, error)) error {
+	expiry, err := time.Parse("2006-01-02", lm.Expiry)
+	if err != nil {
+		return fmt.Errorf("leg %d: invalid expiry %q", lm.LegID, lm.Expiry)
+	}
+
+	// Expired: settle at intrinsic value
+	if expiry.Before(today) {
+		if spot <= 0 {
+			return fmt.Errorf("leg %d: no underlying price to settle", lm.LegID)
+		}
+		lm.Mark, lm.Delta, lm.Gamma, lm.Theta, lm.Vega, lm.IV = 0, 0, 0, 0, 0, 0
+		if lm.Type == string(calculator.Call) {
+			lm.Mark = math.Max(0, spot-lm.Strike)
+		} else {
+			lm.Mark = math.Max(0, lm.Strike-spot)
+		}
+		lm.Mark = round(lm.Mark, 100)
+		return nil
+	}
+
+	contracts, err := chain(chainKey{ticker: ticker, expiry: lm.Expiry})
+	if err != nil {
+		return fmt.Errorf("chain %s %s: %v", ticker, lm.Expiry, err)
+	}
+	for _, c := range contracts {
+		if c.Expiry != lm.Expiry || string(c.Type) != lm.Type || math.Abs(c.Strike-lm.Strike) > 0.001 {
+			continue
+		}
+		mark := c.Last
+		if c.Bid > 0 && c.Ask > 0 {
+			mark = (c.Bid + c.Ask) / 2
+		}
+		lm.Mark = round(mark, 100)
+		lm.IV, lm.Delta, lm.Gamma, lm.Theta, lm.Vega = c.Vol, c.Delta, c.Gamma, c.Theta, c.Vega
+		return nil
+	}
+	return fmt.Errorf("no quote for %s %s %.2f %s", ticker, lm.Expiry, lm.Strike, lm.Type)
+}
+
+func round(v, scale float64) float64 {
+	return math.Round(v*scale) / scale
+}
+
+// LegsFromTrade converts a trade from the strategy generator into position legs,
+// filled at the entry prices CalculateMetrics assumes (see strategies.TradeLeg.EntryPrice)
+func LegsFromTrade(t strategies.Trade, fillTime time.Time) []storage.PositionLeg {
+	legs := make([]storage.PositionLeg, 0, len(t.Legs))
+	for _, leg := range t.Legs {
+		pl := storage.PositionLeg{
+			Type:      string(leg.Option.Type),
+			Action:    string(leg.Action),
+			Strike:    leg.Option.Strike,
+			Expiry:    leg.Option.Expiry,
+			Quantity:  leg.Quantity,
+			FillPrice: leg.EntryPrice(),
+			FillTime:  fillTime,
+		}
+		if leg.IsStock {
+			pl.Type, pl.Strike, pl.Expiry = "Stock", 0, ""
+		}
+		legs = append(legs, pl)
+	}
+	return legs
+}
+
+// ValidateLeg checks a leg before it is stored
+func ValidateLeg(leg storage.PositionLeg) error {
+	if leg.Action != string(strategies.Buy) && leg.Action != string(strategies.Sell) {
+		return fmt.Errorf("invalid action %q, expected Buy or Sell", leg.Action)
+	}
+	if leg.Quantity <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if leg.FillPrice < 0 {
+		return fmt.Errorf("fill price must not be negative")
+	}
+	switch leg.Type {
+	case "Stock":
+		return nil
+	case string(calculator.Call), string(calculator.Put):
+		if leg.Strike <= 0 {
+			return fmt.Errorf("strike required")
+		}
+		if _, err := time.Parse("2006-01-02", leg.Expiry); err != nil {
+			return fmt.Errorf("invalid expiry %q, expected YYYY-MM-DD", leg.Expiry)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid type %q, expected Call, Put or Stock", leg.Type)
+}
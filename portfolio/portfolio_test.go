@@ -0,0 +1,62 @@
+package portfolio
+
+import (
+	"fmt"
+	"strikelogic/calculator"
+	"strikelogic/storage"
+	"testing"
+	"time"
+)
+
+// coveredCall is 100 shares bought at 95 and a short 105 call sold for 2.5, last marked at 1.8
+func coveredCall() storage.Position {
+	marked := time.Date(2030, 1, 9, 20, 0, 0, 0, time.UTC)
+	return storage.Position{ID: 1, AccountID: 1, Ticker: "XYZ", Name: "Covered Call", Legs: []storage.PositionLeg{
+		{ID: 10, Type: "Stock", Action: "Buy", Quantity: 100, FillPrice: 95},
+		{ID: 11, Type: "Call", Action: "Sell", Strike: 105, Expiry: "2030-01-18", Quantity: 1, FillPrice: 2.5, Mark: 1.8, MarkedAt: &marked},
+	}}
+}
+
+func TestMarkPosition(t *testing.T) {
+	now := time.Date(2030, 1, 10, 15, 0, 0, 0, time.UTC)
+	quote := func(string) (float64, error) { return 100, nil }
+	chain := func(key chainKey) ([]calculator.OptionContract, error) {
+		return []calculator.OptionContract{
+			{Expiry: key.expiry, Type: calculator.Call, Strike: 100, Bid: 3, Ask: 3.2},
+			{Expiry: key.expiry, Type: calculator.Call, Strike: 105, Bid: 1, Ask: 1.2, Vol: 0.3, Delta: 0.25, Theta: -0.05},
+		}, nil
+	}
+
+	pm := markPosition(coveredCall(), now, quote, chain)
+	if pm.Error != "" {
+		t.Fatalf("unexpected error %q", pm.Error)
+	}
+	// Stock at 100 and the call at its 1.1 mid
+	if pm.Legs[0].Mark != 100 || pm.Legs[1].Mark != 1.1 {
+		t.Errorf("marks %v and %v, want 100 and 1.1", pm.Legs[0].Mark, pm.Legs[1].Mark)
+	}
+	if pm.Cost != 9250 || pm.Value != 9890 || pm.UnrealizedPnL != 640 {
+		t.Errorf("cost %v value %v P&L %v, want 9250, 9890 and 640", pm.Cost, pm.Value, pm.UnrealizedPnL)
+	}
+	if pm.Delta != 75 || pm.Theta != 5 || pm.DTE != 8 {
+		t.Errorf("delta %v theta %v DTE %d, want 75, 5 and 8", pm.Delta, pm.Theta, pm.DTE)
+	}
+
+	// Without a chain the call keeps its last mark; never-marked legs would use the fill price
+	noChain := func(chainKey) ([]calculator.OptionContract, error) { return nil, fmt.Errorf("down") }
+	stale := markPosition(coveredCall(), now, quote, noChain)
+	if stale.Error == "" || !stale.Legs[1].Stale || stale.Legs[1].Mark != 1.8 {
+		t.Errorf("stale leg %+v, error %q", stale.Legs[1], stale.Error)
+	}
+	p := coveredCall()
+	p.Legs[1].MarkedAt = nil
+	if stale := markPosition(p, now, quote, noChain); stale.Legs[1].Mark != 2.5 || stale.UnrealizedPnL != 500 {
+		t.Errorf("never-marked leg at %v with P&L %v, want the 2.5 fill and 500", stale.Legs[1].Mark, stale.UnrealizedPnL)
+	}
+
+	// Past expiry the call settles at intrinsic value without a chain
+	expired := markPosition(coveredCall(), time.Date(2030, 1, 21, 15, 0, 0, 0, time.UTC), func(string) (float64, error) { return 108, nil }, noChain)
+	if expired.Error != "" || expired.Legs[1].Mark != 3 || expired.Legs[1].Delta != 0 {
+		t.Errorf("expired call %+v, error %q", expired.Legs[1], expired.Error)
+	}
+}
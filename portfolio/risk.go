@@ -0,0 +1,281 @@
+package portfolio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strikelogic/calculator"
+	"strikelogic/storage"
+	"time"
+)
+
+// Beta settings
+const (
+	Benchmark           = "SPY"
+	BetaLookbackDays    = 180
+	MinBetaObservations = 20 // Daily returns needed before a computed beta is trusted; below this beta defaults to 1
+)
+
+// ExpiryBuckets are the DTE ranges risk is grouped into; stock legs go in their own "stock" bucket
+var ExpiryBuckets = []struct {
+	Name   string
+	MaxDTE int
+}{
+	{"0-7", 7},
+	{"8-30", 30},
+	{"31-60", 60},
+	{"61-90", 90},
+	{"91-180", 180},
+	{"181+", math.MaxInt32},
+}
+
+// Exposure is the summed risk of a group of legs. Delta is in shares of the underlying, so it only
+// adds up within one ticker; BetaDelta is in benchmark shares. Theta is per day and Vega per vol point, in dollars.
+type Exposure struct {
+	Name      string  `json:"name"`
+	Positions int     `json:"positions"`
+	Delta     float64 `json:"delta"`
+	BetaDelta float64 `json:"betaDelta"`
+	Gamma     float64 `json:"gamma"`
+	Theta     float64 `json:"theta"`
+	Vega      float64 `json:"vega"`
+}
+
+// UnderlyingRisk is the exposure to one ticker along with its beta to the benchmark
+type UnderlyingRisk struct {
+	Exposure
+	Price            float64 `json:"price"`
+	DollarDelta      float64 `json:"dollarDelta"` // Delta x price
+	Beta             float64 `json:"beta"`
+	BetaObservations int     `json:"betaObservations"` // Daily returns used; zero when beta defaulted to 1
+	UnrealizedPnL    float64 `json:"unrealizedPnl"`
+}
+
+// RiskReport aggregates greeks across the book
+type RiskReport struct {
+	AsOf           time.Time        `json:"asOf"`
+	Benchmark      string           `json:"benchmark"`
+	BenchmarkPrice float64          `json:"benchmarkPrice"`
+	Positions      int              `json:"positions"`
+	BetaDelta      float64          `json:"betaDelta"`   // Benchmark-equivalent shares
+	DollarDelta    float64          `json:"dollarDelta"` // Sum of delta x price across underlyings
+	Gamma          float64          `json:"gamma"`
+	Theta          float64          `json:"theta"`
+	Vega           float64          `json:"vega"`
+	UnrealizedPnL  float64          `json:"unrealizedPnl"`
+	ByUnderlying   []UnderlyingRisk `json:"byUnderlying"`
+	ByExpiry       []Exposure       `json:"byExpiry"`
+	Warnings       []string         `json:"warnings,omitempty"`
+}
+
+// Beta estimates ticker's beta to the benchmark from daily closes captured over the lookback window,
+// returning the number of paired daily returns used
+func Beta(ticker, benchmark string, asOf time.Time) (float64, int, error) {
+	if ticker == benchmark {
+		return 1, 0, nil
+	}
+	from := asOf.AddDate(0, 0, -BetaLookbackDays)
+
+	tickerCloses, err := storage.GetDailyCloses(ticker, from, asOf)
+	if err != nil {
+		return 0, 0, err
+	}
+	benchCloses, err := storage.GetDailyCloses(benchmark, from, asOf)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	x, y := pairedReturns(benchCloses, tickerCloses)
+	if len(x) < MinBetaObservations {
+		return 0, len(x), fmt.Errorf("only %d daily returns for %s against %s, need %d", len(x), ticker, benchmark, MinBetaObservations)
+	}
+
+	meanX, meanY := mean(x), mean(y)
+	cov, varX := 0.0, 0.0
+	for i := range x {
+		cov += (x[i] - meanX) * (y[i] - meanY)
+		varX += (x[i] - meanX) * (x[i] - meanX)
+	}
+	if varX == 0 {
+		return 0, len(x), fmt.Errorf("no benchmark variance")
+	}
+	return cov / varX, len(x), nil
+}
+
+// pairedReturns returns daily log returns of both series over consecutive days present in both
+func pairedReturns(bench, other []storage.UnderlyingQuote) (x, y []float64) {
+	byDay := map[string]float64{}
+	for _, q := range other {
+		byDay[q.CapturedAt.UTC().Format("2006-01-02")] = q.Price
+	}
+
+	var prevBench, prevOther float64
+	for _, q := range bench {
+		price, ok := byDay[q.CapturedAt.UTC().Format("2006-01-02")]
+		if !ok {
+			prevBench, prevOther = 0, 0
+			continue
+		}
+		if prevBench > 0 && prevOther > 0 && q.Price > 0 && price > 0 {
+			x = append(x, math.Log(q.Price/prevBench))
+			y = append(y, math.Log(price/prevOther))
+		}
+		prevBench, prevOther = q.Price, price
+	}
+	return x, y
+}
+
+func mean(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
+
+// Risk aggregates a mark-to-market report by underlying and expiry bucket, beta-weighting delta to Benchmark.
+// Betas that can't be computed from stored history default to 1 and are listed in Warnings; the history is
+// the daily closes captured by chain snapshots and by Refresh. Underlyings without a quote have no beta-weighted delta.
+// The benchmark is quoted from the primary provider only, like the marks.
+func Risk(report Report) (RiskReport, error) {
+	provider, _ := calculator.Providers()
+	benchPrice, err := provider.Quote(Benchmark)
+	if err != nil {
+		return RiskReport{}, fmt.Errorf("benchmark quote: %v", err)
+	}
+
+	risk := RiskReport{AsOf: report.MarkedAt, Benchmark: Benchmark, BenchmarkPrice: benchPrice, Positions: len(report.Positions)}
+
+	underlyings := map[string]*UnderlyingRisk{}
+	var tickers []string
+	buckets := map[string]*Exposure{}
+	bucketPositions := map[string]map[int64]bool{}
+
+	today := time.Date(report.MarkedAt.Year(), report.MarkedAt.Month(), report.MarkedAt.Day(), 0, 0, 0, 0, time.UTC)
+	for _, pm := range report.Positions {
+		if pm.Error != "" {
+			risk.Warnings = append(risk.Warnings, fmt.Sprintf("Position %d (%s) uses stale marks: %s", pm.PositionID, pm.Ticker, pm.Error))
+		}
+
+		u, ok := underlyings[pm.Ticker]
+		if !ok {
+			u = &UnderlyingRisk{Exposure: Exposure{Name: pm.Ticker}, Price: pm.Underlying, Beta: 1}
+			beta, n, err := Beta(pm.Ticker, Benchmark, report.MarkedAt)
+			if err != nil {
+				risk.Warnings = append(risk.Warnings, fmt.Sprintf("Beta for %s defaulted to 1: %v", pm.Ticker, err))
+			} else {
+				u.Beta, u.BetaObservations = beta, n
+			}
+			if u.Price <= 0 {
+				risk.Warnings = append(risk.Warnings, fmt.Sprintf("No quote for %s: its delta is left out of the beta-weighted and dollar delta", pm.Ticker))
+			}
+			underlyings[pm.Ticker] = u
+			tickers = append(tickers, pm.Ticker)
+		}
+		// Converts underlying shares into benchmark-equivalent shares
+		weight := u.Beta * u.Price / benchPrice
+
+		u.Positions++
+		u.UnrealizedPnL += pm.UnrealizedPnL
+		risk.UnrealizedPnL += pm.UnrealizedPnL
+
+		for _, lm := range pm.Legs {
+			sign := 1.0
+			if lm.Action == "Sell" {
+				sign = -1
+			}
+			mult, bucket := float64(lm.Quantity)*100, "stock"
+			if lm.Type == "Stock" {
+				mult = float64(lm.Quantity)
+			} else {
+				bucket = expiryBucket(lm.Expiry, today)
+			}
+
+			e := Exposure{
+				Delta:     sign * lm.Delta * mult,
+				BetaDelta: sign * lm.Delta * mult * weight,
+				Gamma:     sign * lm.Gamma * mult,
+				Theta:     sign * lm.Theta * mult,
+				Vega:      sign * lm.Vega * mult,
+			}
+			u.add(e)
+
+			b, ok := buckets[bucket]
+			if !ok {
+				b = &Exposure{Name: bucket}
+				buckets[bucket] = b
+				bucketPositions[bucket] = map[int64]bool{}
+			}
+			b.add(e)
+			bucketPositions[bucket][pm.PositionID] = true
+		}
+	}
+
+	sort.Strings(tickers)
+	for _, t := range tickers {
+		u := underlyings[t]
+		u.DollarDelta = u.Delta * u.Price
+		risk.BetaDelta += u.BetaDelta
+		risk.DollarDelta += u.DollarDelta
+		risk.Gamma += u.Gamma
+		risk.Theta += u.Theta
+		risk.Vega += u.Vega
+
+		u.Exposure.round()
+		u.DollarDelta = round(u.DollarDelta, 100)
+		u.Beta = round(u.Beta, 100)
+		u.UnrealizedPnL = round(u.UnrealizedPnL, 100)
+		risk.ByUnderlying = append(risk.ByUnderlying, *u)
+	}
+
+	bucketNames := []string{"stock"}
+	for _, b := range ExpiryBuckets {
+		bucketNames = append(bucketNames, b.Name)
+	}
+	for _, name := range bucketNames {
+		if b, ok := buckets[name]; ok {
+			b.Positions = len(bucketPositions[name])
+			b.round()
+			risk.ByExpiry = append(risk.ByExpiry, *b)
+		}
+	}
+
+	risk.BetaDelta = round(risk.BetaDelta, 100)
+	risk.DollarDelta = round(risk.DollarDelta, 100)
+	risk.Gamma = round(risk.Gamma, 1000)
+	risk.Theta = round(risk.Theta, 100)
+	risk.Vega = round(risk.Vega, 100)
+	risk.UnrealizedPnL = round(risk.UnrealizedPnL, 100)
+	return risk, nil
+}
+
+// expiryBucket names the ExpiryBuckets range an expiry falls in
+func expiryBucket(expiry string, today time.Time) string {
+	t, err := time.Parse("2006-01-02", expiry)
+	if err != nil {
+		return ExpiryBuckets[0].Name
+	}
+	dte := int(t.Sub(today).Hours() / 24)
+	for _, b := range ExpiryBuckets {
+		if dte <= b.MaxDTE {
+			return b.Name
+		}
+	}
+	return ExpiryBuckets[len(ExpiryBuckets)-1].Name
+}
+
+func (e *Exposure) add(o Exposure) {
+	e.Delta += o.Delta
+	e.BetaDelta += o.BetaDelta
+	e.Gamma += o.Gamma
+	e.Theta += o.Theta
+	e.Vega += o.Vega
+}
+
+func (e *Exposure) round() {
+	e.Delta = round(e.Delta, 100)
+	e.BetaDelta = round(e.BetaDelta, 100)
+	e.Gamma = round(e.Gamma, 1000)
+	e.Theta = round(e.Theta, 100)
+	e.Vega = round(e.Vega, 100)
+}
@@ -0,0 +1,55 @@
+package portfolio
+
+import (
+	"math"
+	"strikelogic/storage"
+	"testing"
+	"time"
+)
+
+func TestExpiryBucket(t *testing.T) {
+	today := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		days int
+		want string
+	}{
+		{0, "0-7"},
+		{7, "0-7"},
+		{8, "8-30"},
+		{30, "8-30"},
+		{31, "31-60"},
+		{90, "61-90"},
+		{180, "91-180"},
+		{181, "181+"},
+		{1000, "181+"},
+	}
+	for _, tt := range tests {
+		if got := expiryBucket(today.AddDate(0, 0, tt.days).Format("2006-01-02"), today); got != tt.want {
+			t.Errorf("%d DTE in bucket %q, want %q", tt.days, got, tt.want)
+		}
+	}
+	if got := expiryBucket("soon", today); got != "0-7" {
+		t.Errorf("an unparseable expiry went to %q", got)
+	}
+}
+
+func TestPairedReturns(t *testing.T) {
+	day := func(d int, price float64) storage.UnderlyingQuote {
+		return storage.UnderlyingQuote{Price: price, CapturedAt: time.Date(2030, 1, d, 20, 0, 0, 0, time.UTC)}
+	}
+	bench := []storage.UnderlyingQuote{day(1, 100), day(2, 101), day(3, 102), day(4, 100), day(7, 103), day(8, 104)}
+	// The 4th is missing, which breaks the pair across it
+	other := []storage.UnderlyingQuote{day(1, 50), day(2, 51), day(3, 53), day(7, 52), day(8, 54)}
+
+	x, y := pairedReturns(bench, other)
+	if len(x) != 3 || len(y) != 3 {
+		t.Fatalf("%d and %d returns, want 3", len(x), len(y))
+	}
+	wantX := []float64{math.Log(101.0 / 100), math.Log(102.0 / 101), math.Log(104.0 / 103)}
+	wantY := []float64{math.Log(51.0 / 50), math.Log(53.0 / 51), math.Log(54.0 / 52)}
+	for i := range x {
+		if math.Abs(x[i]-wantX[i]) > 1e-12 || math.Abs(y[i]-wantY[i]) > 1e-12 {
+			t.Errorf("return %d = %v, %v, want %v, %v", i, x[i], y[i], wantX[i], wantY[i])
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package rates
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRate is the flat rate used until a curve is loaded
+const DefaultRate = 0.05
+
+// Point is one tenor on the curve. Rate is continuously compounded, as a decimal (0.05 = 5%).
+type Point struct {
+	Tenor float64 `json:"tenor"` // Years
+	Label string  `json:"label"` // Original tenor label, e.g. "3M"
+	Rate  float64 `json:"rate"`
+}
+
+// Curve is a risk-free term structure
+type Curve struct {
+	Points []Point   `json:"points"` // Sorted by tenor
+	AsOf   time.Time `json:"asOf"`
+	Source string    `json:"source"`
+}
+
+var (
+	curveMu sync.RWMutex
+	current = Flat(DefaultRate)
+	path    string
+)
+
+// Flat returns a curve with the same rate at every tenor
+func Flat(rate float64) Curve {
+	return Curve{Points: []Point{{Tenor: 1, Label: "1Y", Rate: rate}}, Source: "flat"}
+}
+
+// Rate interpolates linearly between tenors and extrapolates flat beyond the ends
+func (c Curve) Rate(T float64) float64 {
+	if len(c.Points) == 0 {
+		return DefaultRate
+	}
+	if T <= c.Points[0].Tenor {
+		return c.Points[0].Rate
+	}
+	last := c.Points[len(c.Points)-1]
+	if T >= last.Tenor {
+		return last.Rate
+	}
+
+	for i := 1; i < len(c.Points); i++ {
+		lo, hi := c.Points[i-1], c.Points[i]
+		if T <= hi.Tenor {
+			w := (T - lo.Tenor) / (hi.Tenor - lo.Tenor)
+			return lo.Rate + w*(hi.Rate-lo.Rate)
+		}
+	}
+	return last.Rate
+}
+
+// Current returns the active curve
+func Current() Curve {
+	curveMu.RLock()
+	defer curveMu.RUnlock()
+	return current
+}
+
+// SetCurve replaces the active curve
+func SetCurve(c Curve) {
+	curveMu.Lock()
+	defer curveMu.Unlock()
+	current = c
+}
+
+// RateFor returns the risk-free rate for a time to expiry in years
+func RateFor(T float64) float64 {
+	return Current().Rate(T)
+}
+
+// LoadFile reads a curve from a .csv or .json file, makes it active and remembers the path for Refresh
+func LoadFile(p string) error {
+	c, err := ReadFile(p)
+	if err != nil {
+		return err
+	}
+
+	curveMu.Lock()
+	current = c
+	path = p
+	curveMu.Unlock()
+
+	log.Printf("Loaded rate curve from %s (%d tenors)", p, len(c.Points))
+	return nil
+}
+
+// Refresh reloads the curve from the file last passed to LoadFile
+func Refresh() (Curve, error) {
+	curveMu.RLock()
+	p := path
+	curveMu.RUnlock()
+
+	if p == "" {
+		return Current(), fmt.Errorf("no rate curve file configured")
+	}
+	if err := LoadFile(p); err != nil {
+		return Current(), err
+	}
+	return Current(), nil
+}
+
+// ReadFile parses a curve file without activating it.
+//
+// CSV: a header row, then "tenor,rate" rows, e.g. "3M,5.28". An optional third "date" column sets AsOf.
+// JSON: {"asOf": "2024-05-01", "points": [{"tenor": "3M", "rate": 5.28}]}.
+// Rates are Treasury par yields in percent; they are converted to continuous compounding.
+func ReadFile(p string) (Curve, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return Curve{}, err
+	}
+	defer f.Close()
+
+	var c Curve
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".csv":
+		c, err = parseCSV(f)
+	case ".json":
+		c, err = parseJSON(f)
+	default:
+		return Curve{}, fmt.Errorf("unsupported rate curve format: %s", p)
+	}
+	if err != nil {
+		return Curve{}, fmt.Errorf("failed to parse %s: %v", p, err)
+	}
+	c.Source = p
+	return c, nil
+}
+
+func parseCSV(r io.Reader) (Curve, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return Curve{}, err
+	}
+
+	var c Curve
+	for i, rec := range records {
+		if i == 0 || len(rec) < 2 {
+			continue // header
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+		if err != nil {
+			return Curve{}, fmt.Errorf("row %d: invalid rate %q", i+1, rec[1])
+		}
+		point, err := newPoint(rec[0], rate)
+		if err != nil {
+			return Curve{}, fmt.Errorf("row %d: %v", i+1, err)
+		}
+		c.Points = append(c.Points, point)
+
+		if len(rec) > 2 && c.AsOf.IsZero() {
+			if t, err := time.Parse("2006-01-02", strings.TrimSpace(rec[2])); err == nil {
+				c.AsOf = t
+			}
+		}
+	}
+	return finish(c)
+}
+
+func parseJSON(r io.Reader) (Curve, error) {
+	var raw struct {
+		AsOf   string `json:"asOf"`
+		Points []struct {
+			Tenor string  `json:"tenor"`
+			Rate  float64 `json:"rate"`
+		} `json:"points"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return Curve{}, err
+	}
+
+	var c Curve
+	if raw.AsOf != "" {
+		t, err := time.Parse("2006-01-02", raw.AsOf)
+		if err != nil {
+			return Curve{}, fmt.Errorf("invalid asOf %q", raw.AsOf)
+		}
+		c.AsOf = t
+	}
+	for _, p := range raw.Points {
+		point, err := newPoint(p.Tenor, p.Rate)
+		if err != nil {
+			return Curve{}, err
+		}
+		c.Points = append(c.Points, point)
+	}
+	return finish(c)
+}
+
+// FromPercent builds a curve from tenor labels and par yields in percent, as ReadFile does
+func FromPercent(asOf time.Time, yields map[string]float64) (Curve, error) {
+	c := Curve{AsOf: asOf}
+	for label, rate := range yields {
+		point, err := newPoint(label, rate)
+		if err != nil {
+			return Curve{}, err
+		}
+		c.Points = append(c.Points, point)
+	}
+	return finish(c)
+}
+
+func newPoint(label string, percent float64) (Point, error) {
+	label = strings.ToUpper(strings.TrimSpace(label))
+	tenor, err := ParseTenor(label)
+	if err != nil {
+		return Point{}, err
+	}
+	// Treasury yields are quoted semi-annually compounded
+	y := percent / 100
+	return Point{Tenor: tenor, Label: label, Rate: 2 * math.Log(1+y/2)}, nil
+}
+
+func finish(c Curve) (Curve, error) {
+	if len(c.Points) == 0 {
+		return Curve{}, fmt.Errorf("curve has no points")
+	}
+	sort.Slice(c.Points, func(i, j int) bool { return c.Points[i].Tenor < c.Points[j].Tenor })
+	return c, nil
+}
+
+// ParseTenor converts labels like "1M", "13W", "6M", "2Y" (or a plain number of years) to years
+func ParseTenor(label string) (float64, error) {
+	label = strings.ToUpper(strings.TrimSpace(label))
+	if v, err := strconv.ParseFloat(label, 64); err == nil {
+		return v, nil
+	}
+	if len(label) < 2 {
+		return 0, fmt.Errorf("invalid tenor %q", label)
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(label[:len(label)-1]), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid tenor %q", label)
+	}
+	switch label[len(label)-1] {
+	case 'D':
+		return n / 365, nil
+	case 'W':
+		return n * 7 / 365, nil
+	case 'M':
+		return n / 12, nil
+	case 'Y':
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid tenor %q", label)
+}
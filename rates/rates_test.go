@@ -0,0 +1,128 @@
+package rates
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTenor(t *testing.T) {
+	tests := []struct {
+		label string
+		want  float64
+	}{
+		{"1M", 1.0 / 12},
+		{"13W", 13.0 * 7 / 365},
+		{"6m", 0.5},
+		{"2Y", 2},
+		{"30D", 30.0 / 365},
+		{"0.25", 0.25},
+		{" 10Y ", 10},
+	}
+	for _, tt := range tests {
+		got, err := ParseTenor(tt.label)
+		if err != nil {
+			t.Errorf("ParseTenor(%q): %v", tt.label, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("ParseTenor(%q) = %v, want %v", tt.label, got, tt.want)
+		}
+	}
+
+	for _, label := range []string{"", "M", "3X", "-1Y", "0M", "abcY"} {
+		if _, err := ParseTenor(label); err == nil {
+			t.Errorf("ParseTenor(%q): expected an error", label)
+		}
+	}
+}
+
+func TestPercentToContinuous(t *testing.T) {
+	c, err := FromPercent(time.Time{}, map[string]float64{"1Y": 5, "2Y": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A 5% semi-annual par yield is 2 ln(1.025) continuously compounded
+	if want := 2 * math.Log(1.025); math.Abs(c.Points[0].Rate-want) > 1e-12 {
+		t.Errorf("5%% converts to %v, want %v", c.Points[0].Rate, want)
+	}
+	if c.Points[1].Rate != 0 {
+		t.Errorf("0%% converts to %v", c.Points[1].Rate)
+	}
+}
+
+func TestCurveRate(t *testing.T) {
+	c := Curve{Points: []Point{{Tenor: 0.25, Rate: 0.04}, {Tenor: 1, Rate: 0.05}, {Tenor: 2, Rate: 0.03}}}
+	tests := []struct {
+		T, want float64
+	}{
+		{0, 0.04},      // Flat before the first tenor
+		{0.25, 0.04},   // On a tenor
+		{0.625, 0.045}, // Halfway between 3M and 1Y
+		{1.5, 0.04},    // Halfway between 1Y and 2Y
+		{2, 0.03},
+		{30, 0.03}, // Flat past the last tenor
+	}
+	for _, tt := range tests {
+		if got := c.Rate(tt.T); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("Rate(%v) = %v, want %v", tt.T, got, tt.want)
+		}
+	}
+
+	if got := (Curve{}).Rate(1); got != DefaultRate {
+		t.Errorf("empty curve rate %v, want DefaultRate", got)
+	}
+	if got := Flat(0.02).Rate(7); got != 0.02 {
+		t.Errorf("flat curve rate %v, want 0.02", got)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	asOf := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	files := map[string]string{
+		"csv":  write("rates.csv", "tenor,rate,date\n1Y,4.11,2025-06-02\n3M,4.35,2025-06-02\n"),
+		"json": write("rates.json", `{"asOf": "2025-06-02", "points": [{"tenor": "1Y", "rate": 4.11}, {"tenor": "3M", "rate": 4.35}]}`),
+	}
+	for format, p := range files {
+		c, err := ReadFile(p)
+		if err != nil {
+			t.Errorf("%s: %v", format, err)
+			continue
+		}
+		if !c.AsOf.Equal(asOf) || c.Source != p {
+			t.Errorf("%s: asOf %v source %q", format, c.AsOf, c.Source)
+		}
+		// Points are sorted by tenor whatever the file order
+		if len(c.Points) != 2 || c.Points[0].Label != "3M" || c.Points[1].Label != "1Y" {
+			t.Fatalf("%s: points %+v", format, c.Points)
+		}
+		if want := 2 * math.Log(1+0.0435/2); math.Abs(c.Points[0].Rate-want) > 1e-12 {
+			t.Errorf("%s: 3M rate %v, want %v", format, c.Points[0].Rate, want)
+		}
+	}
+
+	bad := map[string]string{
+		"bad rate":    write("bad.csv", "tenor,rate\n1Y,abc\n"),
+		"bad tenor":   write("tenor.csv", "tenor,rate\n1Q,4\n"),
+		"no points":   write("empty.csv", "tenor,rate\n"),
+		"bad asOf":    write("asof.json", `{"asOf": "June", "points": [{"tenor": "1Y", "rate": 4}]}`),
+		"unsupported": write("rates.txt", "1Y 4"),
+		"missing":     filepath.Join(dir, "missing.csv"),
+	}
+	for name, p := range bad {
+		if _, err := ReadFile(p); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
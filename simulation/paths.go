@@ -0,0 +1,111 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Dynamics describes how the underlying moves. The base process is GBM; jumps add Merton jump-diffusion
+// and StochasticVol switches the variance to a Heston process.
+type Dynamics struct {
+	Drift float64 `json:"drift"` // Annual drift; zero uses the risk-free rate less the dividend yield
+	Vol   float64 `json:"vol"`   // Annual volatility (initial volatility under StochasticVol); zero uses the legs' average IV
+
+	// Merton jumps: log jump sizes are normal with JumpMean and JumpStd, arriving JumpIntensity times a year
+	JumpIntensity float64 `json:"jumpIntensity"`
+	JumpMean      float64 `json:"jumpMean"`
+	JumpStd       float64 `json:"jumpStd"`
+
+	// Heston variance; zero parameters take the defaults below
+	StochasticVol bool    `json:"stochasticVol"`
+	MeanReversion float64 `json:"meanReversion"` // kappa
+	LongRunVol    float64 `json:"longRunVol"`    // sqrt(theta); defaults to Vol
+	VolOfVol      float64 `json:"volOfVol"`      // xi
+	Correlation   float64 `json:"correlation"`   // rho between price and variance shocks
+}
+
+// Heston defaults
+const (
+	DefaultMeanReversion = 2.0
+	DefaultVolOfVol      = 0.5
+	DefaultCorrelation   = -0.7
+)
+
+// withDefaults fills in the Heston defaults
+func (d Dynamics) withDefaults() Dynamics {
+	if !d.StochasticVol {
+		return d
+	}
+	if d.MeanReversion <= 0 {
+		d.MeanReversion = DefaultMeanReversion
+	}
+	if d.LongRunVol <= 0 {
+		d.LongRunVol = d.Vol
+	}
+	if d.VolOfVol <= 0 {
+		d.VolOfVol = DefaultVolOfVol
+	}
+	if d.Correlation == 0 {
+		d.Correlation = DefaultCorrelation
+	}
+	return d
+}
+
+// path is one simulated day-by-day trajectory: prices[i] and vols[i] at the end of day i (index 0 is today)
+type path struct {
+	prices []float64
+	vols   []float64
+}
+
+// simulatePath steps the process one day at a time. drops[i] is a cash dividend paid at the end of day i.
+func (d Dynamics) simulatePath(rng *rand.Rand, spot float64, days int, drops []float64) path {
+	const dt = 1.0 / 365
+
+	p := path{prices: make([]float64, days+1), vols: make([]float64, days+1)}
+	p.prices[0], p.vols[0] = spot, d.Vol
+
+	// Compensate the drift so jumps don't change the expected return
+	jumpComp := d.JumpIntensity * (math.Exp(d.JumpMean+0.5*d.JumpStd*d.JumpStd) - 1)
+
+	logS := math.Log(spot)
+	v := d.Vol * d.Vol
+	for i := 1; i <= days; i++ {
+		z1 := rng.NormFloat64()
+
+		variance := v
+		if d.StochasticVol {
+			// Full truncation Euler: negative variance is treated as zero
+			variance = math.Max(v, 0)
+			z2 := d.Correlation*z1 + math.Sqrt(1-d.Correlation*d.Correlation)*rng.NormFloat64()
+			theta := d.LongRunVol * d.LongRunVol
+			v += d.MeanReversion*(theta-variance)*dt + d.VolOfVol*math.Sqrt(variance*dt)*z2
+		}
+
+		logS += (d.Drift-jumpComp-0.5*variance)*dt + math.Sqrt(variance*dt)*z1
+
+		if d.JumpIntensity > 0 {
+			for n := poisson(rng, d.JumpIntensity*dt); n > 0; n-- {
+				logS += d.JumpMean + d.JumpStd*rng.NormFloat64()
+			}
+		}
+
+		if i < len(drops) && drops[i] > 0 {
+			logS = math.Log(math.Max(math.Exp(logS)-drops[i], 0.01*spot))
+		}
+
+		p.prices[i] = math.Exp(logS)
+		p.vols[i] = math.Sqrt(math.Max(v, 0))
+	}
+	return p
+}
+
+// poisson draws from a Poisson distribution with a small mean (Knuth's method)
+func poisson(rng *rand.Rand, mean float64) int {
+	limit := math.Exp(-mean)
+	n, prod := 0, rng.Float64()
+	for prod > limit {
+		n++
+		prod *= rng.Float64()
+	}
+	return n
+}
@@ -0,0 +1,347 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strikelogic/calculator"
+	"strikelogic/rates"
+	"strikelogic/strategies"
+	"time"
+)
+
+// Simulation limits
+const (
+	DefaultPaths = 2000
+	MaxPaths     = 50000
+	DefaultBins  = 40
+)
+
+// Rules are early-management rules, checked at the end of each day. Zero disables a rule.
+type Rules struct {
+	ProfitTarget float64 `json:"profitTarget"` // Close at this fraction of max profit, e.g. 0.5
+	CloseDTE     int     `json:"closeDTE"`     // Close when the front expiry is this many days away, e.g. 21
+	// Close when the loss reaches this multiple of the credit, e.g. 2, or of the debit. A debit spread can't lose
+	// more than it paid, so its stop must be a fraction, e.g. 0.5.
+	StopLoss float64 `json:"stopLoss"`
+}
+
+// Config controls a simulation run
+type Config struct {
+	Paths       int       `json:"paths"`       // Defaults to DefaultPaths
+	Seed        int64     `json:"seed"`        // Zero seeds from the clock
+	Bins        int       `json:"bins"`        // Histogram bins, defaults to DefaultBins
+	MaxProfit   float64   `json:"maxProfit"`   // Reference for Rules.ProfitTarget; zero uses the credit received, or the debit paid
+	TouchLevels []float64 `json:"touchLevels"` // Prices for probability of touch; defaults to the option strikes
+	Dynamics    Dynamics  `json:"dynamics"`
+	Rules       Rules     `json:"rules"`
+}
+
+// MaxProfitFor is the Config.MaxProfit for trade at spot: its max profit at the front expiry, or zero when
+// the profit is unlimited (e.g. a long call), so Rules.ProfitTarget falls back to the debit paid
+func MaxProfitFor(trade strategies.Trade, spot float64) float64 {
+	trade.CalculatePayoff(spot)
+	if trade.UnlimitedProfit {
+		return 0
+	}
+	return trade.MaxProfit
+}
+
+// Exit reasons
+const (
+	ExitExpiry       = "expiry"
+	ExitProfitTarget = "profitTarget"
+	ExitDTE          = "dte"
+	ExitStopLoss     = "stopLoss"
+)
+
+// Bin is one histogram bucket of final P&L
+type Bin struct {
+	From        float64 `json:"from"`
+	To          float64 `json:"to"`
+	Count       int     `json:"count"`
+	Probability float64 `json:"probability"` // Percent of paths
+}
+
+// Percentile is the P&L at or below which Percent of paths finish
+type Percentile struct {
+	Percent int     `json:"percent"`
+	PnL     float64 `json:"pnl"`
+}
+
+// Touch is the probability the underlying trades through Price before the front expiry
+type Touch struct {
+	Price       float64 `json:"price"`
+	Probability float64 `json:"probability"` // Percent of paths
+}
+
+// Result summarizes the P&L distribution across paths
+type Result struct {
+	Paths       int            `json:"paths"`
+	Days        int            `json:"days"` // Days to the front expiry
+	Mean        float64        `json:"mean"`
+	StdDev      float64        `json:"stdDev"`
+	PoP         float64        `json:"pop"` // Percent of paths closing with a profit
+	AvgDaysHeld float64        `json:"avgDaysHeld"`
+	Exits       map[string]int `json:"exits"` // Paths closed by each exit reason
+	Percentiles []Percentile   `json:"percentiles"`
+	Histogram   []Bin          `json:"histogram"`
+	Touch       []Touch        `json:"touch"`
+}
+
+// Run simulates the strategy from spot until its front expiry, applying cfg.Rules along each path.
+// Open legs are revalued with Black-Scholes at their IV, scaled by the path's volatility under StochasticVol.
+// Cash dividends are escrowed out of the option values and dropped from the paths on their ex-dates.
+func Run(strategy calculator.StrategyInput, spot float64, cfg Config) (Result, error) {
+	if spot <= 0 {
+		return Result{}, fmt.Errorf("current price required")
+	}
+	if len(strategy.Legs) == 0 {
+		return Result{}, fmt.Errorf("strategy has no legs")
+	}
+	if cfg.Paths <= 0 {
+		cfg.Paths = DefaultPaths
+	}
+	if cfg.Paths > MaxPaths {
+		return Result{}, fmt.Errorf("at most %d paths", MaxPaths)
+	}
+	if cfg.Bins <= 0 {
+		cfg.Bins = DefaultBins
+	}
+
+	now := time.Now()
+	var front time.Time
+	ivSum, ivCount := 0.0, 0
+	entry := 0.0 // Net debit in dollars; negative for a credit
+	for _, leg := range strategy.Legs {
+		sign := 1.0
+		if leg.Action == "Sell" {
+			sign = -1
+		}
+		if leg.IsStock {
+			entry += sign * leg.EntryPrice * leg.Quantity
+			continue
+		}
+		entry += sign * leg.EntryPrice * leg.Quantity * 100
+		if front.IsZero() || leg.Expiry.Before(front) {
+			front = leg.Expiry
+		}
+		if leg.IV > 0 {
+			ivSum += leg.IV
+			ivCount++
+		}
+	}
+	if front.IsZero() {
+		return Result{}, fmt.Errorf("strategy has no option legs")
+	}
+	days := int(math.Ceil(front.Sub(now).Hours() / 24))
+	if days < 1 {
+		days = 1
+	}
+
+	dyn := cfg.Dynamics
+	if dyn.Vol <= 0 {
+		if ivCount == 0 {
+			return Result{}, fmt.Errorf("volatility required")
+		}
+		dyn.Vol = ivSum / float64(ivCount)
+	}
+	// Cash dividends drop the paths on their ex-dates; a continuous yield comes out of the drift
+	q, cash := calculator.DividendInputs(strategy.DividendYield, strategy.Dividends, now)
+	drops := make([]float64, days+1)
+	for _, d := range cash {
+		if day := int(math.Ceil(d.T * 365)); day >= 1 && day <= days {
+			drops[day] += d.Amount
+		}
+	}
+	if dyn.Drift == 0 {
+		dyn.Drift = rates.RateFor(float64(days)/365) - q
+	}
+	dyn = dyn.withDefaults()
+
+	// Reference amounts for the rules. Without a max profit a debit trade targets a return on what it paid.
+	maxProfit := cfg.MaxProfit
+	if maxProfit <= 0 {
+		maxProfit = math.Abs(entry)
+	}
+	stopBasis := math.Abs(entry)
+
+	touchLevels := cfg.TouchLevels
+	if len(touchLevels) == 0 {
+		touchLevels = strikes(strategy)
+	}
+	touches := make([]int, len(touchLevels))
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = now.UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	managed := cfg.Rules.ProfitTarget > 0 || cfg.Rules.CloseDTE > 0 || cfg.Rules.StopLoss > 0
+	pnls := make([]float64, cfg.Paths)
+	exits := map[string]int{}
+	daysHeld := 0
+
+	for n := 0; n < cfg.Paths; n++ {
+		p := dyn.simulatePath(rng, spot, days, drops)
+
+		for i, level := range touchLevels {
+			if touched(p.prices, level) {
+				touches[i]++
+			}
+		}
+
+		exit, day := ExitExpiry, days
+		if managed {
+			for d := 1; d < days; d++ {
+				date := now.AddDate(0, 0, d)
+				pnl := value(strategy, p.prices[d], p.vols[d]/dyn.Vol, date) - entry
+				dte := days - d
+
+				if cfg.Rules.ProfitTarget > 0 && maxProfit > 0 && pnl >= cfg.Rules.ProfitTarget*maxProfit {
+					exit, day = ExitProfitTarget, d
+				} else if cfg.Rules.StopLoss > 0 && stopBasis > 0 && -pnl >= cfg.Rules.StopLoss*stopBasis {
+					exit, day = ExitStopLoss, d
+				} else if cfg.Rules.CloseDTE > 0 && dte <= cfg.Rules.CloseDTE {
+					exit, day = ExitDTE, d
+				}
+				if exit != ExitExpiry {
+					break
+				}
+			}
+		}
+
+		date := now.AddDate(0, 0, day)
+		if exit == ExitExpiry {
+			date = front
+		}
+		pnls[n] = value(strategy, p.prices[day], p.vols[day]/dyn.Vol, date) - entry
+		exits[exit]++
+		daysHeld += day
+	}
+
+	res := summarize(pnls, cfg.Bins)
+	res.Days = days
+	res.Exits = exits
+	res.AvgDaysHeld = math.Round(float64(daysHeld)/float64(cfg.Paths)*10) / 10
+	for i, level := range touchLevels {
+		res.Touch = append(res.Touch, Touch{Price: level, Probability: math.Round(float64(touches[i])/float64(cfg.Paths)*1000) / 10})
+	}
+	return res, nil
+}
+
+// value is the dollar value of the strategy's legs on date with the underlying at price.
+// volScale multiplies each leg's IV (1 without stochastic vol). Dividends are measured from date.
+func value(strategy calculator.StrategyInput, price, volScale float64, date time.Time) float64 {
+	q, dividends := calculator.DividendInputs(strategy.DividendYield, strategy.Dividends, date)
+	total := 0.0
+	for _, leg := range strategy.Legs {
+		sign := 1.0
+		if leg.Action == "Sell" {
+			sign = -1
+		}
+		if leg.IsStock {
+			total += sign * price * leg.Quantity
+			continue
+		}
+
+		var v float64
+		T := leg.Expiry.Sub(date).Hours() / 24 / 365
+		if T <= 0 || leg.IV <= 0 {
+			if leg.Type == calculator.Call {
+				v = math.Max(0, price-leg.Strike)
+			} else {
+				v = math.Max(0, leg.Strike-price)
+			}
+		} else {
+			in := calculator.PricingInput{Type: leg.Type, S: price, K: leg.Strike, T: T, R: rates.RateFor(T), Sigma: leg.IV * volScale, Q: q, Dividends: dividends}
+			v = calculator.BlackScholesModel{}.Price(in).Price
+		}
+		total += sign * v * leg.Quantity * 100
+	}
+	return total
+}
+
+// strikes returns the distinct option strikes, in order
+func strikes(strategy calculator.StrategyInput) []float64 {
+	seen := map[float64]bool{}
+	var out []float64
+	for _, leg := range strategy.Legs {
+		if !leg.IsStock && !seen[leg.Strike] {
+			seen[leg.Strike] = true
+			out = append(out, leg.Strike)
+		}
+	}
+	sort.Float64s(out)
+	return out
+}
+
+// touched reports whether the path reaches level (checked at daily closes)
+func touched(prices []float64, level float64) bool {
+	start := prices[0]
+	for _, p := range prices {
+		if (level >= start && p >= level) || (level < start && p <= level) {
+			return true
+		}
+	}
+	return false
+}
+
+// summarize computes the moments, percentiles and histogram of the path P&Ls
+func summarize(pnls []float64, bins int) Result {
+	n := float64(len(pnls))
+	sorted := append([]float64(nil), pnls...)
+	sort.Float64s(sorted)
+
+	mean, wins := 0.0, 0
+	for _, v := range pnls {
+		mean += v
+		if v > 0 {
+			wins++
+		}
+	}
+	mean /= n
+	variance := 0.0
+	for _, v := range pnls {
+		variance += (v - mean) * (v - mean)
+	}
+
+	res := Result{
+		Paths:  len(pnls),
+		Mean:   math.Round(mean*100) / 100,
+		StdDev: math.Round(math.Sqrt(variance/n)*100) / 100,
+		PoP:    math.Round(float64(wins)/n*1000) / 10,
+	}
+
+	for _, pct := range []int{5, 10, 25, 50, 75, 90, 95} {
+		idx := int(math.Round(float64(pct) / 100 * (n - 1)))
+		res.Percentiles = append(res.Percentiles, Percentile{Percent: pct, PnL: math.Round(sorted[idx]*100) / 100})
+	}
+
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if hi == lo {
+		res.Histogram = []Bin{{From: lo, To: hi, Count: len(pnls), Probability: 100}}
+		return res
+	}
+	width := (hi - lo) / float64(bins)
+	counts := make([]int, bins)
+	for _, v := range pnls {
+		i := int((v - lo) / width)
+		if i >= bins {
+			i = bins - 1
+		}
+		counts[i]++
+	}
+	for i, c := range counts {
+		from := lo + float64(i)*width
+		res.Histogram = append(res.Histogram, Bin{
+			From:        math.Round(from*100) / 100,
+			To:          math.Round((from+width)*100) / 100,
+			Count:       c,
+			Probability: math.Round(float64(c)/n*1000) / 10,
+		})
+	}
+	return res
+}
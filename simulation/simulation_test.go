@@ -0,0 +1,132 @@
+package simulation
+
+import (
+	"strikelogic/calculator"
+	"strikelogic/strategies"
+	"testing"
+	"time"
+)
+
+// callLeg is a 30 day call quoted at price with no spread
+func callLeg(action strategies.Action, strike, price float64) strategies.TradeLeg {
+	return strategies.TradeLeg{Action: action, Quantity: 1, Option: calculator.OptionContract{
+		Strike: strike, Type: calculator.Call, Expiry: time.Now().AddDate(0, 0, 30).Format("2006-01-02"),
+		Bid: price, Ask: price, Last: price, Vol: 0.3,
+	}}
+}
+
+func TestMaxProfitFor(t *testing.T) {
+	spread := strategies.Trade{Legs: []strategies.TradeLeg{callLeg(strategies.Buy, 100, 4), callLeg(strategies.Sell, 110, 1)}}
+	if got := MaxProfitFor(spread, 100); got < 699 || got > 701 {
+		t.Errorf("bull call spread max profit %v, want 700", got)
+	}
+	if spread.MaxProfit != 0 {
+		t.Error("MaxProfitFor should not modify the caller's trade")
+	}
+
+	// A long call's profit has no cap, so the profit target falls back to the debit
+	long := strategies.Trade{Legs: []strategies.TradeLeg{callLeg(strategies.Buy, 100, 4)}}
+	if got := MaxProfitFor(long, 100); got != 0 {
+		t.Errorf("long call max profit %v, want 0 (unlimited)", got)
+	}
+}
+
+// shortCall sells one 30 day 105 call for 2
+func shortCall() calculator.StrategyInput {
+	return calculator.StrategyInput{Legs: []calculator.LegInput{{
+		Strike: 105, Type: calculator.Call, Action: "Sell", Quantity: 1, Expiry: time.Now().AddDate(0, 0, 30), IV: 0.3, EntryPrice: 2,
+	}}}
+}
+
+func TestSummarize(t *testing.T) {
+	pnls := []float64{300, -100, 50, 0, 350, 100, -50, 200, 150, 250}
+	res := summarize(pnls, 4)
+
+	if res.Paths != 10 || res.Mean != 125 || res.PoP != 70 {
+		t.Errorf("paths %d mean %v PoP %v, want 10, 125 and 70", res.Paths, res.Mean, res.PoP)
+	}
+	if res.StdDev != 143.61 { // sqrt(20625)
+		t.Errorf("std dev %v, want 143.61", res.StdDev)
+	}
+	want := map[int]float64{5: -100, 25: 0, 50: 150, 95: 350}
+	for _, p := range res.Percentiles {
+		if w, ok := want[p.Percent]; ok && p.PnL != w {
+			t.Errorf("%dth percentile %v, want %v", p.Percent, p.PnL, w)
+		}
+	}
+
+	if len(res.Histogram) != 4 || res.Histogram[0].From != -100 || res.Histogram[3].To != 350 {
+		t.Fatalf("histogram %+v", res.Histogram)
+	}
+	count := 0
+	for _, b := range res.Histogram {
+		count += b.Count
+	}
+	if count != 10 || res.Histogram[3].Count != 3 { // 250, 300 and 350
+		t.Errorf("histogram counts %+v", res.Histogram)
+	}
+
+	flat := summarize([]float64{-200, -200}, 4)
+	if len(flat.Histogram) != 1 || flat.Histogram[0].Probability != 100 || flat.PoP != 0 {
+		t.Errorf("constant P&L summarized to %+v", flat)
+	}
+}
+
+func TestTouched(t *testing.T) {
+	prices := []float64{100, 102, 98, 105}
+	for level, want := range map[float64]bool{105: true, 106: false, 98: true, 97.9: false, 100: true} {
+		if got := touched(prices, level); got != want {
+			t.Errorf("touched(%v) = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestRunRules(t *testing.T) {
+	run := func(rules Rules) Result {
+		t.Helper()
+		res, err := Run(shortCall(), 100, Config{Paths: 500, Seed: 7, Rules: rules})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	held := run(Rules{})
+	if held.Exits[ExitExpiry] != 500 || held.AvgDaysHeld != float64(held.Days) {
+		t.Errorf("unmanaged exits %v held %v days, want all at expiry after %d", held.Exits, held.AvgDaysHeld, held.Days)
+	}
+	if again := run(Rules{}); again.Mean != held.Mean || again.StdDev != held.StdDev {
+		t.Errorf("the same seed gave means %v and %v", held.Mean, again.Mean)
+	}
+
+	// Every path reaches 21 DTE before anything else can happen
+	dte := run(Rules{CloseDTE: 21})
+	if dte.Exits[ExitDTE] != 500 || dte.AvgDaysHeld != float64(dte.Days-21) {
+		t.Errorf("close DTE exits %v held %v days, want all after %d", dte.Exits, dte.AvgDaysHeld, dte.Days-21)
+	}
+
+	// Taking half the credit early lowers the hold and narrows the spread of outcomes
+	managed := run(Rules{ProfitTarget: 0.5, StopLoss: 2})
+	if managed.Exits[ExitProfitTarget] == 0 || managed.Exits[ExitStopLoss] == 0 {
+		t.Errorf("managed exits %v, want profit targets and stops", managed.Exits)
+	}
+	if managed.AvgDaysHeld >= held.AvgDaysHeld || managed.StdDev >= held.StdDev {
+		t.Errorf("managed held %v days with std dev %v, unmanaged %v and %v", managed.AvgDaysHeld, managed.StdDev, held.AvgDaysHeld, held.StdDev)
+	}
+	// The worst outcome is capped near the stop: a loss of twice the 200 credit, give or take a day's move
+	if worst := managed.Percentiles[0].PnL; worst < -700 {
+		t.Errorf("5th percentile %v with a 400 stop", worst)
+	}
+}
+
+func TestRunRejectsBadInput(t *testing.T) {
+	if _, err := Run(shortCall(), 0, Config{}); err == nil {
+		t.Error("zero spot: expected an error")
+	}
+	if _, err := Run(calculator.StrategyInput{}, 100, Config{}); err == nil {
+		t.Error("no legs: expected an error")
+	}
+	if _, err := Run(shortCall(), 100, Config{Paths: MaxPaths + 1}); err == nil {
+		t.Error("too many paths: expected an error")
+	}
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"time"
+)
+
+// UnderlyingQuote is a point-in-time price of an underlying
+type UnderlyingQuote struct {
+	Ticker     string    `json:"ticker"`
+	Price      float64   `json:"price"`
+	CapturedAt time.Time `json:"capturedAt"`
+}
+
+// OptionQuote is a point-in-time snapshot of a single option contract.
+// JSON field names mirror calculator.OptionContract so rows can be decoded straight into it.
+type OptionQuote struct {
+	Ticker     string    `json:"underlying"`
+	Expiry     string    `json:"expiry"` // 2006-01-02
+	Type       string    `json:"type"`   // "Call" or "Put"
+	Strike     float64   `json:"strike"`
+	Bid        float64   `json:"bid"`
+	Ask        float64   `json:"ask"`
+	Last       float64   `json:"last"`
+	IV         float64   `json:"vol"`
+	Delta      float64   `json:"delta"`
+	Gamma      float64   `json:"gamma"`
+	Theta      float64   `json:"theta"`
+	Vega       float64   `json:"vega"`
+	CapturedAt time.Time `json:"capturedAt"`
+}
+
+func migrateChainHistory() error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS underlying_quotes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ticker TEXT NOT NULL,
+			price REAL NOT NULL,
+			captured_at DATETIME NOT NULL,
+			UNIQUE(ticker, captured_at)
+		);`,
+		`CREATE TABLE IF NOT EXISTS option_quotes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ticker TEXT NOT NULL,
+			expiry TEXT NOT NULL,
+			type TEXT NOT NULL,
+			strike REAL NOT NULL,
+			bid REAL DEFAULT 0,
+			ask REAL DEFAULT 0,
+			last REAL DEFAULT 0,
+			iv REAL DEFAULT 0,
+			delta REAL DEFAULT 0,
+			gamma REAL DEFAULT 0,
+			theta REAL DEFAULT 0,
+			vega REAL DEFAULT 0,
+			captured_at DATETIME NOT NULL,
+			UNIQUE(ticker, expiry, type, strike, captured_at)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_underlying_quotes_lookup ON underlying_quotes (ticker, captured_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_option_quotes_lookup ON option_quotes (ticker, expiry, captured_at);`,
+		`INSERT INTO schema_version (version) VALUES (2)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// normalizeTime stores all timestamps as whole-second UTC so DATETIME strings compare correctly
+func normalizeTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+// SaveChainSnapshot stores the underlying price and all contracts captured at the same moment
+func SaveChainSnapshot(quote UnderlyingQuote, contracts []OptionQuote) error {
+	capturedAt := normalizeTime(quote.CapturedAt)
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT OR IGNORE INTO underlying_quotes (ticker, price, captured_at) VALUES (?, ?, ?)`,
+		quote.Ticker, quote.Price, capturedAt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO option_quotes (ticker, expiry, type, strike, bid, ask, last, iv, delta, gamma, theta, vega, captured_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range contracts {
+		_, err = stmt.Exec(quote.Ticker, c.Expiry, c.Type, c.Strike, c.Bid, c.Ask, c.Last, c.IV, c.Delta, c.Gamma, c.Theta, c.Vega, capturedAt)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SaveQuote stores an underlying price captured without a chain, e.g. by the portfolio marks
+func SaveQuote(quote UnderlyingQuote) error {
+	_, err := DB.Exec(`INSERT OR IGNORE INTO underlying_quotes (ticker, price, captured_at) VALUES (?, ?, ?)`,
+		quote.Ticker, quote.Price, normalizeTime(quote.CapturedAt))
+	return err
+}
+
+// GetQuoteAsOf returns the latest underlying price captured at or before asOf
+func GetQuoteAsOf(ticker string, asOf time.Time) (UnderlyingQuote, error) {
+	q := UnderlyingQuote{Ticker: ticker}
+	err := DB.QueryRow(`SELECT price, captured_at FROM underlying_quotes WHERE ticker = ? AND captured_at <= ? ORDER BY captured_at DESC LIMIT 1`,
+		ticker, normalizeTime(asOf)).Scan(&q.Price, &q.CapturedAt)
+	return q, err
+}
+
+// GetChainAsOf reconstructs the chain as it looked at asOf: for every expiry that had not yet
+// expired, the most recent snapshot captured at or before asOf.
+func GetChainAsOf(ticker string, asOf time.Time) ([]OptionQuote, error) {
+	asOf = normalizeTime(asOf)
+
+	querySQL := `SELECT o.ticker, o.expiry, o.type, o.strike, o.bid, o.ask, o.last, o.iv, o.delta, o.gamma, o.theta, o.vega, o.captured_at
+		FROM option_quotes o
+		WHERE o.ticker = ? AND o.expiry >= ?
+		AND o.captured_at = (
+			SELECT MAX(captured_at) FROM option_quotes
+			WHERE ticker = o.ticker AND expiry = o.expiry AND captured_at <= ?
+		)
+		ORDER BY o.expiry, o.type, o.strike`
+
+	rows, err := DB.Query(querySQL, ticker, asOf.Format("2006-01-02"), asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chain []OptionQuote
+	for rows.Next() {
+		var c OptionQuote
+		err = rows.Scan(&c.Ticker, &c.Expiry, &c.Type, &c.Strike, &c.Bid, &c.Ask, &c.Last, &c.IV, &c.Delta, &c.Gamma, &c.Theta, &c.Vega, &c.CapturedAt)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, c)
+	}
+	return chain, rows.Err()
+}
+
+// GetSnapshotTimes lists the times a ticker's chain was captured within [from, to]. Quote-only captures
+// (see SaveQuote) are not snapshots.
+func GetSnapshotTimes(ticker string, from, to time.Time) ([]time.Time, error) {
+	rows, err := DB.Query(`SELECT DISTINCT captured_at FROM option_quotes WHERE ticker = ? AND captured_at >= ? AND captured_at <= ? ORDER BY captured_at`,
+		ticker, normalizeTime(from), normalizeTime(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var times []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, rows.Err()
+}
+
+// GetDailyCloses returns the last captured price of each UTC day within [from, to], oldest first
+func GetDailyCloses(ticker string, from, to time.Time) ([]UnderlyingQuote, error) {
+	rows, err := DB.Query(`SELECT price, captured_at FROM underlying_quotes WHERE ticker = ? AND captured_at >= ? AND captured_at <= ? ORDER BY captured_at`,
+		ticker, normalizeTime(from), normalizeTime(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var closes []UnderlyingQuote
+	for rows.Next() {
+		q := UnderlyingQuote{Ticker: ticker}
+		if err := rows.Scan(&q.Price, &q.CapturedAt); err != nil {
+			return nil, err
+		}
+		if n := len(closes); n > 0 && closes[n-1].CapturedAt.UTC().Format("2006-01-02") == q.CapturedAt.UTC().Format("2006-01-02") {
+			closes[n-1] = q
+			continue
+		}
+		closes = append(closes, q)
+	}
+	return closes, rows.Err()
+}
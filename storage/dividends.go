@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Dividend is a scheduled cash dividend for a ticker
+type Dividend struct {
+	Ticker string    `json:"ticker"`
+	ExDate time.Time `json:"exDate"`
+	Amount float64   `json:"amount"`
+}
+
+func migrateDividends() error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS dividend_yields (
+			ticker TEXT PRIMARY KEY,
+			yield REAL NOT NULL,
+			updated_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS dividends (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ticker TEXT NOT NULL,
+			ex_date TEXT NOT NULL,
+			amount REAL NOT NULL,
+			UNIQUE(ticker, ex_date)
+		);`,
+		`INSERT INTO schema_version (version) VALUES (3)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetDividendYield stores the continuous annual dividend yield for a ticker (0.013 = 1.3%)
+func SetDividendYield(ticker string, yield float64) error {
+	_, err := DB.Exec(`INSERT INTO dividend_yields (ticker, yield, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(ticker) DO UPDATE SET yield = excluded.yield, updated_at = excluded.updated_at`,
+		ticker, yield, normalizeTime(time.Now()))
+	return err
+}
+
+// GetDividendYield returns the stored yield for a ticker, or 0 if none is stored
+func GetDividendYield(ticker string) (float64, error) {
+	var yield float64
+	err := DB.QueryRow(`SELECT yield FROM dividend_yields WHERE ticker = ?`, ticker).Scan(&yield)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return yield, err
+}
+
+// SaveDividend inserts or updates a cash dividend, keyed by ticker and ex-date
+func SaveDividend(d Dividend) error {
+	_, err := DB.Exec(`INSERT INTO dividends (ticker, ex_date, amount) VALUES (?, ?, ?)
+		ON CONFLICT(ticker, ex_date) DO UPDATE SET amount = excluded.amount`,
+		d.Ticker, d.ExDate.Format("2006-01-02"), d.Amount)
+	return err
+}
+
+// GetDividends returns the cash dividends for a ticker with an ex-date on or after from
+func GetDividends(ticker string, from time.Time) ([]Dividend, error) {
+	rows, err := DB.Query(`SELECT ticker, ex_date, amount FROM dividends WHERE ticker = ? AND ex_date >= ? ORDER BY ex_date`,
+		ticker, from.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dividends []Dividend
+	for rows.Next() {
+		var d Dividend
+		var exDate string
+		if err := rows.Scan(&d.Ticker, &exDate, &d.Amount); err != nil {
+			return nil, err
+		}
+		d.ExDate, err = time.Parse("2006-01-02", exDate)
+		if err != nil {
+			return nil, err
+		}
+		dividends = append(dividends, d)
+	}
+	return dividends, rows.Err()
+}
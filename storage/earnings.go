@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Earnings report timings
+const (
+	BeforeOpen = "bmo"
+	AfterClose = "amc"
+)
+
+// EarningsEvent is a scheduled or past earnings report
+type EarningsEvent struct {
+	Ticker string    `json:"ticker"`
+	Date   time.Time `json:"date"`
+	Timing string    `json:"timing"`         // bmo, amc or empty when unknown
+	Move   *float64  `json:"move,omitempty"` // Realized move in percent, for past reports when the source provides it
+}
+
+func migrateEarnings() error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS earnings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ticker TEXT NOT NULL,
+			report_date TEXT NOT NULL,
+			timing TEXT DEFAULT '',
+			move REAL,
+			updated_at DATETIME NOT NULL,
+			UNIQUE(ticker, report_date)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_earnings_date ON earnings (report_date);`,
+		`INSERT INTO schema_version (version) VALUES (5)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveEarnings inserts or updates an earnings report, keyed by ticker and date.
+// A missing Move keeps any move already stored.
+func SaveEarnings(e EarningsEvent) error {
+	var move sql.NullFloat64
+	if e.Move != nil {
+		move = sql.NullFloat64{Float64: *e.Move, Valid: true}
+	}
+	_, err := DB.Exec(`INSERT INTO earnings (ticker, report_date, timing, move, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(ticker, report_date) DO UPDATE SET timing = excluded.timing, move = COALESCE(excluded.move, earnings.move), updated_at = excluded.updated_at`,
+		e.Ticker, e.Date.Format("2006-01-02"), e.Timing, move, normalizeTime(time.Now()))
+	return err
+}
+
+// GetEarnings returns reports dated within [from, to], by date. An empty ticker matches every ticker.
+func GetEarnings(ticker string, from, to time.Time) ([]EarningsEvent, error) {
+	query := `SELECT ticker, report_date, COALESCE(timing, ''), move FROM earnings WHERE report_date >= ? AND report_date <= ?`
+	args := []interface{}{from.Format("2006-01-02"), to.Format("2006-01-02")}
+	if ticker != "" {
+		query += ` AND ticker = ?`
+		args = append(args, ticker)
+	}
+	query += ` ORDER BY report_date, ticker`
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []EarningsEvent{}
+	for rows.Next() {
+		var e EarningsEvent
+		var date string
+		var move sql.NullFloat64
+		if err := rows.Scan(&e.Ticker, &date, &e.Timing, &move); err != nil {
+			return nil, err
+		}
+		if e.Date, err = time.Parse("2006-01-02", date); err != nil {
+			return nil, err
+		}
+		if move.Valid {
+			e.Move = &move.Float64
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,343 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Account groups positions, e.g. one per brokerage account
+type Account struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Position statuses
+const (
+	PositionOpen   = "open"
+	PositionClosed = "closed"
+)
+
+// Position is a saved multi-leg trade
+type Position struct {
+	ID        int64         `json:"id"`
+	AccountID int64         `json:"accountId"`
+	Ticker    string        `json:"ticker"`
+	Name      string        `json:"name"` // Strategy name, e.g. "Iron Condor"
+	Notes     string        `json:"notes"`
+	Status    string        `json:"status"` // open or closed
+	OpenedAt  time.Time     `json:"openedAt"`
+	ClosedAt  *time.Time    `json:"closedAt,omitempty"`
+	Legs      []PositionLeg `json:"legs"`
+}
+
+// PositionLeg is one filled leg of a position. Type is "Call", "Put" or "Stock"; Expiry and Strike are empty for stock.
+type PositionLeg struct {
+	ID         int64      `json:"id"`
+	PositionID int64      `json:"positionId"`
+	Type       string     `json:"type"`
+	Action     string     `json:"action"` // Buy or Sell
+	Strike     float64    `json:"strike"`
+	Expiry     string     `json:"expiry"` // 2006-01-02
+	Quantity   int        `json:"quantity"`
+	FillPrice  float64    `json:"fillPrice"` // Per share
+	FillTime   time.Time  `json:"fillTime"`
+	ClosePrice *float64   `json:"closePrice,omitempty"`
+	Mark       float64    `json:"mark"` // Last mark-to-market price per share
+	MarkedAt   *time.Time `json:"markedAt,omitempty"`
+}
+
+func migratePortfolio() error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS accounts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			created_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS positions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			account_id INTEGER NOT NULL REFERENCES accounts(id),
+			ticker TEXT NOT NULL,
+			name TEXT NOT NULL,
+			notes TEXT DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'open',
+			opened_at DATETIME NOT NULL,
+			closed_at DATETIME
+		);`,
+		`CREATE TABLE IF NOT EXISTS position_legs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			position_id INTEGER NOT NULL REFERENCES positions(id),
+			type TEXT NOT NULL,
+			action TEXT NOT NULL,
+			strike REAL DEFAULT 0,
+			expiry TEXT DEFAULT '',
+			quantity INTEGER NOT NULL,
+			fill_price REAL NOT NULL,
+			fill_time DATETIME NOT NULL,
+			close_price REAL,
+			mark REAL DEFAULT 0,
+			marked_at DATETIME
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_positions_account ON positions (account_id, status);`,
+		`CREATE INDEX IF NOT EXISTS idx_position_legs_position ON position_legs (position_id);`,
+		`INSERT INTO schema_version (version) VALUES (4)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CreateAccount stores a new account
+func CreateAccount(name string) (Account, error) {
+	acct := Account{Name: name, CreatedAt: normalizeTime(time.Now())}
+	res, err := DB.Exec(`INSERT INTO accounts (name, created_at) VALUES (?, ?)`, acct.Name, acct.CreatedAt)
+	if err != nil {
+		return Account{}, err
+	}
+	acct.ID, err = res.LastInsertId()
+	return acct, err
+}
+
+// GetAccounts returns every account
+func GetAccounts() ([]Account, error) {
+	rows, err := DB.Query(`SELECT id, name, created_at FROM accounts ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []Account{}
+	for rows.Next() {
+		var a Account
+		if err := rows.Scan(&a.ID, &a.Name, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// DeleteAccount removes an account that has no positions
+func DeleteAccount(id int64) error {
+	var n int
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM positions WHERE account_id = ?`, id).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("account %d still has %d positions", id, n)
+	}
+	return execOne(`DELETE FROM accounts WHERE id = ?`, id)
+}
+
+// CreatePosition stores a position and its legs, returning it with IDs filled in
+func CreatePosition(p Position) (Position, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return Position{}, err
+	}
+
+	p.Status = PositionOpen
+	p.OpenedAt = normalizeTime(p.OpenedAt)
+	res, err := tx.Exec(`INSERT INTO positions (account_id, ticker, name, notes, status, opened_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		p.AccountID, p.Ticker, p.Name, p.Notes, p.Status, p.OpenedAt)
+	if err != nil {
+		tx.Rollback()
+		return Position{}, err
+	}
+	if p.ID, err = res.LastInsertId(); err != nil {
+		tx.Rollback()
+		return Position{}, err
+	}
+
+	for i := range p.Legs {
+		leg := &p.Legs[i]
+		leg.PositionID = p.ID
+		leg.FillTime = normalizeTime(leg.FillTime)
+		res, err := tx.Exec(`INSERT INTO position_legs (position_id, type, action, strike, expiry, quantity, fill_price, fill_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			leg.PositionID, leg.Type, leg.Action, leg.Strike, leg.Expiry, leg.Quantity, leg.FillPrice, leg.FillTime)
+		if err != nil {
+			tx.Rollback()
+			return Position{}, err
+		}
+		if leg.ID, err = res.LastInsertId(); err != nil {
+			tx.Rollback()
+			return Position{}, err
+		}
+	}
+	return p, tx.Commit()
+}
+
+// GetPositions returns positions with their legs. Zero accountID and an empty status match everything.
+func GetPositions(accountID int64, status string) ([]Position, error) {
+	query := `SELECT id, account_id, ticker, name, COALESCE(notes, ''), status, opened_at, closed_at FROM positions WHERE 1 = 1`
+	var args []interface{}
+	if accountID != 0 {
+		query += ` AND account_id = ?`
+		args = append(args, accountID)
+	}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	positions := []Position{}
+	for rows.Next() {
+		var p Position
+		var closedAt sql.NullTime
+		if err := rows.Scan(&p.ID, &p.AccountID, &p.Ticker, &p.Name, &p.Notes, &p.Status, &p.OpenedAt, &closedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if closedAt.Valid {
+			p.ClosedAt = &closedAt.Time
+		}
+		positions = append(positions, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range positions {
+		legs, err := getPositionLegs(positions[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		positions[i].Legs = legs
+	}
+	return positions, nil
+}
+
+// GetPosition returns one position with its legs
+func GetPosition(id int64) (Position, error) {
+	var p Position
+	var closedAt sql.NullTime
+	err := DB.QueryRow(`SELECT id, account_id, ticker, name, COALESCE(notes, ''), status, opened_at, closed_at FROM positions WHERE id = ?`, id).
+		Scan(&p.ID, &p.AccountID, &p.Ticker, &p.Name, &p.Notes, &p.Status, &p.OpenedAt, &closedAt)
+	if err != nil {
+		return Position{}, err
+	}
+	if closedAt.Valid {
+		p.ClosedAt = &closedAt.Time
+	}
+	p.Legs, err = getPositionLegs(id)
+	return p, err
+}
+
+func getPositionLegs(positionID int64) ([]PositionLeg, error) {
+	rows, err := DB.Query(`SELECT id, position_id, type, action, strike, expiry, quantity, fill_price, fill_time, close_price, mark, marked_at
+		FROM position_legs WHERE position_id = ? ORDER BY id`, positionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	legs := []PositionLeg{}
+	for rows.Next() {
+		var l PositionLeg
+		var closePrice sql.NullFloat64
+		var markedAt sql.NullTime
+		if err := rows.Scan(&l.ID, &l.PositionID, &l.Type, &l.Action, &l.Strike, &l.Expiry, &l.Quantity, &l.FillPrice, &l.FillTime, &closePrice, &l.Mark, &markedAt); err != nil {
+			return nil, err
+		}
+		if closePrice.Valid {
+			l.ClosePrice = &closePrice.Float64
+		}
+		if markedAt.Valid {
+			l.MarkedAt = &markedAt.Time
+		}
+		legs = append(legs, l)
+	}
+	return legs, rows.Err()
+}
+
+// UpdatePosition saves a position's name and notes
+func UpdatePosition(id int64, name, notes string) error {
+	return execOne(`UPDATE positions SET name = ?, notes = ? WHERE id = ?`, name, notes, id)
+}
+
+// ClosePosition marks a position closed, recording each leg's exit price by leg ID.
+// Legs without a price close at their last mark, or at their fill price if they were never marked.
+func ClosePosition(id int64, closePrices map[int64]float64, closedAt time.Time) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec(`UPDATE positions SET status = ?, closed_at = ? WHERE id = ? AND status = ?`, PositionClosed, normalizeTime(closedAt), id, PositionOpen)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return fmt.Errorf("no open position %d", id)
+	}
+
+	if _, err := tx.Exec(`UPDATE position_legs SET close_price = CASE WHEN marked_at IS NULL THEN fill_price ELSE mark END WHERE position_id = ?`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for legID, price := range closePrices {
+		if _, err := tx.Exec(`UPDATE position_legs SET close_price = ? WHERE id = ? AND position_id = ?`, price, legID, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeletePosition removes a position and its legs
+func DeletePosition(id int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM position_legs WHERE position_id = ?`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	res, err := tx.Exec(`DELETE FROM positions WHERE id = ?`, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// SaveLegMark records the latest mark-to-market price of a leg
+func SaveLegMark(legID int64, mark float64, markedAt time.Time) error {
+	_, err := DB.Exec(`UPDATE position_legs SET mark = ?, marked_at = ? WHERE id = ?`, mark, normalizeTime(markedAt), legID)
+	return err
+}
+
+// execOne runs a statement that must affect exactly one row
+func execOne(query string, args ...interface{}) error {
+	res, err := DB.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
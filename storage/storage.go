@@ -138,6 +138,48 @@ func InitDB() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if version < 2 {
+		log.Println("Migrating database to version 2...")
+		if err := migrateChainHistory(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if version < 3 {
+		log.Println("Migrating database to version 3...")
+		if err := migrateDividends(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if version < 4 {
+		log.Println("Migrating database to version 4...")
+		if err := migratePortfolio(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if version < 5 {
+		log.Println("Migrating database to version 5...")
+		if err := migrateEarnings(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if version < 6 {
+		log.Println("Migrating database to version 6...")
+		if err := migrateWhales(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if version < 7 {
+		log.Println("Migrating database to version 7...")
+		if err := migrateVolatility(); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 func SaveArticle(article Article) error {
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"time"
+)
+
+// PriceBar is one day's OHLC for an underlying
+type PriceBar struct {
+	Ticker string    `json:"ticker"`
+	Date   time.Time `json:"date"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+}
+
+// IVPoint is a ticker's ATM 30-day implied volatility on one day
+type IVPoint struct {
+	Ticker string    `json:"ticker"`
+	Date   time.Time `json:"date"`
+	IV     float64   `json:"iv"`
+}
+
+func migrateVolatility() error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS price_bars (
+			ticker TEXT NOT NULL,
+			date TEXT NOT NULL,
+			open REAL NOT NULL,
+			high REAL NOT NULL,
+			low REAL NOT NULL,
+			close REAL NOT NULL,
+			PRIMARY KEY (ticker, date)
+		);`,
+		`CREATE TABLE IF NOT EXISTS iv_history (
+			ticker TEXT NOT NULL,
+			date TEXT NOT NULL,
+			iv REAL NOT NULL,
+			PRIMARY KEY (ticker, date)
+		);`,
+		`INSERT INTO schema_version (version) VALUES (7)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SavePriceBar inserts or replaces a ticker's bar for the day
+func SavePriceBar(b PriceBar) error {
+	_, err := DB.Exec(`INSERT INTO price_bars (ticker, date, open, high, low, close) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(ticker, date) DO UPDATE SET open = excluded.open, high = excluded.high, low = excluded.low, close = excluded.close`,
+		b.Ticker, b.Date.Format("2006-01-02"), b.Open, b.High, b.Low, b.Close)
+	return err
+}
+
+// GetPriceBars returns a ticker's bars dated within [from, to], oldest first
+func GetPriceBars(ticker string, from, to time.Time) ([]PriceBar, error) {
+	rows, err := DB.Query(`SELECT date, open, high, low, close FROM price_bars WHERE ticker = ? AND date >= ? AND date <= ? ORDER BY date`,
+		ticker, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bars []PriceBar
+	for rows.Next() {
+		b := PriceBar{Ticker: ticker}
+		var date string
+		if err := rows.Scan(&date, &b.Open, &b.High, &b.Low, &b.Close); err != nil {
+			return nil, err
+		}
+		if b.Date, err = time.Parse("2006-01-02", date); err != nil {
+			return nil, err
+		}
+		bars = append(bars, b)
+	}
+	return bars, rows.Err()
+}
+
+// SaveIV inserts or replaces a ticker's ATM 30-day IV for the day
+func SaveIV(p IVPoint) error {
+	_, err := DB.Exec(`INSERT INTO iv_history (ticker, date, iv) VALUES (?, ?, ?)
+		ON CONFLICT(ticker, date) DO UPDATE SET iv = excluded.iv`,
+		p.Ticker, p.Date.Format("2006-01-02"), p.IV)
+	return err
+}
+
+// GetIVHistory returns a ticker's stored IVs dated within [from, to], oldest first
+func GetIVHistory(ticker string, from, to time.Time) ([]IVPoint, error) {
+	rows, err := DB.Query(`SELECT date, iv FROM iv_history WHERE ticker = ? AND date >= ? AND date <= ? ORDER BY date`,
+		ticker, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []IVPoint
+	for rows.Next() {
+		p := IVPoint{Ticker: ticker}
+		var date string
+		if err := rows.Scan(&date, &p.IV); err != nil {
+			return nil, err
+		}
+		if p.Date, err = time.Parse("2006-01-02", date); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// GetDayQuotes returns every underlying price captured for a ticker on the UTC day of date, oldest first
+func GetDayQuotes(ticker string, date time.Time) ([]UnderlyingQuote, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	rows, err := DB.Query(`SELECT price, captured_at FROM underlying_quotes WHERE ticker = ? AND captured_at >= ? AND captured_at < ? ORDER BY captured_at`,
+		ticker, day, day.Add(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []UnderlyingQuote
+	for rows.Next() {
+		q := UnderlyingQuote{Ticker: ticker}
+		if err := rows.Scan(&q.Price, &q.CapturedAt); err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, rows.Err()
+}
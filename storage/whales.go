@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"time"
+)
+
+// WhaleAlert is a contract flagged by the unusual activity scanner.
+// Volume is cumulative through the day, so repeat flags on the same trade date update one alert.
+type WhaleAlert struct {
+	ID           int64     `json:"id"`
+	Ticker       string    `json:"ticker"`
+	Expiry       string    `json:"expiry"` // 2006-01-02
+	Type         string    `json:"type"`   // "Call" or "Put"
+	Strike       float64   `json:"strike"`
+	TradeDate    string    `json:"tradeDate"` // 2006-01-02
+	Volume       int64     `json:"volume"`
+	OpenInterest int64     `json:"openInterest"`
+	VolumeOI     float64   `json:"volumeOI"`   // Volume / open interest; 0 when open interest is 0
+	Premium      float64   `json:"premium"`    // Volume x price x 100, in dollars
+	OTMPercent   float64   `json:"otmPercent"` // Distance out of the money, percent of spot; negative in the money
+	Price        float64   `json:"price"`      // Option price used for Premium
+	Underlying   float64   `json:"underlying"`
+	IV           float64   `json:"iv"`
+	DetectedAt   time.Time `json:"detectedAt"` // First flagged
+	UpdatedAt    time.Time `json:"updatedAt"`  // Last flagged
+}
+
+func migrateWhales() error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS whale_alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ticker TEXT NOT NULL,
+			expiry TEXT NOT NULL,
+			type TEXT NOT NULL,
+			strike REAL NOT NULL,
+			trade_date TEXT NOT NULL,
+			volume INTEGER NOT NULL,
+			open_interest INTEGER NOT NULL,
+			volume_oi REAL NOT NULL,
+			premium REAL NOT NULL,
+			otm_percent REAL NOT NULL,
+			price REAL NOT NULL,
+			underlying REAL NOT NULL,
+			iv REAL,
+			detected_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			UNIQUE(ticker, expiry, type, strike, trade_date)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_whale_alerts_updated ON whale_alerts (updated_at);`,
+		`INSERT INTO schema_version (version) VALUES (6)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveWhaleAlerts inserts new alerts and refreshes the activity of ones already flagged on the same trade date
+func SaveWhaleAlerts(alerts []WhaleAlert) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO whale_alerts (ticker, expiry, type, strike, trade_date, volume, open_interest, volume_oi, premium, otm_percent, price, underlying, iv, detected_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(ticker, expiry, type, strike, trade_date) DO UPDATE SET
+			volume = excluded.volume, open_interest = excluded.open_interest, volume_oi = excluded.volume_oi,
+			premium = excluded.premium, otm_percent = excluded.otm_percent, price = excluded.price,
+			underlying = excluded.underlying, iv = excluded.iv, updated_at = excluded.updated_at`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, a := range alerts {
+		at := normalizeTime(a.UpdatedAt)
+		_, err := stmt.Exec(a.Ticker, a.Expiry, a.Type, a.Strike, a.TradeDate, a.Volume, a.OpenInterest, a.VolumeOI,
+			a.Premium, a.OTMPercent, a.Price, a.Underlying, a.IV, at, at)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetWhaleAlerts returns alerts updated at or after since, by premium, largest first. An empty ticker matches every ticker.
+func GetWhaleAlerts(ticker string, since time.Time, limit int) ([]WhaleAlert, error) {
+	query := `SELECT id, ticker, expiry, type, strike, trade_date, volume, open_interest, volume_oi, premium, otm_percent, price, underlying, COALESCE(iv, 0), detected_at, updated_at
+		FROM whale_alerts WHERE updated_at >= ?`
+	args := []interface{}{normalizeTime(since)}
+	if ticker != "" {
+		query += ` AND ticker = ?`
+		args = append(args, ticker)
+	}
+	query += ` ORDER BY premium DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []WhaleAlert{}
+	for rows.Next() {
+		var a WhaleAlert
+		err := rows.Scan(&a.ID, &a.Ticker, &a.Expiry, &a.Type, &a.Strike, &a.TradeDate, &a.Volume, &a.OpenInterest, &a.VolumeOI,
+			&a.Premium, &a.OTMPercent, &a.Price, &a.Underlying, &a.IV, &a.DetectedAt, &a.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
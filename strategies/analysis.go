@@ -3,24 +3,42 @@ package strategies
 import (
 	"math"
 	"strikelogic/calculator"
+	"strikelogic/rates"
+	"time"
 )
 
-// CalculateMetrics computes MaxProfit, MaxRisk, BreakEvens, and Greeks for the trade
+// EntryPrice is the per-share price the leg is assumed to fill at: buys at the ask, sells at the bid,
+// falling back to the last price when that side of the quote is empty. Stock legs fill at StockPrice.
+func (leg TradeLeg) EntryPrice() float64 {
+	if leg.IsStock {
+		return leg.StockPrice
+	}
+	price := leg.Option.Ask
+	if leg.Action == Sell {
+		price = leg.Option.Bid
+	}
+	if price == 0 {
+		price = leg.Option.Last
+	}
+	return price
+}
+
+// CalculateMetrics computes MaxProfit, MaxRisk, BreakEvens, ROI, Greeks and, under a lognormal
+// distribution, PoP, EV and the probabilities of max profit and max loss
 func (t *Trade) CalculateMetrics(currentPrice float64) {
+	t.CalculatePayoff(currentPrice)
+	if dist, ok := t.ExpiryDistribution(currentPrice, nil); ok {
+		t.CalculateProbabilities(dist)
+	}
+}
+
+// CalculatePayoff is CalculateMetrics without the probabilities, for callers that supply their own
+// distribution to CalculateProbabilities
+func (t *Trade) CalculatePayoff(currentPrice float64) {
 	// 1. Calculate Net Debit/Credit
 	t.NetDebit = 0
 	for _, leg := range t.Legs {
-		cost := leg.Option.Ask // Assuming we buy at Ask
-		if leg.Action == Sell {
-			cost = leg.Option.Bid // Sell at Bid
-		}
-
-		// If it's a stock leg
-		if leg.IsStock {
-			cost = leg.StockPrice
-		}
-
-		amount := cost * float64(leg.Quantity)
+		amount := leg.EntryPrice() * float64(leg.Quantity)
 		if !leg.IsStock {
 			amount *= 100 // Options multiplier
 		}
@@ -61,9 +79,9 @@ func (t *Trade) CalculateMetrics(currentPrice float64) {
 	}
 
 	// 3. Analyze P&L Profile to find MaxProfit, MaxRisk, BreakEvens
-	// We scan a range of prices at expiry.
-	// Range: 0 to 2 * currentPrice (or higher if needed)
-	// Resolution: 1000 points?
+	// We scan a range of prices at the front expiry.
+	// Range: 0 to 3 * currentPrice
+	// Resolution: 1000 points
 
 	minPrice := 0.0
 	maxPrice := currentPrice * 3.0
@@ -76,9 +94,10 @@ func (t *Trade) CalculateMetrics(currentPrice float64) {
 	var pnlPoints []float64
 	prices := []float64{}
 
+	pnlAt := t.pnlAtFrontExpiry()
 	for i := 0; i <= steps; i++ {
 		price := minPrice + float64(i)*stepSize
-		pnl := t.CalculatePnLAtExpiry(price)
+		pnl := pnlAt(price)
 
 		if pnl > maxProfit {
 			maxProfit = pnl
@@ -96,6 +115,12 @@ func (t *Trade) CalculateMetrics(currentPrice float64) {
 	if t.MaxRisk < 0 {
 		t.MaxRisk = 0 // If minProfit is positive, there is no risk (arbitrage?)
 	}
+	// Still losing (or gaining) more than a share's worth per $1 rise at the top of the scan
+	slope := 0.0
+	if stepSize > 0 {
+		slope = (pnlPoints[steps] - pnlPoints[steps-1]) / stepSize
+	}
+	t.UndefinedRisk, t.UnlimitedProfit = slope < -1, slope > 1
 
 	// Find BreakEvens (Zero crossings)
 	var breakEvens []float64
@@ -114,41 +139,217 @@ func (t *Trade) CalculateMetrics(currentPrice float64) {
 		}
 	}
 	t.BreakEvens = breakEvens
+
+	// 4. Return on risk and probability of profit
+	t.ROI = 0
+	if t.MaxRisk > 0 {
+		t.ROI = math.Round(t.MaxProfit/t.MaxRisk*1000) / 10
+	}
+
+	if front := t.FrontExpiry(); front != "" {
+		t.ExpirationDate = front
+	}
 }
 
-// CalculatePnLAtExpiry calculates the P&L of the trade if the underlying is at `price` at expiry
-func (t *Trade) CalculatePnLAtExpiry(price float64) float64 {
-	pnl := 0.0
+// CalculateProbabilities integrates the front expiry P&L against dist, setting PoP, EV, ProbMaxProfit and ProbMaxLoss.
+// MaxProfit and MaxRisk must already be set (see CalculatePayoff).
+func (t *Trade) CalculateProbabilities(dist calculator.Distribution) {
+	pnl := t.pnlAtFrontExpiry()
+	values := make([]float64, len(dist.Prices))
+	for i, p := range dist.Prices {
+		values[i] = pnl(p)
+	}
 
-	// Start with initial debit/credit
-	// If NetDebit is positive, we paid money. P&L starts at -NetDebit.
-	// If NetDebit is negative (credit), we received money. P&L starts at -NetDebit (positive).
-	pnl -= t.NetDebit
+	// P&L within 1% of the trade's range of the max counts as reaching it
+	tol := 0.01 * (t.MaxProfit + t.MaxRisk)
 
+	var pop, ev, pMax, pMin float64
+	for i, prob := range dist.Probs {
+		v := values[i]
+		ev += prob * v
+		if v > 0 {
+			pop += prob
+		}
+		if v >= t.MaxProfit-tol {
+			pMax += prob
+		}
+		if t.MaxRisk > 0 && v <= -t.MaxRisk+tol {
+			pMin += prob
+		}
+	}
+
+	t.PoP = math.Round(pop*1000) / 10
+	t.EV = math.Round(ev*100) / 100
+	t.ProbMaxProfit = math.Round(pMax*1000) / 10
+	t.ProbMaxLoss = math.Round(pMin*1000) / 10
+}
+
+// ExpiryDistribution is the terminal distribution of the underlying at the front expiry.
+// With a surface it is the surface's implied distribution, otherwise a lognormal at the average IV of the front legs.
+func (t *Trade) ExpiryDistribution(currentPrice float64, surface calculator.VolSurface) (calculator.Distribution, bool) {
+	front := t.FrontExpiry()
+	expiry, err := time.Parse("2006-01-02", front)
+	if err != nil || currentPrice <= 0 {
+		return calculator.Distribution{}, false
+	}
+
+	sigma, n := 0.0, 0
+	underlying := ""
 	for _, leg := range t.Legs {
-		value := 0.0
-		if leg.IsStock {
-			value = price * float64(leg.Quantity)
-		} else {
-			// Option value at expiry
-			optValue := 0.0
-			if leg.Option.Type == calculator.Call {
-				if price > leg.Option.Strike {
-					optValue = price - leg.Option.Strike
-				}
+		if !leg.IsStock && leg.Option.Expiry == front && leg.Option.Vol > 0 {
+			sigma += leg.Option.Vol
+			n++
+			underlying = leg.Option.Underlying
+		}
+	}
+	if n == 0 {
+		return calculator.Distribution{}, false
+	}
+	sigma /= float64(n)
+
+	T := math.Max(expiry.Sub(time.Now()).Hours()/24/365.0, 1.0/365)
+	r := rates.RateFor(T)
+	var q float64
+	if underlying != "" {
+		q, _ = calculator.LookupDividends(underlying)
+	}
+
+	if surface != nil {
+		if atm := surface.Vol(currentPrice, T); atm > 0 {
+			return calculator.SmileDistribution(currentPrice, T, r, q, atm, func(K float64) float64 { return surface.Vol(K, T) }), true
+		}
+	}
+	return calculator.LognormalDistribution(currentPrice, T, r, q, sigma), true
+}
+
+// FrontExpiry returns the earliest option leg expiry (YYYY-MM-DD), or "" for a stock-only trade
+func (t *Trade) FrontExpiry() string {
+	front := ""
+	for _, leg := range t.Legs {
+		if leg.IsStock || leg.Option.Expiry == "" {
+			continue
+		}
+		if front == "" || leg.Option.Expiry < front {
+			front = leg.Option.Expiry
+		}
+	}
+	return front
+}
+
+// CalculatePnLAtExpiry calculates the P&L of the trade if the underlying is at `price` at the front expiry.
+// Legs expiring later (calendars, diagonals, PMCC) are valued with the default pricing model.
+func (t *Trade) CalculatePnLAtExpiry(price float64) float64 {
+	return t.pnlAtFrontExpiry()(price)
+}
+
+// PnLAt calculates the P&L of the trade if the underlying is at `price` on date.
+// Legs still open on that date are valued with the default pricing model.
+func (t *Trade) PnLAt(price float64, date time.Time) float64 {
+	return t.pnlAt(date)(price)
+}
+
+// pnlAtFrontExpiry is pnlAt the front expiry
+func (t *Trade) pnlAtFrontExpiry() func(price float64) float64 {
+	horizon, _ := time.Parse("2006-01-02", t.FrontExpiry())
+	return t.pnlAt(horizon)
+}
+
+// pnlAt resolves the leg expiries and dividends once and returns the P&L function at horizon
+func (t *Trade) pnlAt(horizon time.Time) func(price float64) float64 {
+	var q float64
+	var dividends []calculator.CashDividend
+	expiries := make([]time.Time, len(t.Legs))
+	for i, leg := range t.Legs {
+		expiries[i], _ = time.Parse("2006-01-02", leg.Option.Expiry)
+	}
+	for i, leg := range t.Legs {
+		if !leg.IsStock && expiries[i].After(horizon) && leg.Option.Underlying != "" {
+			yield, schedule := calculator.LookupDividends(leg.Option.Underlying)
+			q, dividends = calculator.DividendInputs(yield, schedule, horizon)
+			break
+		}
+	}
+
+	return func(price float64) float64 {
+		pnl := 0.0
+
+		// Start with initial debit/credit
+		// If NetDebit is positive, we paid money. P&L starts at -NetDebit.
+		// If NetDebit is negative (credit), we received money. P&L starts at -NetDebit (positive).
+		pnl -= t.NetDebit
+
+		for i, leg := range t.Legs {
+			value := 0.0
+			if leg.IsStock {
+				value = price * float64(leg.Quantity)
 			} else {
-				if price < leg.Option.Strike {
-					optValue = leg.Option.Strike - price
-				}
+				value = optionValueAt(leg.Option, expiries[i], price, horizon, q, dividends) * 100 * float64(leg.Quantity)
 			}
-			value = optValue * 100 * float64(leg.Quantity)
+
+			if leg.Action == Buy {
+				pnl += value
+			} else {
+				pnl -= value
+			}
+		}
+		return pnl
+	}
+}
+
+// optionValueAt is the per-share value of an option expiring at expiry, at horizon with the underlying at price.
+// Options expiring by the horizon (or with no expiry) are worth intrinsic value.
+func optionValueAt(opt calculator.OptionContract, expiry time.Time, price float64, horizon time.Time, q float64, dividends []calculator.CashDividend) float64 {
+	if expiry.IsZero() || !expiry.After(horizon) {
+		if opt.Type == calculator.Call {
+			return math.Max(0, price-opt.Strike)
 		}
+		return math.Max(0, opt.Strike-price)
+	}
 
-		if leg.Action == Buy {
-			pnl += value
-		} else {
-			pnl -= value
+	T := expiry.Sub(horizon).Hours() / 24 / 365.0
+	return calculator.DefaultModel().Price(calculator.PricingInput{
+		Type:      opt.Type,
+		S:         price,
+		K:         opt.Strike,
+		T:         T,
+		R:         rates.RateFor(T),
+		Sigma:     opt.Vol,
+		Q:         q,
+		Dividends: dividends,
+	}).Price
+}
+
+// MatrixInput maps the trade onto the profit matrix's input, with each leg entered at its EntryPrice
+func (t *Trade) MatrixInput() calculator.StrategyInput {
+	input := calculator.StrategyInput{InitialDebit: t.NetDebit}
+
+	for _, leg := range t.Legs {
+		if leg.IsStock {
+			input.Legs = append(input.Legs, calculator.LegInput{
+				IsStock:    true,
+				Action:     string(leg.Action),
+				Quantity:   float64(leg.Quantity),
+				EntryPrice: leg.EntryPrice(),
+			})
+			continue
 		}
+
+		expiry, _ := time.Parse("2006-01-02", leg.Option.Expiry)
+
+		optType := calculator.Call
+		if leg.Option.Type == calculator.Put {
+			optType = calculator.Put
+		}
+
+		input.Legs = append(input.Legs, calculator.LegInput{
+			Strike:     leg.Option.Strike,
+			Type:       optType,
+			Action:     string(leg.Action),
+			Quantity:   float64(leg.Quantity),
+			Expiry:     expiry,
+			IV:         leg.Option.Vol,
+			EntryPrice: leg.EntryPrice(),
+		})
 	}
-	return pnl
+	return input
 }
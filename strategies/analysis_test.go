@@ -0,0 +1,119 @@
+package strategies
+
+import (
+	"math"
+	"strikelogic/calculator"
+	"strikelogic/rates"
+	"testing"
+	"time"
+)
+
+func normCDF(x float64) float64 { return 0.5 * math.Erfc(-x/math.Sqrt2) }
+
+// bullCallSpread buys K1 and sells K2 calls at Black-Scholes prices with no spread
+func bullCallSpread(spot, k1, k2, sigma float64, expiry time.Time) Trade {
+	T := expiry.Sub(time.Now()).Hours() / 24 / 365
+	r := rates.RateFor(T)
+	leg := func(action Action, K float64) TradeLeg {
+		price, delta, _, _, _ := calculator.CalculateOptionPrice(calculator.Call, spot, K, T, r, sigma)
+		return TradeLeg{Action: action, Quantity: 1, Option: calculator.OptionContract{
+			Strike: K, Expiry: expiry.Format("2006-01-02"), Type: calculator.Call,
+			Bid: price, Ask: price, Last: price, Vol: sigma, Delta: delta,
+		}}
+	}
+	return Trade{Name: "Bull Call Spread", Legs: []TradeLeg{leg(Buy, k1), leg(Sell, k2)}}
+}
+
+func TestVerticalProbabilitiesMatchClosedForm(t *testing.T) {
+	const spot, k1, k2, sigma = 100.0, 95.0, 110.0, 0.3
+	expiry := time.Now().UTC().AddDate(0, 0, 60).Truncate(24 * time.Hour)
+	trade := bullCallSpread(spot, k1, k2, sigma, expiry)
+	trade.CalculateMetrics(spot)
+
+	T := expiry.Sub(time.Now()).Hours() / 24 / 365
+	r := rates.RateFor(T)
+	// Risk-neutral probability of finishing above K
+	above := func(K float64) float64 {
+		return normCDF((math.Log(spot/K) + (r-0.5*sigma*sigma)*T) / (sigma * math.Sqrt(T)))
+	}
+
+	debit := trade.NetDebit / 100
+	if math.Abs(trade.MaxProfit-(k2-k1-debit)*100) > 1 || math.Abs(trade.MaxRisk-debit*100) > 1 {
+		t.Errorf("max profit %.2f / risk %.2f, want %.2f / %.2f", trade.MaxProfit, trade.MaxRisk, (k2-k1-debit)*100, debit*100)
+	}
+	breakEven := k1 + debit
+	if len(trade.BreakEvens) != 1 || math.Abs(trade.BreakEvens[0]-breakEven) > 0.5 {
+		t.Errorf("break evens %v, want [%.2f]", trade.BreakEvens, breakEven)
+	}
+
+	// The distribution is bucketed about $0.30 wide here and max profit/loss count P&L within 1% of the
+	// trade's range, which moves each probability by up to half a point; allow one
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"PoP", trade.PoP, above(breakEven) * 100},
+		{"ProbMaxProfit", trade.ProbMaxProfit, above(k2) * 100},
+		{"ProbMaxLoss", trade.ProbMaxLoss, (1 - above(k1)) * 100},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > 1 {
+			t.Errorf("%s = %.1f%%, closed form %.2f%%", c.name, c.got, c.want)
+		}
+	}
+
+	// Priced at the model, the expected payoff is the forward value of the debit: EV = debit * (e^rT - 1)
+	wantEV := trade.NetDebit * (math.Exp(r*T) - 1)
+	if math.Abs(trade.EV-wantEV) > 3 {
+		t.Errorf("EV = %.2f, want %.2f", trade.EV, wantEV)
+	}
+}
+
+func TestCalculateProbabilitiesDiscrete(t *testing.T) {
+	expiry := time.Now().UTC().AddDate(0, 0, 30).Truncate(24 * time.Hour).Format("2006-01-02")
+	// Bull call spread 100/110 for a $4 debit: loses 400 below 100, makes 600 above 110
+	trade := Trade{
+		Legs: []TradeLeg{
+			{Action: Buy, Quantity: 1, Option: calculator.OptionContract{Strike: 100, Expiry: expiry, Type: calculator.Call, Ask: 5}},
+			{Action: Sell, Quantity: 1, Option: calculator.OptionContract{Strike: 110, Expiry: expiry, Type: calculator.Call, Bid: 1}},
+		},
+		NetDebit:  400,
+		MaxProfit: 600,
+		MaxRisk:   400,
+	}
+
+	dist := calculator.Distribution{
+		Prices: []float64{90, 102, 106, 115},
+		Probs:  []float64{0.4, 0.2, 0.1, 0.3},
+	}
+	trade.CalculateProbabilities(dist)
+
+	// P&L: -400, -200, +200, +600
+	if trade.PoP != 40 {
+		t.Errorf("PoP = %v, want 40", trade.PoP)
+	}
+	if want := 0.4*-400 + 0.2*-200 + 0.1*200 + 0.3*600; math.Abs(trade.EV-want) > 1e-9 {
+		t.Errorf("EV = %v, want %v", trade.EV, want)
+	}
+	if trade.ProbMaxProfit != 30 || trade.ProbMaxLoss != 40 {
+		t.Errorf("ProbMaxProfit %v, ProbMaxLoss %v, want 30 and 40", trade.ProbMaxProfit, trade.ProbMaxLoss)
+	}
+}
+
+func TestUndefinedRisk(t *testing.T) {
+	expiry := time.Now().UTC().AddDate(0, 0, 30)
+	spread := bullCallSpread(100, 100, 110, 0.3, expiry)
+	spread.CalculatePayoff(100)
+	if spread.UndefinedRisk {
+		t.Error("a vertical has defined risk")
+	}
+
+	// Selling a second 110 call leaves one naked
+	ratio := spread
+	ratio.Legs = []TradeLeg{spread.Legs[0], spread.Legs[1]}
+	ratio.Legs[1].Quantity = 2
+	ratio.CalculatePayoff(100)
+	if !ratio.UndefinedRisk {
+		t.Errorf("a 1x2 call ratio has undefined risk (MaxRisk %.2f)", ratio.MaxRisk)
+	}
+}
@@ -0,0 +1,497 @@
+package strategies
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strikelogic/calculator"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RecipeSpec is a strategy recipe defined in a config file rather than in Go.
+//
+//	name: Jade Lizard
+//	sentiment: Bullish
+//	legs:
+//	  - {action: Sell, type: put, strike: {by: otm, value: 2}}
+//	  - {action: Sell, type: call, strike: {by: otm, value: 1}}
+//	  - {action: Buy, type: call, strike: {by: otm, value: 2}}
+type RecipeSpec struct {
+	Name          string    `json:"name" yaml:"name"`
+	Description   string    `json:"description" yaml:"description"`
+	Sentiment     string    `json:"sentiment" yaml:"sentiment"`         // "Bullish", "Bearish", "Neutral"
+	BackMonthDays int       `json:"backMonthDays" yaml:"backMonthDays"` // Non-zero for recipes with back-month legs
+	Legs          []LegSpec `json:"legs" yaml:"legs"`
+}
+
+// LegSpec is one leg of a RecipeSpec
+type LegSpec struct {
+	Action   Action         `json:"action" yaml:"action"`     // Buy or Sell
+	Type     string         `json:"type" yaml:"type"`         // call, put or stock
+	Quantity int            `json:"quantity" yaml:"quantity"` // Defaults to 1 (100 for stock)
+	Expiry   string         `json:"expiry" yaml:"expiry"`     // front (default) or back
+	Strike   StrikeSelector `json:"strike" yaml:"strike"`     // Ignored for stock
+}
+
+// Leg types and expiries accepted in a LegSpec
+const (
+	LegCall  = "call"
+	LegPut   = "put"
+	LegStock = "stock"
+
+	ExpiryFront = "front"
+	ExpiryBack  = "back"
+)
+
+// StrikeSelector picks a strike from the chain. By is one of the Select* constants.
+type StrikeSelector struct {
+	By    string  `json:"by" yaml:"by"`
+	Value float64 `json:"value,omitempty" yaml:"value,omitempty"`
+	Leg   int     `json:"leg,omitempty" yaml:"leg,omitempty"` // Reference leg (1-based) for leg and width selectors
+}
+
+// Strike selectors
+const (
+	SelectATM          = "atm"          // Closest to spot
+	SelectTarget       = "target"       // Closest to the target price
+	SelectOTM          = "otm"          // Value strikes out of the money
+	SelectITM          = "itm"          // Value strikes in the money
+	SelectDelta        = "delta"        // |delta| closest to Value
+	SelectProbOTM      = "probotm"      // Probability of expiring out of the money closest to Value (0-1)
+	SelectPercent      = "percent"      // Closest to spot * (1 + Value/100)
+	SelectLeg          = "leg"          // Same strike as Leg
+	SelectWidth        = "width"        // Value dollars further out of the money than Leg
+	SelectWidthPercent = "widthpercent" // Value percent of spot further out of the money than Leg
+)
+
+// normalize makes the spec's keywords case-insensitive
+func (spec *RecipeSpec) normalize() {
+	for i := range spec.Legs {
+		leg := &spec.Legs[i]
+		switch strings.ToLower(string(leg.Action)) {
+		case "buy":
+			leg.Action = Buy
+		case "sell":
+			leg.Action = Sell
+		}
+		leg.Type = strings.ToLower(leg.Type)
+		leg.Expiry = strings.ToLower(leg.Expiry)
+		leg.Strike.By = strings.ToLower(leg.Strike.By)
+	}
+}
+
+// Validate checks a spec for mistakes that would otherwise only show up as a recipe that never builds
+func (spec RecipeSpec) Validate() error {
+	if spec.Name == "" {
+		return fmt.Errorf("recipe has no name")
+	}
+	if len(spec.Legs) == 0 {
+		return fmt.Errorf("recipe %s has no legs", spec.Name)
+	}
+
+	for i, leg := range spec.Legs {
+		n := i + 1
+		if leg.Action != Buy && leg.Action != Sell {
+			return fmt.Errorf("recipe %s leg %d: action must be Buy or Sell", spec.Name, n)
+		}
+		switch strings.ToLower(leg.Type) {
+		case LegStock:
+			continue
+		case LegCall, LegPut:
+		default:
+			return fmt.Errorf("recipe %s leg %d: type must be call, put or stock", spec.Name, n)
+		}
+		switch strings.ToLower(leg.Expiry) {
+		case "", ExpiryFront:
+		case ExpiryBack:
+			if spec.BackMonthDays <= 0 {
+				return fmt.Errorf("recipe %s leg %d: back-month leg needs backMonthDays", spec.Name, n)
+			}
+		default:
+			return fmt.Errorf("recipe %s leg %d: expiry must be front or back", spec.Name, n)
+		}
+
+		sel := leg.Strike
+		switch sel.By {
+		case SelectATM, SelectTarget, SelectPercent:
+		case SelectOTM, SelectITM:
+			if sel.Value < 1 {
+				return fmt.Errorf("recipe %s leg %d: %s needs a value of at least 1", spec.Name, n, sel.By)
+			}
+		case SelectDelta, SelectProbOTM:
+			if sel.Value <= 0 || sel.Value >= 1 {
+				return fmt.Errorf("recipe %s leg %d: %s must be between 0 and 1", spec.Name, n, sel.By)
+			}
+		case SelectLeg, SelectWidth, SelectWidthPercent:
+			if sel.Leg < 1 || sel.Leg >= n || strings.ToLower(spec.Legs[sel.Leg-1].Type) == LegStock {
+				return fmt.Errorf("recipe %s leg %d: leg must refer to an earlier option leg", spec.Name, n)
+			}
+			if sel.By != SelectLeg && sel.Value == 0 {
+				return fmt.Errorf("recipe %s leg %d: %s needs a non-zero value", spec.Name, n, sel.By)
+			}
+		default:
+			return fmt.Errorf("recipe %s leg %d: unknown strike selector %q", spec.Name, n, sel.By)
+		}
+	}
+	return nil
+}
+
+// Recipe compiles the spec into a StrategyRecipe for the given underlying price
+func (spec RecipeSpec) Recipe(currentPrice float64) StrategyRecipe {
+	return StrategyRecipe{
+		Name:          spec.Name,
+		Description:   spec.Description,
+		BackMonthDays: spec.BackMonthDays,
+		Builder: func(c []calculator.OptionContract, tp float64) *Trade {
+			front, back := c, []calculator.OptionContract(nil)
+			if spec.BackMonthDays > 0 {
+				front, back = splitExpiries(c)
+			}
+
+			trade := &Trade{Name: spec.Name, Sentiment: spec.Sentiment}
+			var parts []string
+			for _, ls := range spec.Legs {
+				leg, ok := spec.buildLeg(ls, trade.Legs, front, back, currentPrice, tp)
+				if !ok {
+					return nil
+				}
+				trade.Legs = append(trade.Legs, leg)
+				parts = append(parts, describeLeg(leg, spec.BackMonthDays > 0))
+			}
+			trade.Description = strings.Join(parts, ", ")
+			return trade
+		},
+	}
+}
+
+func (spec RecipeSpec) buildLeg(ls LegSpec, built []TradeLeg, front, back []calculator.OptionContract, currentPrice, targetPrice float64) (TradeLeg, bool) {
+	if strings.ToLower(ls.Type) == LegStock {
+		qty := ls.Quantity
+		if qty == 0 {
+			qty = 100
+		}
+		return TradeLeg{Action: ls.Action, Quantity: qty, IsStock: true, StockPrice: currentPrice}, true
+	}
+
+	optType := calculator.Call
+	if strings.ToLower(ls.Type) == LegPut {
+		optType = calculator.Put
+	}
+	chain := front
+	if strings.ToLower(ls.Expiry) == ExpiryBack {
+		chain = back
+	}
+
+	opt := selectStrike(ls.Strike, chain, optType, currentPrice, targetPrice, built)
+	if opt == nil {
+		return TradeLeg{}, false
+	}
+
+	qty := ls.Quantity
+	if qty == 0 {
+		qty = 1
+	}
+	return TradeLeg{Action: ls.Action, Quantity: qty, Option: *opt}, true
+}
+
+// selectStrike resolves a StrikeSelector against one expiry of the chain
+func selectStrike(sel StrikeSelector, chain []calculator.OptionContract, optType calculator.OptionType, currentPrice, targetPrice float64, built []TradeLeg) *calculator.OptionContract {
+	switch sel.By {
+	case SelectATM:
+		return findClosest(chain, currentPrice, optType)
+	case SelectTarget:
+		return findClosest(chain, targetPrice, optType)
+	case SelectOTM:
+		return findOTM(chain, currentPrice, optType, int(sel.Value))
+	case SelectITM:
+		return findITM(chain, currentPrice, optType, int(sel.Value))
+	case SelectDelta:
+		return findDelta(chain, sel.Value, optType)
+	case SelectProbOTM:
+		return findProbOTM(chain, sel.Value, optType, currentPrice, time.Now())
+	case SelectPercent:
+		return findClosest(chain, currentPrice*(1+sel.Value/100), optType)
+	}
+
+	ref := sel.Leg - 1
+	if ref < 0 || ref >= len(built) || built[ref].IsStock {
+		return nil
+	}
+	refStrike := built[ref].Option.Strike
+	switch sel.By {
+	case SelectLeg:
+		return findStrike(chain, refStrike, optType)
+	case SelectWidth:
+		return findWidth(chain, refStrike, sel.Value, optType)
+	case SelectWidthPercent:
+		return findWidth(chain, refStrike, currentPrice*sel.Value/100, optType)
+	}
+	return nil
+}
+
+// findProbOTM returns the contract whose lognormal probability of expiring out of the money is closest to target
+func findProbOTM(chain []calculator.OptionContract, target float64, optType calculator.OptionType, currentPrice float64, now time.Time) *calculator.OptionContract {
+	var best *calculator.OptionContract
+	minDiff := math.MaxFloat64
+
+	for i := range chain {
+		opt := &chain[i]
+		if opt.Type != optType || opt.Vol <= 0 {
+			continue
+		}
+		expiry, err := time.Parse("2006-01-02", opt.Expiry)
+		if err != nil {
+			continue
+		}
+		T := math.Max(expiry.Sub(now).Hours()/24/365.0, 0.001)
+
+		// CalculatePoP is the chance of finishing above the strike: ITM for a call, OTM for a put
+		above := calculator.CalculatePoP(opt.Strike, currentPrice, T, opt.Vol)
+		probOTM := above
+		if optType == calculator.Call {
+			probOTM = 1 - above
+		}
+
+		diff := math.Abs(probOTM - target)
+		if diff < minDiff {
+			minDiff = diff
+			best = opt
+		}
+	}
+	return best
+}
+
+// findWidth returns the contract closest to width dollars further out of the money than refStrike
+// (above it for calls, below for puts; a negative width goes the other way).
+// It always moves at least one strike, so a width narrower than the strike spacing never gives a zero-width spread.
+func findWidth(chain []calculator.OptionContract, refStrike, width float64, optType calculator.OptionType) *calculator.OptionContract {
+	dir := 1.0
+	if optType == calculator.Put {
+		dir = -1
+	}
+	if width < 0 {
+		dir, width = -dir, -width
+	}
+	target := refStrike + dir*width
+
+	var best *calculator.OptionContract
+	minDiff := math.MaxFloat64
+	for i := range chain {
+		if chain[i].Type != optType || (chain[i].Strike-refStrike)*dir <= 0 {
+			continue
+		}
+		diff := math.Abs(chain[i].Strike - target)
+		if diff < minDiff {
+			minDiff = diff
+			best = &chain[i]
+		}
+	}
+	return best
+}
+
+func describeLeg(leg TradeLeg, withExpiry bool) string {
+	if leg.IsStock {
+		return fmt.Sprintf("%s %d Shares", leg.Action, leg.Quantity)
+	}
+	if withExpiry {
+		return fmt.Sprintf("%s %d %s %s %.2f", leg.Action, leg.Quantity, leg.Option.Expiry, leg.Option.Type, leg.Option.Strike)
+	}
+	return fmt.Sprintf("%s %d %s %.2f", leg.Action, leg.Quantity, leg.Option.Type, leg.Option.Strike)
+}
+
+// recipeFile is the top level of a recipe config file
+type recipeFile struct {
+	Recipes []RecipeSpec `json:"recipes" yaml:"recipes"`
+}
+
+// ReadRecipeFile parses a .yaml, .yml or .json recipe file and validates every recipe in it
+func ReadRecipeFile(path string) ([]RecipeSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file recipeFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	default:
+		return nil, fmt.Errorf("unsupported recipe file format: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	for i := range file.Recipes {
+		spec := &file.Recipes[i]
+		spec.normalize()
+		if err := spec.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return file.Recipes, nil
+}
+
+// LoadRecipes reads every recipe file in dir (or a single file) and makes them available through Recipes.
+// Names must not clash with the built-in recipes or each other.
+func LoadRecipes(path string) ([]RecipeSpec, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, e := range entries {
+			switch strings.ToLower(filepath.Ext(e.Name())) {
+			case ".yaml", ".yml", ".json":
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+		sort.Strings(files)
+	}
+
+	names := make(map[string]bool)
+	for _, spec := range builtinSpecs() {
+		names[spec.Name] = true
+	}
+
+	var specs []RecipeSpec
+	for _, f := range files {
+		loaded, err := ReadRecipeFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range loaded {
+			if names[spec.Name] {
+				return nil, fmt.Errorf("%s: duplicate recipe name %q", f, spec.Name)
+			}
+			names[spec.Name] = true
+			specs = append(specs, spec)
+		}
+	}
+
+	SetCustomRecipes(specs)
+	recipePathMu.Lock()
+	recipePath = path
+	recipePathMu.Unlock()
+
+	log.Printf("Loaded %d custom recipes from %s", len(specs), path)
+	return specs, nil
+}
+
+// ReloadRecipes re-reads the path last passed to LoadRecipes
+func ReloadRecipes() ([]RecipeSpec, error) {
+	recipePathMu.Lock()
+	path := recipePath
+	recipePathMu.Unlock()
+
+	if path == "" {
+		return nil, fmt.Errorf("no recipe path configured")
+	}
+	return LoadRecipes(path)
+}
+
+var (
+	customMu      sync.RWMutex
+	customRecipes []RecipeSpec
+
+	recipePathMu sync.Mutex
+	recipePath   string
+)
+
+// SetCustomRecipes replaces the config-defined recipes
+func SetCustomRecipes(specs []RecipeSpec) {
+	customMu.Lock()
+	defer customMu.Unlock()
+	customRecipes = specs
+}
+
+// CustomRecipes returns the config-defined recipes
+func CustomRecipes() []RecipeSpec {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	return customRecipes
+}
+
+// RecipeInfo describes an available recipe for /api/recipes
+type RecipeInfo struct {
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	Sentiment     string    `json:"sentiment,omitempty"`
+	BackMonthDays int       `json:"backMonthDays,omitempty"`
+	Custom        bool      `json:"custom"`
+	Legs          []LegSpec `json:"legs"`
+}
+
+// ListRecipes describes the built-in recipes followed by the custom ones
+func ListRecipes() []RecipeInfo {
+	builtins := builtinSpecs()
+	var list []RecipeInfo
+	for i, spec := range append(builtins, CustomRecipes()...) {
+		list = append(list, RecipeInfo{
+			Name:          spec.Name,
+			Description:   spec.Description,
+			Sentiment:     spec.Sentiment,
+			BackMonthDays: spec.BackMonthDays,
+			Custom:        i >= len(builtins),
+			Legs:          spec.Legs,
+		})
+	}
+	return list
+}
+
+// ApplySelectors overrides the strike selectors of the named recipes, e.g. from an /api/calculate request.
+// Each override lists selectors by leg; a leg left empty ({}) keeps the recipe's own selector.
+func ApplySelectors(specs []RecipeSpec, overrides map[string][]StrikeSelector) ([]RecipeSpec, error) {
+	if len(overrides) == 0 {
+		return specs, nil
+	}
+
+	byName := make(map[string]int, len(specs))
+	for i, spec := range specs {
+		byName[spec.Name] = i
+	}
+
+	out := make([]RecipeSpec, len(specs))
+	copy(out, specs)
+	for name, selectors := range overrides {
+		i, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown recipe: %s", name)
+		}
+		spec := out[i]
+		if len(selectors) > len(spec.Legs) {
+			return nil, fmt.Errorf("recipe %s has %d legs, got %d selectors", name, len(spec.Legs), len(selectors))
+		}
+
+		spec.Legs = append([]LegSpec(nil), spec.Legs...)
+		for j, sel := range selectors {
+			if sel.By == "" {
+				continue
+			}
+			sel.By = strings.ToLower(sel.By)
+			spec.Legs[j].Strike = sel
+		}
+		if err := spec.Validate(); err != nil {
+			return nil, err
+		}
+		out[i] = spec
+	}
+	return out, nil
+}
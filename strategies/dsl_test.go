@@ -0,0 +1,107 @@
+package strategies
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	call := func(by string, value float64, leg int) LegSpec {
+		return LegSpec{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: by, Value: value, Leg: leg}}
+	}
+
+	tests := []struct {
+		name string
+		spec RecipeSpec
+		err  string // Substring of the expected error; empty for a valid spec
+	}{
+		{"valid vertical", RecipeSpec{Name: "V", Legs: []LegSpec{call(SelectATM, 0, 0), {Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectWidth, Value: 5, Leg: 1}}}}, ""},
+		{"stock needs no selector", RecipeSpec{Name: "S", Legs: []LegSpec{{Action: Buy, Type: LegStock}}}, ""},
+		{"back month with days", RecipeSpec{Name: "C", BackMonthDays: 30, Legs: []LegSpec{call(SelectATM, 0, 0), {Action: Buy, Type: LegCall, Expiry: ExpiryBack, Strike: StrikeSelector{By: SelectLeg, Leg: 1}}}}, ""},
+		{"no name", RecipeSpec{Legs: []LegSpec{call(SelectATM, 0, 0)}}, "no name"},
+		{"no legs", RecipeSpec{Name: "X"}, "no legs"},
+		{"bad action", RecipeSpec{Name: "X", Legs: []LegSpec{{Action: "Hold", Type: LegCall, Strike: StrikeSelector{By: SelectATM}}}}, "action"},
+		{"bad type", RecipeSpec{Name: "X", Legs: []LegSpec{{Action: Buy, Type: "future", Strike: StrikeSelector{By: SelectATM}}}}, "type"},
+		{"back month without days", RecipeSpec{Name: "X", Legs: []LegSpec{{Action: Buy, Type: LegCall, Expiry: ExpiryBack, Strike: StrikeSelector{By: SelectATM}}}}, "backMonthDays"},
+		{"bad expiry", RecipeSpec{Name: "X", Legs: []LegSpec{{Action: Buy, Type: LegCall, Expiry: "weekly", Strike: StrikeSelector{By: SelectATM}}}}, "expiry"},
+		{"otm zero", RecipeSpec{Name: "X", Legs: []LegSpec{call(SelectOTM, 0, 0)}}, "at least 1"},
+		{"delta out of range", RecipeSpec{Name: "X", Legs: []LegSpec{call(SelectDelta, 30, 0)}}, "between 0 and 1"},
+		{"probotm zero", RecipeSpec{Name: "X", Legs: []LegSpec{call(SelectProbOTM, 0, 0)}}, "between 0 and 1"},
+		{"leg refers forward", RecipeSpec{Name: "X", Legs: []LegSpec{call(SelectLeg, 0, 1)}}, "earlier option leg"},
+		{"leg refers to stock", RecipeSpec{Name: "X", Legs: []LegSpec{{Action: Buy, Type: LegStock}, call(SelectWidth, 5, 1)}}, "earlier option leg"},
+		{"zero width", RecipeSpec{Name: "X", Legs: []LegSpec{call(SelectATM, 0, 0), call(SelectWidth, 0, 1)}}, "non-zero"},
+		{"unknown selector", RecipeSpec{Name: "X", Legs: []LegSpec{call("gamma", 1, 0)}}, "unknown strike selector"},
+	}
+
+	for _, tt := range tests {
+		err := tt.spec.Validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.err != "" && err == nil:
+			t.Errorf("%s: expected an error containing %q", tt.name, tt.err)
+		case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+			t.Errorf("%s: error %q does not mention %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestBuiltinSpecsValidate(t *testing.T) {
+	for _, spec := range builtinSpecs() {
+		if err := spec.Validate(); err != nil {
+			t.Errorf("built-in recipe %s: %v", spec.Name, err)
+		}
+	}
+}
+
+func TestReadRecipeFile(t *testing.T) {
+	specs, err := ReadRecipeFile(filepath.Join("..", "data", "recipes", "desk.yaml"))
+	if err != nil {
+		t.Fatalf("shipped recipes: %v", err)
+	}
+	if len(specs) == 0 {
+		t.Error("shipped recipe file has no recipes")
+	}
+
+	// Keywords are case-insensitive in files
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mixed.yaml")
+	yaml := "recipes:\n  - name: Mixed Case\n    legs:\n      - {action: BUY, type: Call, strike: {by: ATM}}\n      - {action: sell, type: CALL, strike: {by: Width, value: 5, leg: 1}}\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	specs, err = ReadRecipeFile(path)
+	if err != nil {
+		t.Fatalf("mixed case: %v", err)
+	}
+	if specs[0].Legs[0].Action != Buy || specs[0].Legs[1].Strike.By != SelectWidth {
+		t.Errorf("keywords not normalized: %+v", specs[0].Legs)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"recipes": [{"name": "Bad", "legs": [{"action": "Buy", "type": "call", "strike": {"by": "delta", "value": 2}}]}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadRecipeFile(bad); err == nil {
+		t.Error("a recipe that fails validation should fail the whole file")
+	}
+
+	if _, err := ReadRecipeFile(filepath.Join(dir, "recipes.txt")); err == nil {
+		t.Error("unsupported extensions should fail")
+	}
+}
+
+func TestLoadRecipesRejectsDuplicateNames(t *testing.T) {
+	defer SetCustomRecipes(CustomRecipes())
+
+	dir := t.TempDir()
+	yaml := "recipes:\n  - name: Long Call\n    legs:\n      - {action: Buy, type: call, strike: {by: atm}}\n"
+	if err := os.WriteFile(filepath.Join(dir, "clash.yaml"), []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRecipes(dir); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected a duplicate name error, got %v", err)
+	}
+}
@@ -2,18 +2,32 @@ package strategies
 
 import (
 	"fmt"
+	"log"
 	"math"
 	"sort"
 	"strikelogic/calculator"
 	"time"
 )
 
+// Structure preferences for GenerateOptions.Prefer
+const (
+	PreferCredit = "credit" // Net credit trades first, for rich premium
+	PreferDebit  = "debit"  // Net debit trades first, for cheap premium
+)
+
+// GenerateOptions are the optional inputs of GenerateAllStrategies; the zero value builds every recipe in order
+type GenerateOptions struct {
+	Specs   []RecipeSpec          // Recipes to build (see ApplySelectors); nil means RecipeSpecs()
+	Surface calculator.VolSurface // Probabilities use its implied distribution rather than a lognormal
+	Prefer  string                // PreferCredit or PreferDebit moves matching structures to the front
+}
+
 // GenerateAllStrategies iterates through the option chain and builds standard trades
-func GenerateAllStrategies(chain []calculator.OptionContract, targetDate string, sentiment string, targetPrice float64) ([]Trade, error) {
+func GenerateAllStrategies(chain []calculator.OptionContract, targetDate string, sentiment string, targetPrice float64, opts GenerateOptions) ([]Trade, error) {
 	// 1. Strict Filter: Ensure we only work with the date closest to targetDate
 	// The incoming chain might contain one or multiple dates depending on how strict the fetch was.
 	// We re-apply the strict "Closest Date" logic to be 100% sure we isolate one single expiry.
-	filteredChain := filterChainByClosestDate(chain, targetDate)
+	filteredChain := FilterChainByClosestDate(chain, targetDate)
 	if len(filteredChain) == 0 {
 		return nil, fmt.Errorf("No contracts found for date %s", targetDate)
 	}
@@ -32,332 +46,333 @@ func GenerateAllStrategies(chain []calculator.OptionContract, targetDate string,
 
 	var trades []Trade
 
-	// Define Recipes
-	// Define Recipes
-	recipes := []StrategyRecipe{
+	specs := opts.Specs
+	if specs == nil {
+		specs = RecipeSpecs()
+	}
+	var recipes []StrategyRecipe
+	for _, spec := range specs {
+		recipes = append(recipes, spec.Recipe(currentPrice))
+	}
+
+	// Filter by sentiment if provided
+	// Normalize sentiment
+	targetSentiment := ""
+	if sentiment != "" {
+		if sentiment == "very_bullish" || sentiment == "bullish" || sentiment == "directional" {
+			targetSentiment = "Bullish"
+		} else if sentiment == "very_bearish" || sentiment == "bearish" {
+			targetSentiment = "Bearish"
+		} else if sentiment == "neutral" {
+			targetSentiment = "Neutral"
+		}
+	}
+
+	// Multi-expiry recipes also need their back months, which a single-expiry fetch won't include
+	chain, recipes = withBackMonths(chain, ticker, filteredChain[0].Expiry, recipes)
+
+	for _, recipe := range recipes {
+		trade := recipe.Builder(ChainForRecipe(recipe, chain, targetDate), targetPrice)
+		if trade != nil {
+			// Filter logic
+			if targetSentiment != "" && trade.Sentiment != targetSentiment {
+				continue
+			}
+
+			trade.CalculatePayoff(currentPrice)
+			if dist, ok := trade.ExpiryDistribution(currentPrice, opts.Surface); ok {
+				trade.CalculateProbabilities(dist)
+			}
+
+			// Calculate Expiry Label
+			expiryDate, _ := time.Parse("2006-01-02", trade.ExpirationDate)
+			daysToExpiry := math.Ceil(expiryDate.Sub(time.Now()).Hours() / 24)
+			trade.ExpiryLabel = fmt.Sprintf("%s (%.0fd)", expiryDate.Format("Jan 02"), daysToExpiry)
+
+			trades = append(trades, *trade)
+		}
+	}
+
+	if opts.Prefer == PreferCredit || opts.Prefer == PreferDebit {
+		preferred := func(t Trade) bool {
+			if opts.Prefer == PreferCredit {
+				return t.NetDebit < 0
+			}
+			return t.NetDebit > 0
+		}
+		sort.SliceStable(trades, func(i, j int) bool { return preferred(trades[i]) && !preferred(trades[j]) })
+	}
+
+	return trades, nil
+}
+
+// Recipes returns the standard strategy recipes followed by any loaded from config (see LoadRecipes).
+// Strikes are chosen relative to currentPrice, so the recipes must be rebuilt whenever the underlying price changes.
+func Recipes(currentPrice float64) []StrategyRecipe {
+	var recipes []StrategyRecipe
+	for _, spec := range RecipeSpecs() {
+		recipes = append(recipes, spec.Recipe(currentPrice))
+	}
+	return recipes
+}
+
+// RecipeSpecs returns the built-in recipe specs followed by the custom ones
+func RecipeSpecs() []RecipeSpec {
+	return append(builtinSpecs(), CustomRecipes()...)
+}
+
+// builtinSpecs are the standard recipes. Their default strikes step through the chain by index;
+// callers can swap in delta, probability or width selectors per leg (see WithSelectors).
+func builtinSpecs() []RecipeSpec {
+	return []RecipeSpec{
 		{
 			Name:        "Long Call",
 			Description: "Buy 1 Call (Strike = Target Price)",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				opt := findClosest(c, tp, calculator.Call)
-				if opt == nil {
-					return nil
-				}
-				return &Trade{
-					Name:        "Long Call",
-					Description: fmt.Sprintf("Buy 1 Call at Strike %.2f", opt.Strike),
-					Sentiment:   "Bullish",
-					Legs: []TradeLeg{
-						{Action: Buy, Quantity: 1, Option: *opt},
-					},
-				}
+			Sentiment:   "Bullish",
+			Legs: []LegSpec{
+				{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: SelectTarget}},
 			},
 		},
 		{
 			Name:        "Long Put",
 			Description: "Buy 1 Put (Strike = Target Price)",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				opt := findClosest(c, tp, calculator.Put)
-				if opt == nil {
-					return nil
-				}
-				return &Trade{
-					Name:        "Long Put",
-					Description: fmt.Sprintf("Buy 1 Put at Strike %.2f", opt.Strike),
-					Sentiment:   "Bearish",
-					Legs: []TradeLeg{
-						{Action: Buy, Quantity: 1, Option: *opt},
-					},
-				}
+			Sentiment:   "Bearish",
+			Legs: []LegSpec{
+				{Action: Buy, Type: LegPut, Strike: StrikeSelector{By: SelectTarget}},
 			},
 		},
 		{
 			Name:        "Covered Call",
 			Description: "Buy 100 Shares + Sell 1 OTM Call",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				call := findOTM(c, currentPrice, calculator.Call, 1)
-				if call == nil {
-					return nil
-				}
-				return &Trade{
-					Name:        "Covered Call",
-					Description: fmt.Sprintf("Buy 100 Shares + Sell 1 Call at Strike %.2f", call.Strike),
-					Sentiment:   "Bullish",
-					Legs: []TradeLeg{
-						{Action: Buy, Quantity: 100, IsStock: true, StockPrice: currentPrice},
-						{Action: Sell, Quantity: 1, Option: *call},
-					},
-				}
+			Sentiment:   "Bullish",
+			Legs: []LegSpec{
+				{Action: Buy, Type: LegStock, Quantity: 100},
+				{Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
 			},
 		},
 		{
 			Name:        "Cash-Secured Put",
 			Description: "Sell 1 OTM Put",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				put := findOTM(c, currentPrice, calculator.Put, 1)
-				if put == nil {
-					return nil
-				}
-				return &Trade{
-					Name:        "Cash-Secured Put",
-					Description: fmt.Sprintf("Sell 1 Put at Strike %.2f", put.Strike),
-					Sentiment:   "Bullish",
-					Legs: []TradeLeg{
-						{Action: Sell, Quantity: 1, Option: *put},
-					},
-				}
+			Sentiment:   "Bullish",
+			Legs: []LegSpec{
+				{Action: Sell, Type: LegPut, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
 			},
 		},
 		{
 			Name:        "Bull Call Spread",
 			Description: "Buy ITM Call + Sell OTM Call",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				buyLeg := findITM(c, currentPrice, calculator.Call, 1)
-				sellLeg := findOTM(c, currentPrice, calculator.Call, 1)
-				if buyLeg == nil || sellLeg == nil {
-					return nil
-				}
-				return &Trade{
-					Name:        "Bull Call Spread",
-					Description: fmt.Sprintf("Buy Call %.2f, Sell Call %.2f", buyLeg.Strike, sellLeg.Strike),
-					Sentiment:   "Bullish",
-					Legs: []TradeLeg{
-						{Action: Buy, Quantity: 1, Option: *buyLeg},
-						{Action: Sell, Quantity: 1, Option: *sellLeg},
-					},
-				}
+			Sentiment:   "Bullish",
+			Legs: []LegSpec{
+				{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: SelectITM, Value: 1}},
+				{Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
 			},
 		},
 		{
 			Name:        "Bull Put Spread",
 			Description: "Buy OTM Put + Sell Higher Strike Put",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				sellLeg := findOTM(c, currentPrice, calculator.Put, 1)
-				buyLeg := findOTM(c, currentPrice, calculator.Put, 3)
-				if sellLeg == nil || buyLeg == nil {
-					return nil
-				}
-				return &Trade{
-					Name:        "Bull Put Spread",
-					Description: fmt.Sprintf("Sell Put %.2f, Buy Put %.2f", sellLeg.Strike, buyLeg.Strike),
-					Sentiment:   "Bullish",
-					Legs: []TradeLeg{
-						{Action: Sell, Quantity: 1, Option: *sellLeg},
-						{Action: Buy, Quantity: 1, Option: *buyLeg},
-					},
-				}
+			Sentiment:   "Bullish",
+			Legs: []LegSpec{
+				{Action: Sell, Type: LegPut, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
+				{Action: Buy, Type: LegPut, Strike: StrikeSelector{By: SelectOTM, Value: 3}},
 			},
 		},
 		{
 			Name:        "Bear Call Spread",
 			Description: "Sell ITM Call + Buy OTM Call",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				sellLeg := findITM(c, currentPrice, calculator.Call, 1)
-				buyLeg := findOTM(c, currentPrice, calculator.Call, 1)
-				if sellLeg == nil || buyLeg == nil {
-					return nil
-				}
-				return &Trade{
-					Name:        "Bear Call Spread",
-					Description: fmt.Sprintf("Sell Call %.2f, Buy Call %.2f", sellLeg.Strike, buyLeg.Strike),
-					Sentiment:   "Bearish",
-					Legs: []TradeLeg{
-						{Action: Sell, Quantity: 1, Option: *sellLeg},
-						{Action: Buy, Quantity: 1, Option: *buyLeg},
-					},
-				}
+			Sentiment:   "Bearish",
+			Legs: []LegSpec{
+				{Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectITM, Value: 1}},
+				{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
 			},
 		},
 		{
 			Name:        "Bear Put Spread",
 			Description: "Buy ITM Put + Sell OTM Put",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				buyLeg := findITM(c, currentPrice, calculator.Put, 1)
-				sellLeg := findOTM(c, currentPrice, calculator.Put, 1)
-				if buyLeg == nil || sellLeg == nil {
-					return nil
-				}
-				return &Trade{
-					Name:        "Bear Put Spread",
-					Description: fmt.Sprintf("Buy Put %.2f, Sell Put %.2f", buyLeg.Strike, sellLeg.Strike),
-					Sentiment:   "Bearish",
-					Legs: []TradeLeg{
-						{Action: Buy, Quantity: 1, Option: *buyLeg},
-						{Action: Sell, Quantity: 1, Option: *sellLeg},
-					},
-				}
+			Sentiment:   "Bearish",
+			Legs: []LegSpec{
+				{Action: Buy, Type: LegPut, Strike: StrikeSelector{By: SelectITM, Value: 1}},
+				{Action: Sell, Type: LegPut, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
 			},
 		},
 		{
 			Name:        "Straddle",
 			Description: "Buy ATM Call + Buy ATM Put (Same Strike)",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				call := findClosest(c, currentPrice, calculator.Call)
-				put := findClosest(c, currentPrice, calculator.Put)
-				if call == nil || put == nil {
-					return nil
-				}
-				if call.Strike != put.Strike {
-					put = findClosest(c, call.Strike, calculator.Put)
-				}
-				if put == nil || call.Strike != put.Strike {
-					return nil
-				}
-
-				return &Trade{
-					Name:        "Straddle",
-					Description: fmt.Sprintf("Buy Call & Put at Strike %.2f", call.Strike),
-					Sentiment:   "Neutral",
-					Legs: []TradeLeg{
-						{Action: Buy, Quantity: 1, Option: *call},
-						{Action: Buy, Quantity: 1, Option: *put},
-					},
-				}
+			Sentiment:   "Neutral",
+			Legs: []LegSpec{
+				{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: SelectATM}},
+				{Action: Buy, Type: LegPut, Strike: StrikeSelector{By: SelectLeg, Leg: 1}},
 			},
 		},
 		{
 			Name:        "Strangle",
 			Description: "Buy OTM Call + Buy OTM Put (Different Strikes)",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				call := findOTM(c, currentPrice, calculator.Call, 1)
-				put := findOTM(c, currentPrice, calculator.Put, 1)
-				if call == nil || put == nil {
-					return nil
-				}
-				return &Trade{
-					Name:        "Strangle",
-					Description: fmt.Sprintf("Buy Call %.2f, Buy Put %.2f", call.Strike, put.Strike),
-					Sentiment:   "Neutral",
-					Legs: []TradeLeg{
-						{Action: Buy, Quantity: 1, Option: *call},
-						{Action: Buy, Quantity: 1, Option: *put},
-					},
-				}
+			Sentiment:   "Neutral",
+			Legs: []LegSpec{
+				{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
+				{Action: Buy, Type: LegPut, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
 			},
 		},
 		{
 			Name:        "Iron Condor",
 			Description: "Sell OTM Put + Buy Further OTM Put + Sell OTM Call + Buy Further OTM Call",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				sellPut := findOTM(c, currentPrice, calculator.Put, 1)
-				buyPut := findOTM(c, currentPrice, calculator.Put, 3)
-				sellCall := findOTM(c, currentPrice, calculator.Call, 1)
-				buyCall := findOTM(c, currentPrice, calculator.Call, 3)
-
-				if sellPut == nil || buyPut == nil || sellCall == nil || buyCall == nil {
-					return nil
-				}
-
-				return &Trade{
-					Name:        "Iron Condor",
-					Description: fmt.Sprintf("Sell Put %.2f/Call %.2f, Buy Put %.2f/Call %.2f", sellPut.Strike, sellCall.Strike, buyPut.Strike, buyCall.Strike),
-					Sentiment:   "Neutral",
-					Legs: []TradeLeg{
-						{Action: Sell, Quantity: 1, Option: *sellPut},
-						{Action: Buy, Quantity: 1, Option: *buyPut},
-						{Action: Sell, Quantity: 1, Option: *sellCall},
-						{Action: Buy, Quantity: 1, Option: *buyCall},
-					},
-				}
+			Sentiment:   "Neutral",
+			Legs: []LegSpec{
+				{Action: Sell, Type: LegPut, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
+				{Action: Buy, Type: LegPut, Strike: StrikeSelector{By: SelectOTM, Value: 3}},
+				{Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
+				{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 3}},
 			},
 		},
 		{
 			Name:        "Iron Butterfly",
-			Description: "Sell ATM Put + Sell ATM Call + Buy OTM Put + Buy OTM Call",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				atmCall := findClosest(c, currentPrice, calculator.Call)
-				atmPut := findClosest(c, currentPrice, calculator.Put)
-				otmCall := findOTM(c, currentPrice, calculator.Call, 2)
-				otmPut := findOTM(c, currentPrice, calculator.Put, 2)
-
-				if atmCall == nil || atmPut == nil || otmCall == nil || otmPut == nil {
-					return nil
-				}
-				if atmCall.Strike != atmPut.Strike {
-					atmPut = findClosest(c, atmCall.Strike, calculator.Put)
-				}
-				if atmPut == nil {
-					return nil
-				}
-
-				return &Trade{
-					Name:        "Iron Butterfly",
-					Description: fmt.Sprintf("Sell ATM %.2f, Buy OTM Call %.2f/Put %.2f", atmCall.Strike, otmCall.Strike, otmPut.Strike),
-					Sentiment:   "Neutral",
-					Legs: []TradeLeg{
-						{Action: Sell, Quantity: 1, Option: *atmPut},
-						{Action: Sell, Quantity: 1, Option: *atmCall},
-						{Action: Buy, Quantity: 1, Option: *otmPut},
-						{Action: Buy, Quantity: 1, Option: *otmCall},
-					},
-				}
+			Description: "Sell ATM Call + Sell ATM Put + Buy OTM Put + Buy OTM Call",
+			Sentiment:   "Neutral",
+			Legs: []LegSpec{
+				{Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectATM}},
+				{Action: Sell, Type: LegPut, Strike: StrikeSelector{By: SelectLeg, Leg: 1}},
+				{Action: Buy, Type: LegPut, Strike: StrikeSelector{By: SelectOTM, Value: 2}},
+				{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 2}},
 			},
 		},
 		{
 			Name:        "Call Broken Wing Butterfly",
 			Description: "Buy 1 ITM Call + Sell 2 ATM Calls + Buy 1 OTM Call (Strikes are NOT equidistant)",
-			Builder: func(c []calculator.OptionContract, tp float64) *Trade {
-				itm := findITM(c, currentPrice, calculator.Call, 1)
-				atm := findClosest(c, currentPrice, calculator.Call)
-				otm := findOTM(c, currentPrice, calculator.Call, 2)
-
-				if itm == nil || atm == nil || otm == nil {
-					return nil
-				}
-
-				return &Trade{
-					Name:        "Call Broken Wing Butterfly",
-					Description: fmt.Sprintf("Buy %.2f, Sell 2x %.2f, Buy %.2f", itm.Strike, atm.Strike, otm.Strike),
-					Sentiment:   "Bullish",
-					Legs: []TradeLeg{
-						{Action: Buy, Quantity: 1, Option: *itm},
-						{Action: Sell, Quantity: 2, Option: *atm},
-						{Action: Buy, Quantity: 1, Option: *otm},
-					},
-				}
+			Sentiment:   "Bullish",
+			Legs: []LegSpec{
+				{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: SelectITM, Value: 1}},
+				{Action: Sell, Type: LegCall, Quantity: 2, Strike: StrikeSelector{By: SelectATM}},
+				{Action: Buy, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 2}},
+			},
+		},
+		{
+			Name:          "Call Calendar",
+			Description:   "Sell Front-Month Call + Buy Back-Month Call (Same Strike = Target Price)",
+			Sentiment:     "Neutral",
+			BackMonthDays: 30,
+			Legs: []LegSpec{
+				{Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectTarget}},
+				{Action: Buy, Type: LegCall, Expiry: ExpiryBack, Strike: StrikeSelector{By: SelectLeg, Leg: 1}},
+			},
+		},
+		{
+			Name:          "Call Diagonal",
+			Description:   "Sell Front-Month OTM Call + Buy Back-Month ITM Call",
+			Sentiment:     "Bullish",
+			BackMonthDays: 30,
+			Legs: []LegSpec{
+				{Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
+				{Action: Buy, Type: LegCall, Expiry: ExpiryBack, Strike: StrikeSelector{By: SelectITM, Value: 1}},
+			},
+		},
+		{
+			Name:          "Double Calendar",
+			Description:   "Put Calendar Below + Call Calendar Above the Current Price",
+			Sentiment:     "Neutral",
+			BackMonthDays: 30,
+			Legs: []LegSpec{
+				{Action: Sell, Type: LegPut, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
+				{Action: Buy, Type: LegPut, Expiry: ExpiryBack, Strike: StrikeSelector{By: SelectLeg, Leg: 1}},
+				{Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectOTM, Value: 1}},
+				{Action: Buy, Type: LegCall, Expiry: ExpiryBack, Strike: StrikeSelector{By: SelectLeg, Leg: 3}},
+			},
+		},
+		{
+			Name:          "Poor Man's Covered Call",
+			Description:   "Buy Deep ITM LEAPS Call (~0.80 Delta) + Sell Front-Month OTM Call (~0.30 Delta)",
+			Sentiment:     "Bullish",
+			BackMonthDays: 180,
+			Legs: []LegSpec{
+				{Action: Buy, Type: LegCall, Expiry: ExpiryBack, Strike: StrikeSelector{By: SelectDelta, Value: 0.80}},
+				{Action: Sell, Type: LegCall, Strike: StrikeSelector{By: SelectDelta, Value: 0.30}},
 			},
 		},
 	}
+}
 
-	// Filter by sentiment if provided
-	// Normalize sentiment
-	targetSentiment := ""
-	if sentiment != "" {
-		if sentiment == "very_bullish" || sentiment == "bullish" || sentiment == "directional" {
-			targetSentiment = "Bullish"
-		} else if sentiment == "very_bearish" || sentiment == "bearish" {
-			targetSentiment = "Bearish"
-		} else if sentiment == "neutral" {
-			targetSentiment = "Neutral"
+// ChainForRecipe selects the contracts a recipe builds from: the expiry closest to targetDate and,
+// for multi-expiry recipes, the later expiry closest to BackMonthDays after it.
+func ChainForRecipe(recipe StrategyRecipe, chain []calculator.OptionContract, targetDate string) []calculator.OptionContract {
+	front := FilterChainByClosestDate(chain, targetDate)
+	if recipe.BackMonthDays == 0 || len(front) == 0 {
+		return front
+	}
+
+	frontDate, err := time.Parse("2006-01-02", front[0].Expiry)
+	if err != nil {
+		return front
+	}
+
+	var later []calculator.OptionContract
+	for _, opt := range chain {
+		if opt.Expiry > front[0].Expiry {
+			later = append(later, opt)
 		}
 	}
+	back := FilterChainByClosestDate(later, frontDate.AddDate(0, 0, recipe.BackMonthDays).Format("2006-01-02"))
 
-	for _, recipe := range recipes {
-		trade := recipe.Builder(filteredChain, targetPrice)
-		if trade != nil {
-			// Filter logic
-			if targetSentiment != "" && trade.Sentiment != targetSentiment {
-				continue
-			}
+	return append(append([]calculator.OptionContract{}, front...), back...)
+}
 
-			trade.CalculateMetrics(currentPrice)
-			trade.ExpirationDate = filteredChain[0].Expiry
+// withBackMonths fetches the back-month expiries multi-expiry recipes need when chain doesn't already
+// hold one within half the recipe's BackMonthDays of its target. Back months come from the configured
+// provider only, never mock data; recipes whose back month can't be fetched are dropped from the returned list.
+func withBackMonths(chain []calculator.OptionContract, ticker, frontExpiry string, recipes []StrategyRecipe) ([]calculator.OptionContract, []StrategyRecipe) {
+	frontDate, err := time.Parse("2006-01-02", frontExpiry)
+	if err != nil {
+		return chain, recipes
+	}
+	provider, _ := calculator.Providers()
 
-			// Calculate Expiry Label
-			expiryDate, _ := time.Parse("2006-01-02", trade.ExpirationDate)
-			daysToExpiry := math.Ceil(expiryDate.Sub(time.Now()).Hours() / 24)
-			trade.ExpiryLabel = fmt.Sprintf("%s (%.0fd)", expiryDate.Format("Jan 02"), daysToExpiry)
+	have := make(map[string]bool)
+	for _, opt := range chain {
+		have[opt.Expiry] = true
+	}
 
-			trades = append(trades, *trade)
+	var usable []StrategyRecipe
+	for _, recipe := range recipes {
+		if recipe.BackMonthDays == 0 {
+			usable = append(usable, recipe)
+			continue
+		}
+		target := frontDate.AddDate(0, 0, recipe.BackMonthDays)
+
+		covered := false
+		for expiry := range have {
+			t, err := time.Parse("2006-01-02", expiry)
+			if err == nil && t.After(frontDate) && math.Abs(t.Sub(target).Hours()/24) <= float64(recipe.BackMonthDays)/2 {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			usable = append(usable, recipe)
+			continue
 		}
-	}
 
-	return trades, nil
+		back, err := calculator.GetOptionsChainFrom(provider, ticker, target.Format("2006-01-02"))
+		if err != nil {
+			log.Printf("Skipping %s: no back month %s for %s: %v", recipe.Name, target.Format("2006-01-02"), ticker, err)
+			continue
+		}
+		usable = append(usable, recipe)
+		added := make(map[string]bool)
+		for _, opt := range back {
+			if !have[opt.Expiry] {
+				chain = append(chain, opt)
+				added[opt.Expiry] = true
+			}
+		}
+		for expiry := range added {
+			have[expiry] = true
+		}
+	}
+	return chain, usable
 }
 
 // Helper functions
 
-func filterChainByClosestDate(chain []calculator.OptionContract, targetDateStr string) []calculator.OptionContract {
+// FilterChainByClosestDate keeps only the contracts of the single expiry closest to targetDateStr
+func FilterChainByClosestDate(chain []calculator.OptionContract, targetDateStr string) []calculator.OptionContract {
 	// 1. Parse target date
 	targetDate, err := time.Parse("2006-01-02", targetDateStr)
 	if err != nil {
@@ -433,6 +448,51 @@ func findClosest(chain []calculator.OptionContract, targetStrike float64, optTyp
 	return best
 }
 
+// findStrike returns the contract at exactly strike, or nil
+func findStrike(chain []calculator.OptionContract, strike float64, optType calculator.OptionType) *calculator.OptionContract {
+	opt := findClosest(chain, strike, optType)
+	if opt == nil || opt.Strike != strike {
+		return nil
+	}
+	return opt
+}
+
+// findDelta returns the contract whose |delta| is closest to target
+func findDelta(chain []calculator.OptionContract, target float64, optType calculator.OptionType) *calculator.OptionContract {
+	var best *calculator.OptionContract
+	minDiff := math.MaxFloat64
+
+	for i := range chain {
+		if chain[i].Type != optType {
+			continue
+		}
+		diff := math.Abs(math.Abs(chain[i].Delta) - target)
+		if diff < minDiff {
+			minDiff = diff
+			best = &chain[i]
+		}
+	}
+	return best
+}
+
+// splitExpiries separates a two-expiry chain (see ChainForRecipe) into its front and back months
+func splitExpiries(chain []calculator.OptionContract) (front, back []calculator.OptionContract) {
+	first := ""
+	for _, opt := range chain {
+		if first == "" || opt.Expiry < first {
+			first = opt.Expiry
+		}
+	}
+	for _, opt := range chain {
+		if opt.Expiry == first {
+			front = append(front, opt)
+		} else {
+			back = append(back, opt)
+		}
+	}
+	return front, back
+}
+
 func findOTM(chain []calculator.OptionContract, currentPrice float64, optType calculator.OptionType, steps int) *calculator.OptionContract {
 	// Sort chain by strike
 	sorted := make([]calculator.OptionContract, len(chain))
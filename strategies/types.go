@@ -30,6 +30,12 @@ type Trade struct {
 	MaxRisk    float64   `json:"maxRisk"` // Positive number representing max loss
 	BreakEvens []float64 `json:"breakEvens"`
 
+	// The loss is still growing at the top of the price scan (e.g. a naked short call), so MaxRisk is only
+	// the loss at 3x the current price
+	UndefinedRisk bool `json:"undefinedRisk"`
+	// Likewise the profit is still growing (e.g. a long call), so MaxProfit is only the profit at 3x
+	UnlimitedProfit bool `json:"unlimitedProfit"`
+
 	// Greeks (Portfolio)
 	Delta float64 `json:"delta"`
 	Gamma float64 `json:"gamma"`
@@ -39,7 +45,18 @@ type Trade struct {
 	// Cost
 	NetDebit float64 `json:"netDebit"` // Positive for debit, negative for credit
 
-	ExpirationDate string `json:"expirationDate"`
+	// Probability and return, measured at the front expiry
+	ROI           float64 `json:"roi"`           // Percent: MaxProfit / MaxRisk
+	PoP           float64 `json:"pop"`           // Percent chance of finishing with a profit
+	EV            float64 `json:"ev"`            // Expected P&L in dollars
+	ProbMaxProfit float64 `json:"probMaxProfit"` // Percent chance of reaching MaxProfit
+	ProbMaxLoss   float64 `json:"probMaxLoss"`   // Percent chance of losing MaxRisk
+
+	// Set by the strategist's risk-profile ideas
+	RiskProfile string  `json:"riskProfile,omitempty"`
+	DegenScore  float64 `json:"degenScore,omitempty"`
+
+	ExpirationDate string `json:"expirationDate"` // Front (earliest) leg expiry
 	ExpiryLabel    string `json:"expiryLabel"`
 }
 
@@ -49,4 +66,7 @@ type StrategyRecipe struct {
 	Description string
 	// Builder is a function that attempts to construct the trade from the chain
 	Builder func(chain []calculator.OptionContract, targetPrice float64) *Trade
+	// BackMonthDays marks a multi-expiry recipe: Builder receives the front expiry plus the expiry
+	// closest to this many days after it (see ChainForRecipe). Zero means a single expiry.
+	BackMonthDays int
 }
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"math"
 	"strikelogic/calculator"
+	"strikelogic/rates"
+	"strikelogic/strategies"
+	"strings"
 	"time"
 )
 
@@ -15,21 +18,21 @@ const (
 	Degen  RiskProfile = "Degen (The Moonshot)"
 )
 
-type TradeLeg struct {
-	Action string                    `json:"action"` // "Buy" or "Sell"
-	Option calculator.OptionContract `json:"option"`
+// ParseRiskProfile accepts "low", "medium" or "degen" (or a full profile name), case-insensitively
+func ParseRiskProfile(s string) (RiskProfile, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low", strings.ToLower(string(Low)):
+		return Low, nil
+	case "medium", strings.ToLower(string(Medium)):
+		return Medium, nil
+	case "degen", "high", strings.ToLower(string(Degen)):
+		return Degen, nil
+	}
+	return "", fmt.Errorf("unknown risk profile: %s", s)
 }
 
-type Trade struct {
-	RiskProfile RiskProfile `json:"riskProfile"`
-	Description string      `json:"description"`
-	Legs        []TradeLeg  `json:"legs"`
-	NetDebit    float64     `json:"netDebit"`
-	MaxProfit   string      `json:"maxProfit"` // String for flexibility (e.g. "Unlimited")
-	ROI         string      `json:"roi"`       // Estimated ROI
-	DegenScore  float64     `json:"degenScore"`
-	PoP         string      `json:"pop"` // Probability of Profit
-}
+// MaxLongDTE is the furthest expiry FindTrades fetches for its long legs (the 180 day LEAPS plus room to find it)
+const MaxLongDTE = 240
 
 // CalculateDegenScore calculates the leverage intensity (1-10)
 func CalculateDegenScore(contract calculator.OptionContract, stockPrice float64) float64 {
@@ -52,12 +55,30 @@ func CalculateDegenScore(contract calculator.OptionContract, stockPrice float64)
 	return math.Round(score*10) / 10
 }
 
-// FindTrades generates 3 distinct trade ideas
-func FindTrades(ticker string, currentPrice, targetPrice float64, targetDateStr string) ([]Trade, error) {
-	var trades []Trade
+// FindTrades generates 3 distinct trade ideas, tagged with their RiskProfile.
+// The Moonshot's ROI is the return at the target price and date rather than the best case.
+func FindTrades(ticker string, currentPrice, targetPrice float64, targetDateStr string) ([]strategies.Trade, error) {
+	var trades []strategies.Trade
+
+	targetDate, err := time.Parse("2006-01-02", targetDateStr)
+	if err != nil {
+		targetDate = time.Now().AddDate(0, 1, 0)
+	}
+
+	daysToTarget := targetDate.Sub(time.Now()).Hours() / 24
+	if daysToTarget < 1 {
+		daysToTarget = 1
+	}
+
+	// Look at options expiring shortly after target date
+	daysOut := int(daysToTarget) + 7
 
-	// Fetch full option chain (Real data with fallback)
-	fullChain, err := calculator.GetOptionsChain(ticker, targetDateStr)
+	// Fetch every expiration the recipes below can use (Real data with fallback)
+	maxDTE := MaxLongDTE
+	if daysOut+30 > maxDTE {
+		maxDTE = daysOut + 30
+	}
+	fullChain, err := calculator.GetFullChain(ticker, 0, maxDTE)
 	if err != nil {
 		return nil, err
 	}
@@ -72,27 +93,20 @@ func FindTrades(ticker string, currentPrice, targetPrice float64, targetDateStr
 	shortLeg := findClosestDelta(shortChain, 0.30)
 
 	if longLeg != nil && shortLeg != nil {
-		netDebit := longLeg.Ask - shortLeg.Bid
-		// Approx BreakEven for PMCC: Long Strike + Net Debit
-		breakEven := longLeg.Strike + netDebit
-		// Use Long Leg expiry for PoP time horizon? Or Short? Usually the trade is managed short term.
-		// Let's use the Short Leg expiry for "Probability of Profit on this campaign"
-		daysOut := 30.0
-		pop := calculator.CalculatePoP(breakEven, currentPrice, daysOut/365.0, longLeg.Vol)
-
-		trades = append(trades, Trade{
-			RiskProfile: Low,
+		// Metrics are measured at the short leg's expiry: "Probability of Profit on this campaign"
+		trade := strategies.Trade{
+			Name:        "Poor Man's Covered Call",
 			Description: "Poor Man's Covered Call (PMCC). Buy deep ITM LEAPS and sell monthly calls against it for income.",
-			Legs: []TradeLeg{
-				{Action: "Buy", Option: *longLeg},
-				{Action: "Sell", Option: *shortLeg},
+			Sentiment:   "Bullish",
+			RiskProfile: string(Low),
+			Legs: []strategies.TradeLeg{
+				{Action: strategies.Buy, Quantity: 1, Option: *longLeg},
+				{Action: strategies.Sell, Quantity: 1, Option: *shortLeg},
 			},
-			NetDebit:   math.Round(netDebit*100) / 100,
-			MaxProfit:  "Variable (Income Generation)",
-			ROI:        "~15-25% annualized",
 			DegenScore: CalculateDegenScore(*longLeg, currentPrice),
-			PoP:        fmt.Sprintf("%.1f%%", pop*100),
-		})
+		}
+		trade.CalculateMetrics(currentPrice)
+		trades = append(trades, trade)
 	}
 
 	// 2. Medium Risk: PMCC (The Strategist)
@@ -105,45 +119,33 @@ func FindTrades(ticker string, currentPrice, targetPrice float64, targetDateStr
 	shortLegMed := findClosestDelta(shortChainMed, 0.40)
 
 	if longLegMed != nil && shortLegMed != nil {
-		netDebit := longLegMed.Ask - shortLegMed.Bid
-		breakEven := longLegMed.Strike + netDebit
-		daysOut := 14.0
-		pop := calculator.CalculatePoP(breakEven, currentPrice, daysOut/365.0, longLegMed.Vol)
-
-		trades = append(trades, Trade{
-			RiskProfile: Medium,
+		trade := strategies.Trade{
+			Name:        "Aggressive PMCC",
 			Description: "Aggressive PMCC. Higher delta short call for more premium, but capped upside.",
-			Legs: []TradeLeg{
-				{Action: "Buy", Option: *longLegMed},
-				{Action: "Sell", Option: *shortLegMed},
+			Sentiment:   "Bullish",
+			RiskProfile: string(Medium),
+			Legs: []strategies.TradeLeg{
+				{Action: strategies.Buy, Quantity: 1, Option: *longLegMed},
+				{Action: strategies.Sell, Quantity: 1, Option: *shortLegMed},
 			},
-			NetDebit:   math.Round(netDebit*100) / 100,
-			MaxProfit:  "Capped at Short Strike",
-			ROI:        "~30-50% annualized",
 			DegenScore: CalculateDegenScore(*longLegMed, currentPrice),
-			PoP:        fmt.Sprintf("%.1f%%", pop*100),
-		})
+		}
+		trade.CalculateMetrics(currentPrice)
+		trades = append(trades, trade)
 	}
 
 	// 3. Degen: Naked Call (The Moonshot)
 	// Find highest ROI if price hits targetPrice by targetDate
-	targetDate, err := time.Parse("2006-01-02", targetDateStr)
-	if err != nil {
-		targetDate = time.Now().AddDate(0, 1, 0)
-	}
-
-	daysToTarget := targetDate.Sub(time.Now()).Hours() / 24
-	if daysToTarget < 1 {
-		daysToTarget = 1
-	}
-
-	// Look at options expiring shortly after target date
-	daysOut := int(daysToTarget) + 7
 	degenChain := filterChainByDays(fullChain, daysOut)
 
 	var bestOption *calculator.OptionContract
 	maxROI := -1.0
 
+	// Value at the target date, so only dividends paid after it still matter
+	model := calculator.DefaultModel()
+	yield, schedule := calculator.LookupDividends(ticker)
+	q, dividends := calculator.DividendInputs(yield, schedule, targetDate)
+
 	for i := range degenChain {
 		opt := &degenChain[i]
 		cost := opt.Ask
@@ -151,13 +153,26 @@ func FindTrades(ticker string, currentPrice, targetPrice float64, targetDateStr
 			continue
 		}
 
-		// Calculate theoretical price at target
-		timeRemaining := float64(daysOut-int(daysToTarget)) / 365.0
+		// Calculate theoretical price at target, with the time left on the option's actual expiry
+		expiry, err := time.Parse("2006-01-02", opt.Expiry)
+		if err != nil {
+			continue
+		}
+		timeRemaining := expiry.Sub(targetDate).Hours() / 24 / 365.0
 		if timeRemaining < 0 {
 			timeRemaining = 0
 		}
 
-		theoPrice, _, _, _ := calculator.BlackScholes(targetPrice, opt.Strike, timeRemaining, 0.05, opt.Vol)
+		theoPrice := model.Price(calculator.PricingInput{
+			Type:      opt.Type,
+			S:         targetPrice,
+			K:         opt.Strike,
+			T:         timeRemaining,
+			R:         rates.RateFor(timeRemaining),
+			Sigma:     opt.Vol,
+			Q:         q,
+			Dividends: dividends,
+		}).Price
 
 		profit := theoPrice - cost
 		roi := profit / cost
@@ -169,21 +184,20 @@ func FindTrades(ticker string, currentPrice, targetPrice float64, targetDateStr
 	}
 
 	if bestOption != nil {
-		breakEven := bestOption.Strike + bestOption.Ask
-		pop := calculator.CalculatePoP(breakEven, currentPrice, float64(daysOut)/365.0, bestOption.Vol)
-
-		trades = append(trades, Trade{
-			RiskProfile: Degen,
+		trade := strategies.Trade{
+			Name:        "Naked Call",
 			Description: "Naked Call. Highest theoretical ROI if target hit.",
-			Legs: []TradeLeg{
-				{Action: "Buy", Option: *bestOption},
+			Sentiment:   "Bullish",
+			RiskProfile: string(Degen),
+			Legs: []strategies.TradeLeg{
+				{Action: strategies.Buy, Quantity: 1, Option: *bestOption},
 			},
-			NetDebit:   bestOption.Ask,
-			MaxProfit:  "Unlimited",
-			ROI:        fmt.Sprintf("%.0f%%", maxROI*100),
 			DegenScore: CalculateDegenScore(*bestOption, currentPrice),
-			PoP:        fmt.Sprintf("%.1f%%", pop*100),
-		})
+		}
+		trade.CalculateMetrics(currentPrice)
+		// The Moonshot is judged on the target scenario, not the best case
+		trade.ROI = math.Round(maxROI * 100)
+		trades = append(trades, trade)
 	}
 
 	return trades, nil
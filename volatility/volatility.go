@@ -0,0 +1,337 @@
+package volatility
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strikelogic/calculator"
+	"strikelogic/storage"
+	"strikelogic/strategies"
+	"time"
+)
+
+// Settings for the IV and HV metrics
+const (
+	IVTenorDays      = 30  // Constant maturity of the stored ATM IV
+	TradingDays      = 252 // Annualization factor for daily variance
+	DefaultHVWindow  = 20
+	MinIVDays        = 20 // Stored IV days needed before rank and percentile set a regime
+	HighIVRank       = 50 // At or above: premium is rich, prefer credit structures
+	LowIVRank        = 25 // At or below: premium is cheap, prefer debit structures
+	RegimeHigh       = "high"
+	RegimeLow        = "low"
+	RegimeNormal     = "normal"
+	ivLookbackPeriod = 365 // Calendar days of IV history used for rank and percentile
+)
+
+// HV is realized volatility over a window of daily bars, annualized. Zero when there are too few bars.
+type HV struct {
+	CloseToClose float64 `json:"closeToClose"`
+	Parkinson    float64 `json:"parkinson"`
+	YangZhang    float64 `json:"yangZhang"`
+}
+
+// Metrics compares a ticker's current IV with its own history and with realized volatility
+type Metrics struct {
+	Ticker       string  `json:"ticker"`
+	AsOf         string  `json:"asOf"`         // Date of the latest stored IV
+	IV           float64 `json:"iv"`           // Latest ATM 30-day IV
+	IVRank       float64 `json:"ivRank"`       // Where IV sits between its 1-year low (0) and high (100)
+	IVPercentile float64 `json:"ivPercentile"` // Percent of days in the last year with lower IV
+	IVLow        float64 `json:"ivLow"`
+	IVHigh       float64 `json:"ivHigh"`
+	IVDays       int     `json:"ivDays"` // Stored IV days behind the rank
+	HVWindow     int     `json:"hvWindow"`
+	HV           HV      `json:"hv"`
+	IVHVRatio    float64 `json:"ivHvRatio,omitempty"` // IV / Yang-Zhang HV
+	Regime       string  `json:"regime,omitempty"`    // high, low or normal; empty without enough history
+	Prefer       string  `json:"prefer,omitempty"`    // strategies.PreferCredit or PreferDebit for the regime
+}
+
+// ATMIV30 interpolates the at-the-money IV to a constant IVTenorDays maturity from the chain snapshot
+// captured at or before asOf. Each expiry's ATM IV is solved from the call and put mids with CalculateIV,
+// and the expiries bracketing the tenor are interpolated in total variance.
+func ATMIV30(ticker string, asOf time.Time) (float64, error) {
+	quote, err := storage.GetQuoteAsOf(ticker, asOf)
+	if err != nil {
+		return 0, fmt.Errorf("no quote for %s: %v", ticker, err)
+	}
+	chain, err := storage.GetChainAsOf(ticker, asOf)
+	if err != nil {
+		return 0, err
+	}
+
+	type expiryIV struct {
+		T  float64
+		IV float64
+	}
+	byExpiry := map[string][]storage.OptionQuote{}
+	for _, q := range chain {
+		byExpiry[q.Expiry] = append(byExpiry[q.Expiry], q)
+	}
+
+	var points []expiryIV
+	for expiry, quotes := range byExpiry {
+		expiryTime, err := time.Parse("2006-01-02", expiry)
+		if err != nil {
+			continue
+		}
+		T := expiryTime.Sub(quote.CapturedAt).Hours() / 24 / 365
+		if T <= 1.0/365 {
+			continue
+		}
+		if iv := atmIV(quotes, quote.Price, T); iv > 0 {
+			points = append(points, expiryIV{T, iv})
+		}
+	}
+	if len(points) == 0 {
+		return 0, fmt.Errorf("no ATM IV could be solved for %s", ticker)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].T < points[j].T })
+
+	target := float64(IVTenorDays) / 365
+	if target <= points[0].T {
+		return points[0].IV, nil
+	}
+	last := points[len(points)-1]
+	if target >= last.T {
+		return last.IV, nil
+	}
+	for i := 1; i < len(points); i++ {
+		lo, hi := points[i-1], points[i]
+		if target <= hi.T {
+			wLo, wHi := lo.IV*lo.IV*lo.T, hi.IV*hi.IV*hi.T
+			w := wLo + (wHi-wLo)*(target-lo.T)/(hi.T-lo.T)
+			return math.Sqrt(w / target), nil
+		}
+	}
+	return last.IV, nil
+}
+
+// atmIV averages the call and put IVs at the strike closest to spot
+func atmIV(quotes []storage.OptionQuote, spot, T float64) float64 {
+	calls := map[float64]float64{}
+	puts := map[float64]float64{}
+	for _, q := range quotes {
+		mid := q.Last
+		if q.Bid > 0 && q.Ask > 0 {
+			mid = (q.Bid + q.Ask) / 2
+		}
+		if mid <= 0 {
+			continue
+		}
+		if q.Type == string(calculator.Call) {
+			calls[q.Strike] = mid
+		} else {
+			puts[q.Strike] = mid
+		}
+	}
+
+	strike, best := 0.0, math.Inf(1)
+	for k := range calls {
+		if _, ok := puts[k]; !ok {
+			continue
+		}
+		if d := math.Abs(k - spot); d < best || (d == best && k < strike) {
+			strike, best = k, d
+		}
+	}
+	if strike == 0 {
+		return 0
+	}
+
+	callIV := calculator.CalculateIVFromCurve(calls[strike], calculator.Call, spot, strike, T)
+	putIV := calculator.CalculateIVFromCurve(puts[strike], calculator.Put, spot, strike, T)
+	switch {
+	case callIV > 0 && putIV > 0:
+		return (callIV + putIV) / 2
+	case callIV > 0:
+		return callIV
+	}
+	return putIV
+}
+
+// CloseToClose is the standard deviation of daily log returns over the last window bars
+func CloseToClose(bars []storage.PriceBar, window int) float64 {
+	if len(bars) < window+1 || window < 2 {
+		return 0
+	}
+	bars = bars[len(bars)-window-1:]
+
+	returns := make([]float64, window)
+	for i := 1; i < len(bars); i++ {
+		returns[i-1] = math.Log(bars[i].Close / bars[i-1].Close)
+	}
+	return math.Sqrt(variance(returns) * TradingDays)
+}
+
+// Parkinson estimates volatility from the high-low range of the last window bars
+func Parkinson(bars []storage.PriceBar, window int) float64 {
+	if len(bars) < window || window < 1 {
+		return 0
+	}
+	bars = bars[len(bars)-window:]
+
+	sum := 0.0
+	for _, b := range bars {
+		hl := math.Log(b.High / b.Low)
+		sum += hl * hl
+	}
+	return math.Sqrt(sum / float64(window) / (4 * math.Ln2) * TradingDays)
+}
+
+// YangZhang combines overnight, open-to-close and Rogers-Satchell variances over the last window bars,
+// which handles both opening gaps and drift
+func YangZhang(bars []storage.PriceBar, window int) float64 {
+	if len(bars) < window+1 || window < 2 {
+		return 0
+	}
+	bars = bars[len(bars)-window-1:]
+
+	overnight := make([]float64, window)
+	openClose := make([]float64, window)
+	rs := 0.0
+	for i := 1; i < len(bars); i++ {
+		b := bars[i]
+		overnight[i-1] = math.Log(b.Open / bars[i-1].Close)
+		openClose[i-1] = math.Log(b.Close / b.Open)
+		rs += math.Log(b.High/b.Close)*math.Log(b.High/b.Open) + math.Log(b.Low/b.Close)*math.Log(b.Low/b.Open)
+	}
+
+	n := float64(window)
+	k := 0.34 / (1.34 + (n+1)/(n-1))
+	v := variance(overnight) + k*variance(openClose) + (1-k)*rs/n
+	return math.Sqrt(math.Max(v, 0) * TradingDays)
+}
+
+// variance is the sample variance
+func variance(v []float64) float64 {
+	mean := 0.0
+	for _, x := range v {
+		mean += x
+	}
+	mean /= float64(len(v))
+	sum := 0.0
+	for _, x := range v {
+		sum += (x - mean) * (x - mean)
+	}
+	return sum / float64(len(v)-1)
+}
+
+// GetMetrics computes IV rank, IV percentile and realized volatility for ticker from stored history
+func GetMetrics(ticker string, hvWindow int) (Metrics, error) {
+	if hvWindow <= 1 {
+		hvWindow = DefaultHVWindow
+	}
+	now := time.Now()
+	m := Metrics{Ticker: ticker, HVWindow: hvWindow}
+
+	history, err := storage.GetIVHistory(ticker, now.AddDate(0, 0, -ivLookbackPeriod), now)
+	if err != nil {
+		return m, err
+	}
+	if len(history) == 0 {
+		return m, fmt.Errorf("no IV history for %s", ticker)
+	}
+
+	latest := history[len(history)-1]
+	m.IV, m.AsOf, m.IVDays = round(latest.IV, 10000), latest.Date.Format("2006-01-02"), len(history)
+	m.IVLow, m.IVHigh = latest.IV, latest.IV
+	below := 0
+	for _, p := range history {
+		m.IVLow = math.Min(m.IVLow, p.IV)
+		m.IVHigh = math.Max(m.IVHigh, p.IV)
+		if p.IV < latest.IV {
+			below++
+		}
+	}
+	if m.IVHigh > m.IVLow {
+		m.IVRank = round((latest.IV-m.IVLow)/(m.IVHigh-m.IVLow)*100, 10)
+	}
+	m.IVPercentile = round(float64(below)/float64(len(history))*100, 10)
+	m.IVLow, m.IVHigh = round(m.IVLow, 10000), round(m.IVHigh, 10000)
+
+	// Enough calendar days to cover the window's trading days
+	bars, err := storage.GetPriceBars(ticker, now.AddDate(0, 0, -hvWindow*2-10), now)
+	if err != nil {
+		return m, err
+	}
+	m.HV = HV{
+		CloseToClose: round(CloseToClose(bars, hvWindow), 10000),
+		Parkinson:    round(Parkinson(bars, hvWindow), 10000),
+		YangZhang:    round(YangZhang(bars, hvWindow), 10000),
+	}
+	if m.HV.YangZhang > 0 {
+		m.IVHVRatio = round(latest.IV/m.HV.YangZhang, 100)
+	}
+
+	if m.IVDays >= MinIVDays {
+		switch {
+		case m.IVRank >= HighIVRank:
+			m.Regime, m.Prefer = RegimeHigh, strategies.PreferCredit
+		case m.IVRank <= LowIVRank:
+			m.Regime, m.Prefer = RegimeLow, strategies.PreferDebit
+		default:
+			m.Regime = RegimeNormal
+		}
+	}
+	return m, nil
+}
+
+// RecordDay stores the ticker's OHLC bar for date, built from that day's regular-session quote snapshots,
+// and its ATM 30-day IV as of the last of them. High and low are only as fine as the snapshot interval.
+// Days the exchange is closed are skipped, so weekends and holidays never enter the bars or the IV history.
+func RecordDay(ticker string, date time.Time) error {
+	if !calculator.IsTradingDay(date) {
+		return nil
+	}
+	snapshots, err := storage.GetDayQuotes(ticker, date)
+	if err != nil {
+		return err
+	}
+	var quotes []storage.UnderlyingQuote
+	for _, q := range snapshots {
+		if calculator.IsMarketOpen(q.CapturedAt) {
+			quotes = append(quotes, q)
+		}
+	}
+	if len(quotes) == 0 {
+		return fmt.Errorf("no session snapshots for %s on %s", ticker, date.Format("2006-01-02"))
+	}
+
+	bar := storage.PriceBar{Ticker: ticker, Date: date, Open: quotes[0].Price, High: quotes[0].Price, Low: quotes[0].Price, Close: quotes[len(quotes)-1].Price}
+	for _, q := range quotes {
+		bar.High = math.Max(bar.High, q.Price)
+		bar.Low = math.Min(bar.Low, q.Price)
+	}
+	if err := storage.SavePriceBar(bar); err != nil {
+		return err
+	}
+
+	iv, err := ATMIV30(ticker, quotes[len(quotes)-1].CapturedAt)
+	if err != nil {
+		return err
+	}
+	return storage.SaveIV(storage.IVPoint{Ticker: ticker, Date: date, IV: iv})
+}
+
+// Run records the previous day (now complete) and the latest session (so far) for each ticker every interval.
+// It never returns.
+func Run(tickers []string, interval time.Duration) {
+	for {
+		session := calculator.SessionDate(time.Now())
+		for _, ticker := range tickers {
+			for _, day := range []time.Time{session.AddDate(0, 0, -1), session} {
+				if err := RecordDay(ticker, day); err != nil {
+					log.Printf("Error recording volatility for %s on %s: %v", ticker, day.Format("2006-01-02"), err)
+				}
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+func round(v, scale float64) float64 {
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,86 @@
+package volatility
+
+import (
+	"math"
+	"strikelogic/storage"
+	"testing"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+// zigzag closes alternate up and down by a log return of r, opening at the prior close with no intraday range
+func zigzag(n int, r float64) []storage.PriceBar {
+	bars := make([]storage.PriceBar, n)
+	price := 100.0
+	for i := range bars {
+		open := price
+		if i > 0 {
+			if i%2 == 1 {
+				price *= math.Exp(r)
+			} else {
+				price *= math.Exp(-r)
+			}
+		}
+		bars[i] = storage.PriceBar{Open: open, High: math.Max(open, price), Low: math.Min(open, price), Close: price}
+	}
+	return bars
+}
+
+// flat bars open and close at 100 with a high and low a log distance of h either side
+func flat(n int, h float64) []storage.PriceBar {
+	bars := make([]storage.PriceBar, n)
+	for i := range bars {
+		bars[i] = storage.PriceBar{Open: 100, High: 100 * math.Exp(h), Low: 100 * math.Exp(-h), Close: 100}
+	}
+	return bars
+}
+
+func TestCloseToClose(t *testing.T) {
+	// Returns of +/-1% over 4 days: sample variance 4 x 0.0001 / 3
+	want := math.Sqrt(0.0004 / 3 * TradingDays)
+	if got := CloseToClose(zigzag(5, 0.01), 4); !near(got, want) {
+		t.Errorf("zigzag HV %v, want %v", got, want)
+	}
+	// Only the last window returns count
+	if got := CloseToClose(append(flat(10, 0.02), zigzag(5, 0.01)...), 4); !near(got, want) {
+		t.Errorf("windowed HV %v, want %v", got, want)
+	}
+	if got := CloseToClose(flat(10, 0.02), 4); got != 0 {
+		t.Errorf("unchanged closes gave %v", got)
+	}
+	if CloseToClose(zigzag(4, 0.01), 4) != 0 || CloseToClose(zigzag(5, 0.01), 1) != 0 {
+		t.Error("too few bars or returns should give 0")
+	}
+}
+
+func TestParkinson(t *testing.T) {
+	// Each day ranges ln(H/L) = 0.04
+	want := math.Sqrt(0.04 * 0.04 / (4 * math.Ln2) * TradingDays)
+	if got := Parkinson(flat(4, 0.02), 4); !near(got, want) {
+		t.Errorf("Parkinson HV %v, want %v", got, want)
+	}
+	if Parkinson(flat(3, 0.02), 4) != 0 || Parkinson(flat(3, 0.02), 0) != 0 {
+		t.Error("too few bars should give 0")
+	}
+}
+
+func TestYangZhang(t *testing.T) {
+	// Without intraday moves every return is overnight, which is close-to-close
+	gaps := zigzag(5, 0.01)
+	for i := range gaps {
+		gaps[i].Open, gaps[i].High, gaps[i].Low = gaps[i].Close, gaps[i].Close, gaps[i].Close
+	}
+	if got, want := YangZhang(gaps, 4), CloseToClose(gaps, 4); !near(got, want) {
+		t.Errorf("gap-only HV %v, want close-to-close %v", got, want)
+	}
+
+	// Without gaps or open-to-close moves only Rogers-Satchell is left: 2h^2 a day, weighted by 1-k
+	k := 0.34 / (1.34 + 5.0/3)
+	want := math.Sqrt((1 - k) * 2 * 0.02 * 0.02 * TradingDays)
+	if got := YangZhang(flat(5, 0.02), 4); !near(got, want) {
+		t.Errorf("range-only HV %v, want %v", got, want)
+	}
+	if YangZhang(flat(4, 0.02), 4) != 0 {
+		t.Error("too few bars should give 0")
+	}
+}
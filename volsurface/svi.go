@@ -0,0 +1,141 @@
+package volsurface
+
+import (
+	"math"
+)
+
+// SVI is Gatheral's raw SVI parameterization of total implied variance in log-moneyness k = ln(K/F):
+// w(k) = A + B * (Rho*(k-M) + sqrt((k-M)^2 + Sigma^2))
+type SVI struct {
+	A     float64 `json:"a"`
+	B     float64 `json:"b"`
+	Rho   float64 `json:"rho"`
+	M     float64 `json:"m"`
+	Sigma float64 `json:"sigma"`
+}
+
+// TotalVariance evaluates w(k)
+func (s SVI) TotalVariance(k float64) float64 {
+	d := k - s.M
+	return s.A + s.B*(s.Rho*d+math.Sqrt(d*d+s.Sigma*s.Sigma))
+}
+
+// fitSVI fits raw SVI to (k, w) pairs with the quasi-explicit method (Zeliade, 2009):
+// for fixed M and Sigma the remaining parameters are linear, so a search over (M, Sigma)
+// wraps a constrained 3-parameter least squares. Returns false if no admissible fit exists.
+func fitSVI(ks, ws []float64) (SVI, float64, bool) {
+	if len(ks) < 5 {
+		return SVI{}, 0, false
+	}
+
+	kMin, kMax := ks[0], ks[0]
+	wMax := 0.0
+	for i, k := range ks {
+		kMin = math.Min(kMin, k)
+		kMax = math.Max(kMax, k)
+		wMax = math.Max(wMax, ws[i])
+	}
+
+	best := SVI{}
+	bestErr := math.Inf(1)
+	found := false
+
+	try := func(m, sigma float64) {
+		params, ok := fitLinear(ks, ws, m, sigma, wMax)
+		if !ok {
+			return
+		}
+		if e := sviError(params, ks, ws); e < bestErr {
+			best, bestErr, found = params, e, true
+		}
+	}
+
+	// Coarse grid, then two rounds of refinement around the best point
+	mLo, mHi := kMin, kMax
+	sLo, sHi := math.Log(0.005), math.Log(2.0)
+	for round := 0; round < 3; round++ {
+		const steps = 16
+		for i := 0; i <= steps; i++ {
+			m := mLo + (mHi-mLo)*float64(i)/steps
+			for j := 0; j <= steps; j++ {
+				try(m, math.Exp(sLo+(sHi-sLo)*float64(j)/steps))
+			}
+		}
+		if !found {
+			return SVI{}, 0, false
+		}
+		mSpan, sSpan := (mHi-mLo)/8, (sHi-sLo)/8
+		mLo, mHi = best.M-mSpan, best.M+mSpan
+		sLo, sHi = math.Log(best.Sigma)-sSpan, math.Log(best.Sigma)+sSpan
+	}
+
+	return best, math.Sqrt(bestErr / float64(len(ks))), true
+}
+
+// fitLinear solves for A, B and Rho given M and Sigma.
+// With y = (k-M)/Sigma, w = a + d*y + c*sqrt(y^2+1) where c = B*Sigma and d = Rho*B*Sigma.
+// Constraints: 0 <= c <= 4*Sigma, |d| <= c, 0 <= a <= max w.
+func fitLinear(ks, ws []float64, m, sigma, wMax float64) (SVI, bool) {
+	// Normal equations for [a, d, c]
+	var ata [3][3]float64
+	var atb [3]float64
+	for i, k := range ks {
+		y := (k - m) / sigma
+		row := [3]float64{1, y, math.Sqrt(y*y + 1)}
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				ata[r][c] += row[r] * row[c]
+			}
+			atb[r] += row[r] * ws[i]
+		}
+	}
+
+	x, ok := solve3(ata, atb)
+	if !ok {
+		return SVI{}, false
+	}
+	a, d, c := x[0], x[1], x[2]
+
+	// Project onto the admissible region rather than rejecting outright
+	c = math.Min(math.Max(c, 0), 4*sigma)
+	d = math.Max(-c, math.Min(d, c))
+	a = math.Min(math.Max(a, 0), wMax)
+	if c == 0 {
+		return SVI{}, false
+	}
+
+	return SVI{A: a, B: c / sigma, Rho: d / c, M: m, Sigma: sigma}, true
+}
+
+func sviError(s SVI, ks, ws []float64) float64 {
+	sum := 0.0
+	for i, k := range ks {
+		diff := s.TotalVariance(k) - ws[i]
+		sum += diff * diff
+	}
+	return sum
+}
+
+// solve3 solves a 3x3 linear system with Cramer's rule
+func solve3(a [3][3]float64, b [3]float64) ([3]float64, bool) {
+	det := func(m [3][3]float64) float64 {
+		return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+			m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+			m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	}
+
+	d := det(a)
+	if math.Abs(d) < 1e-14 {
+		return [3]float64{}, false
+	}
+
+	var x [3]float64
+	for col := 0; col < 3; col++ {
+		m := a
+		for row := 0; row < 3; row++ {
+			m[row][col] = b[row]
+		}
+		x[col] = det(m) / d
+	}
+	return x, true
+}
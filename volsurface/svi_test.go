@@ -0,0 +1,134 @@
+package volsurface
+
+import (
+	"math"
+	"strikelogic/calculator"
+	"testing"
+	"time"
+)
+
+func TestFitSVIRecoversParameters(t *testing.T) {
+	want := SVI{A: 0.02, B: 0.1, Rho: -0.4, M: 0.05, Sigma: 0.2}
+
+	var ks, ws []float64
+	for k := -0.5; k <= 0.5001; k += 0.05 {
+		ks = append(ks, k)
+		ws = append(ws, want.TotalVariance(k))
+	}
+
+	got, rmse, ok := fitSVI(ks, ws)
+	if !ok {
+		t.Fatal("fitSVI found no admissible fit")
+	}
+	if rmse > 1e-4 {
+		t.Errorf("RMSE %.6f on exact SVI data", rmse)
+	}
+	for k := -0.6; k <= 0.6; k += 0.1 {
+		if diff := math.Abs(got.TotalVariance(k) - want.TotalVariance(k)); diff > 2e-4 {
+			t.Errorf("w(%.1f) = %.5f, want %.5f", k, got.TotalVariance(k), want.TotalVariance(k))
+		}
+	}
+	if math.Abs(got.Rho-want.Rho) > 0.05 || math.Abs(got.M-want.M) > 0.02 {
+		t.Errorf("fit %+v, want %+v", got, want)
+	}
+}
+
+func TestFitSVIAdmissible(t *testing.T) {
+	// A noisy smile must still fit within the no-arbitrage parameter constraints
+	ks := []float64{-0.4, -0.3, -0.2, -0.1, 0, 0.1, 0.2, 0.3}
+	ws := []float64{0.030, 0.024, 0.019, 0.016, 0.0135, 0.013, 0.0136, 0.0141}
+
+	s, _, ok := fitSVI(ks, ws)
+	if !ok {
+		t.Fatal("fitSVI found no admissible fit")
+	}
+	if s.B < 0 || math.Abs(s.Rho) > 1 || s.Sigma <= 0 || s.A < 0 {
+		t.Errorf("inadmissible parameters %+v", s)
+	}
+	if s.B*s.Sigma > 4*s.Sigma+1e-12 {
+		t.Errorf("B*Sigma %.4f exceeds 4*Sigma", s.B*s.Sigma)
+	}
+
+	if _, _, ok := fitSVI(ks[:4], ws[:4]); ok {
+		t.Error("fitSVI should refuse fewer than 5 points")
+	}
+}
+
+// syntheticChain quotes OTM options at the vols of a known smile per expiry
+func syntheticChain(spot float64, asOf time.Time, smiles map[int]SVI) []calculator.OptionContract {
+	var chain []calculator.OptionContract
+	for days, svi := range smiles {
+		T := float64(days) / 365
+		expiry := asOf.AddDate(0, 0, days).Format("2006-01-02")
+		r := 0.0
+		forward := spot
+		for K := 70.0; K <= 130; K += 5 {
+			vol := math.Sqrt(svi.TotalVariance(math.Log(K/forward)) / T)
+			for _, typ := range []calculator.OptionType{calculator.Call, calculator.Put} {
+				price, _, _, _, _ := calculator.CalculateOptionPrice(typ, spot, K, T, r, vol)
+				chain = append(chain, calculator.OptionContract{
+					Strike: K, Expiry: expiry, Type: typ, Underlying: "XYZ",
+					Bid: price * 0.99, Ask: price * 1.01, Vol: vol, IVSource: calculator.IVSourceMid,
+				})
+			}
+		}
+	}
+	return chain
+}
+
+func TestBuildSurface(t *testing.T) {
+	asOf := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	near := SVI{A: 0.004, B: 0.05, Rho: -0.5, M: 0, Sigma: 0.1}
+	far := SVI{A: 0.03, B: 0.12, Rho: -0.4, M: 0, Sigma: 0.2}
+	chain := syntheticChain(100, asOf, map[int]SVI{30: near, 180: far})
+
+	s, err := Build("XYZ", 100, chain, asOf)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(s.Smiles) != 2 || s.Smiles[0].T > s.Smiles[1].T {
+		t.Fatalf("expected 2 smiles sorted by T, got %+v", s.Smiles)
+	}
+	for _, sm := range s.Smiles {
+		if sm.Method != MethodSVI {
+			t.Errorf("%s fitted with %s, want svi", sm.Expiry, sm.Method)
+		}
+		if sm.RMSE > 0.005 {
+			t.Errorf("%s RMSE %.4f", sm.Expiry, sm.RMSE)
+		}
+	}
+
+	// Reproduces the quoted vols at the listed expiries
+	for K := 80.0; K <= 120; K += 10 {
+		want := math.Sqrt(near.TotalVariance(math.Log(K/s.Smiles[0].Forward)) / s.Smiles[0].T)
+		if got := s.Vol(K, s.Smiles[0].T); math.Abs(got-want) > 0.005 {
+			t.Errorf("Vol(%v, near) = %.4f, want %.4f", K, got, want)
+		}
+	}
+
+	// Total variance never decreases between expiries
+	for K := 80.0; K <= 120; K += 10 {
+		prev := 0.0
+		for T := s.Smiles[0].T; T <= s.Smiles[1].T; T += 0.05 {
+			w := s.Vol(K, T) * s.Vol(K, T) * T
+			if w < prev-1e-9 {
+				t.Errorf("calendar arbitrage at K=%v T=%.2f: w %.5f < %.5f", K, T, w, prev)
+			}
+			prev = w
+		}
+	}
+
+	g := s.Grid(DefaultMoneyness())
+	if len(g.Vols) != 2 || len(g.Vols[0]) != len(g.Moneyness) {
+		t.Errorf("grid has shape %dx%d", len(g.Vols), len(g.Vols[0]))
+	}
+}
+
+func TestBuildRejectsEmptyChain(t *testing.T) {
+	if _, err := Build("XYZ", 100, nil, time.Now()); err == nil {
+		t.Error("Build with no contracts should fail")
+	}
+	if _, err := Build("XYZ", 0, nil, time.Now()); err == nil {
+		t.Error("Build with no spot should fail")
+	}
+}
@@ -0,0 +1,386 @@
+package volsurface
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strikelogic/calculator"
+	"strikelogic/rates"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Smile fitting methods
+const (
+	MethodSVI    = "svi"    // Raw SVI fit (needs at least 5 clean points)
+	MethodLinear = "linear" // Piecewise linear total variance through the clean points
+)
+
+// Limits used when cleaning quotes before fitting
+const (
+	MaxRelativeSpread = 0.5 // (ask - bid) / mid
+	MaxLogMoneyness   = 1.0 // |ln(K/F)|
+	MinDTE            = 2   // Expiries closer than this are too noisy to fit
+	OutlierVol        = 0.05
+)
+
+// DefaultMaxDTE bounds how far out Load fetches expirations
+const DefaultMaxDTE = 400
+
+// CacheTTL is how long Load reuses a built surface
+const CacheTTL = 5 * time.Minute
+
+// Point is a single cleaned market IV used in a fit
+type Point struct {
+	Strike       float64               `json:"strike"`
+	Type         calculator.OptionType `json:"type"`
+	IV           float64               `json:"iv"`
+	LogMoneyness float64               `json:"logMoneyness"` // ln(K/F)
+}
+
+// Smile is the fitted volatility smile for one expiry
+type Smile struct {
+	Expiry  string  `json:"expiry"`
+	T       float64 `json:"t"`       // Years to expiry
+	Forward float64 `json:"forward"` // Implied from put-call parity where possible
+	Method  string  `json:"method"`
+	SVI     *SVI    `json:"svi,omitempty"`
+	RMSE    float64 `json:"rmse"`   // Root mean square fit error in IV
+	ATMVol  float64 `json:"atmVol"` // Vol at the forward, for the term structure
+	Points  []Point `json:"points"` // Raw points the fit used
+
+	ks, ws []float64 // Linear fit nodes (sorted by k)
+}
+
+// TotalVariance returns w(k) = IV^2 * T at log-moneyness k
+func (s Smile) TotalVariance(k float64) float64 {
+	if s.SVI != nil {
+		return math.Max(s.SVI.TotalVariance(k), 0)
+	}
+	if len(s.ks) == 0 {
+		return 0
+	}
+	// Flat beyond the outermost strikes
+	if k <= s.ks[0] {
+		return s.ws[0]
+	}
+	last := len(s.ks) - 1
+	if k >= s.ks[last] {
+		return s.ws[last]
+	}
+	i := sort.SearchFloat64s(s.ks, k)
+	w := (k - s.ks[i-1]) / (s.ks[i] - s.ks[i-1])
+	return s.ws[i-1] + w*(s.ws[i]-s.ws[i-1])
+}
+
+// Vol returns the smile's implied volatility at log-moneyness k
+func (s Smile) Vol(k float64) float64 {
+	return math.Sqrt(s.TotalVariance(k) / s.T)
+}
+
+// Surface is a strike x expiry implied volatility surface
+type Surface struct {
+	Ticker string    `json:"ticker"`
+	Spot   float64   `json:"spot"`
+	AsOf   time.Time `json:"asOf"`
+	Smiles []Smile   `json:"smiles"` // Sorted by T
+}
+
+// Vol returns the implied volatility for a strike at T years from AsOf.
+// Between expiries total variance is interpolated linearly in T at constant log-forward-moneyness
+// (floored at the earlier expiry's variance, so there is no calendar arbitrage); outside them vol is held flat.
+func (s *Surface) Vol(strike, T float64) float64 {
+	if len(s.Smiles) == 0 || strike <= 0 {
+		return 0
+	}
+	T = math.Max(T, 1.0/365)
+	k := math.Log(strike / s.forward(T))
+
+	first, last := s.Smiles[0], s.Smiles[len(s.Smiles)-1]
+	var w float64
+	switch {
+	case T <= first.T:
+		w = first.TotalVariance(k) * T / first.T
+	case T >= last.T:
+		w = last.TotalVariance(k) * T / last.T
+	default:
+		i := sort.Search(len(s.Smiles), func(i int) bool { return s.Smiles[i].T >= T })
+		lo, hi := s.Smiles[i-1], s.Smiles[i]
+		wLo := lo.TotalVariance(k)
+		wHi := math.Max(hi.TotalVariance(k), wLo)
+		w = wLo + (T-lo.T)/(hi.T-lo.T)*(wHi-wLo)
+	}
+
+	vol := math.Sqrt(math.Max(w, 0) / T)
+	return math.Min(math.Max(vol, calculator.MinIV), calculator.MaxIV)
+}
+
+// forward interpolates ln(F/S) linearly in T, extrapolating the nearest carry beyond the ends
+func (s *Surface) forward(T float64) float64 {
+	carry := func(sm Smile) float64 { return math.Log(sm.Forward/s.Spot) / sm.T }
+
+	first, last := s.Smiles[0], s.Smiles[len(s.Smiles)-1]
+	if T <= first.T {
+		return s.Spot * math.Exp(carry(first)*T)
+	}
+	if T >= last.T {
+		return s.Spot * math.Exp(carry(last)*T)
+	}
+	i := sort.Search(len(s.Smiles), func(i int) bool { return s.Smiles[i].T >= T })
+	lo, hi := s.Smiles[i-1], s.Smiles[i]
+	yLo, yHi := math.Log(lo.Forward/s.Spot), math.Log(hi.Forward/s.Spot)
+	return s.Spot * math.Exp(yLo+(T-lo.T)/(hi.T-lo.T)*(yHi-yLo))
+}
+
+// Grid samples the surface for charting: Vols[i][j] is the vol at Expiries[i] and strike Spot*Moneyness[j]
+type Grid struct {
+	Moneyness []float64   `json:"moneyness"` // K / Spot
+	Expiries  []string    `json:"expiries"`
+	Tenors    []float64   `json:"tenors"` // Years
+	Vols      [][]float64 `json:"vols"`
+}
+
+// DefaultMoneyness is 70% to 130% of spot in 2.5% steps
+func DefaultMoneyness() []float64 {
+	var m []float64
+	for i := 0; i <= 24; i++ {
+		m = append(m, 0.7+float64(i)*0.025)
+	}
+	return m
+}
+
+// Grid evaluates the surface at each smile's expiry and the given moneyness levels
+func (s *Surface) Grid(moneyness []float64) Grid {
+	g := Grid{Moneyness: moneyness}
+	for _, sm := range s.Smiles {
+		row := make([]float64, len(moneyness))
+		for j, m := range moneyness {
+			row[j] = math.Round(s.Vol(s.Spot*m, sm.T)*10000) / 10000
+		}
+		g.Expiries = append(g.Expiries, sm.Expiry)
+		g.Tenors = append(g.Tenors, sm.T)
+		g.Vols = append(g.Vols, row)
+	}
+	return g
+}
+
+// Build fits a surface to a multi-expiry chain quoted at spot as of asOf
+func Build(ticker string, spot float64, chain []calculator.OptionContract, asOf time.Time) (*Surface, error) {
+	if spot <= 0 {
+		return nil, fmt.Errorf("invalid spot price %.2f for %s", spot, ticker)
+	}
+
+	byExpiry := make(map[string][]calculator.OptionContract)
+	for _, c := range chain {
+		byExpiry[c.Expiry] = append(byExpiry[c.Expiry], c)
+	}
+
+	surface := &Surface{Ticker: ticker, Spot: spot, AsOf: asOf}
+	for expiry, contracts := range byExpiry {
+		expiryTime, err := time.Parse("2006-01-02", expiry)
+		if err != nil {
+			continue
+		}
+		days := expiryTime.Sub(asOf).Hours() / 24
+		if days < MinDTE {
+			continue
+		}
+
+		smile, ok := fitSmile(expiry, days/365.0, spot, contracts)
+		if ok {
+			surface.Smiles = append(surface.Smiles, smile)
+		}
+	}
+
+	if len(surface.Smiles) == 0 {
+		return nil, fmt.Errorf("no expiries for %s had enough clean quotes to fit", ticker)
+	}
+	sort.Slice(surface.Smiles, func(i, j int) bool { return surface.Smiles[i].T < surface.Smiles[j].T })
+	return surface, nil
+}
+
+// fitSmile cleans one expiry's quotes and fits SVI, falling back to linear interpolation
+func fitSmile(expiry string, T, spot float64, contracts []calculator.OptionContract) (Smile, bool) {
+	r := rates.RateFor(T)
+	forward := estimateForward(contracts, spot, T, r)
+	smile := Smile{Expiry: expiry, T: T, Forward: forward}
+
+	smile.Points = cleanPoints(contracts, forward)
+	if len(smile.Points) == 0 {
+		return smile, false
+	}
+
+	fit(&smile)
+
+	// Drop points far from the first fit and refit once
+	var kept []Point
+	for _, p := range smile.Points {
+		if math.Abs(smile.Vol(p.LogMoneyness)-p.IV) <= math.Max(OutlierVol, 3*smile.RMSE) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) > 0 && len(kept) < len(smile.Points) {
+		smile.Points = kept
+		fit(&smile)
+	}
+
+	smile.ATMVol = math.Round(smile.Vol(0)*10000) / 10000
+	return smile, true
+}
+
+func fit(smile *Smile) {
+	ks := make([]float64, len(smile.Points))
+	ws := make([]float64, len(smile.Points))
+	for i, p := range smile.Points {
+		ks[i] = p.LogMoneyness
+		ws[i] = p.IV * p.IV * smile.T
+	}
+	smile.ks, smile.ws = ks, ws
+	smile.SVI = nil
+	smile.Method = MethodLinear
+
+	if params, _, ok := fitSVI(ks, ws); ok {
+		smile.SVI = &params
+		smile.Method = MethodSVI
+	}
+
+	sum := 0.0
+	for _, p := range smile.Points {
+		diff := smile.Vol(p.LogMoneyness) - p.IV
+		sum += diff * diff
+	}
+	smile.RMSE = math.Sqrt(sum / float64(len(smile.Points)))
+}
+
+// cleanPoints keeps out-of-the-money quotes with a sane spread and an IV solved from the market.
+// Points come back sorted by strike with one point per strike.
+func cleanPoints(contracts []calculator.OptionContract, forward float64) []Point {
+	var points []Point
+	for _, c := range contracts {
+		if c.Strike <= 0 || c.Bid <= 0 || c.Ask < c.Bid {
+			continue
+		}
+		// OTM side only: those quotes carry the smile, ITM ones mostly carry intrinsic
+		if (c.Type == calculator.Call && c.Strike < forward) || (c.Type == calculator.Put && c.Strike >= forward) {
+			continue
+		}
+		mid := (c.Bid + c.Ask) / 2
+		if (c.Ask-c.Bid)/mid > MaxRelativeSpread {
+			continue
+		}
+		switch c.IVSource {
+		case calculator.IVSourceNeighbor, calculator.IVSourceDefault, calculator.IVSourceVendor:
+			continue
+		}
+		if c.Vol <= calculator.MinIV || c.Vol >= calculator.MaxIV {
+			continue
+		}
+		k := math.Log(c.Strike / forward)
+		if math.Abs(k) > MaxLogMoneyness {
+			continue
+		}
+		points = append(points, Point{Strike: c.Strike, Type: c.Type, IV: c.Vol, LogMoneyness: k})
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].Strike < points[j].Strike })
+	var unique []Point
+	for _, p := range points {
+		if len(unique) > 0 && unique[len(unique)-1].Strike == p.Strike {
+			continue
+		}
+		unique = append(unique, p)
+	}
+	return unique
+}
+
+// estimateForward takes the median put-call parity forward over strikes quoted on both sides,
+// falling back to spot carried at the risk-free rate when parity looks unreliable
+func estimateForward(contracts []calculator.OptionContract, spot, T, r float64) float64 {
+	fallback := spot * math.Exp(r*T)
+
+	type pair struct{ call, put float64 }
+	mids := make(map[float64]*pair)
+	for _, c := range contracts {
+		if c.Bid <= 0 || c.Ask < c.Bid {
+			continue
+		}
+		p := mids[c.Strike]
+		if p == nil {
+			p = &pair{}
+			mids[c.Strike] = p
+		}
+		if c.Type == calculator.Call {
+			p.call = (c.Bid + c.Ask) / 2
+		} else {
+			p.put = (c.Bid + c.Ask) / 2
+		}
+	}
+
+	var forwards []float64
+	for strike, p := range mids {
+		// Parity is cleanest near the money
+		if p.call > 0 && p.put > 0 && math.Abs(strike/spot-1) < 0.1 {
+			forwards = append(forwards, strike+(p.call-p.put)*math.Exp(r*T))
+		}
+	}
+	if len(forwards) == 0 {
+		return fallback
+	}
+
+	sort.Float64s(forwards)
+	f := forwards[len(forwards)/2]
+	if math.Abs(f/fallback-1) > 0.1 {
+		return fallback
+	}
+	return f
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]*Surface)
+)
+
+// Load returns the surface for ticker, reusing one built within CacheTTL
+func Load(ticker string) (*Surface, error) {
+	ticker = strings.ToUpper(ticker)
+
+	cacheMu.Lock()
+	s, ok := cache[ticker]
+	cacheMu.Unlock()
+	if ok && time.Since(s.AsOf) < CacheTTL {
+		return s, nil
+	}
+
+	s, err := Fetch(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMu.Lock()
+	cache[ticker] = s
+	cacheMu.Unlock()
+	return s, nil
+}
+
+// Fetch builds a fresh surface from every expiration within DefaultMaxDTE.
+// Only the configured provider is used; a surface fitted to mock quotes would skew everything priced off it.
+func Fetch(ticker string) (*Surface, error) {
+	provider, _ := calculator.Providers()
+	return fetchFrom(provider, ticker)
+}
+
+func fetchFrom(provider calculator.MarketDataProvider, ticker string) (*Surface, error) {
+	asOf := time.Now()
+
+	spot, err := provider.Quote(ticker)
+	if err != nil {
+		return nil, fmt.Errorf("quote: %v", err)
+	}
+	chain, err := calculator.GetFullChainFrom(provider, ticker, 0, DefaultMaxDTE)
+	if err != nil {
+		return nil, fmt.Errorf("chain: %v", err)
+	}
+
+	return Build(ticker, spot, chain, asOf)
+}
@@ -0,0 +1,172 @@
+package whales
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strikelogic/calculator"
+	"strikelogic/chainhistory"
+	"strikelogic/storage"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Thresholds decide which contracts are unusual. A contract is flagged when it meets every threshold; zero disables one.
+type Thresholds struct {
+	MinVolume     int64   `json:"minVolume"`     // Contracts traded today
+	MinVolumeOI   float64 `json:"minVolumeOI"`   // Volume / open interest; contracts with no open interest always pass
+	MinPremium    float64 `json:"minPremium"`    // Dollars traded: volume x price x 100
+	MinOTMPercent float64 `json:"minOTMPercent"` // Percent of spot out of the money
+	MaxDTE        int     `json:"maxDTE"`        // Expirations scanned; defaults to DefaultThresholds().MaxDTE
+}
+
+// DefaultThresholds flag opening-sized OTM flow: volume above open interest and at least $100k premium
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		MinVolume:     500,
+		MinVolumeOI:   1.0,
+		MinPremium:    100000,
+		MinOTMPercent: 5,
+		MaxDTE:        60,
+	}
+}
+
+var (
+	configMu   sync.RWMutex
+	thresholds = DefaultThresholds()
+)
+
+// SetThresholds replaces the scanner thresholds
+func SetThresholds(t Thresholds) {
+	if t.MaxDTE <= 0 {
+		t.MaxDTE = DefaultThresholds().MaxDTE
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	thresholds = t
+}
+
+// GetThresholds returns the scanner thresholds
+func GetThresholds() Thresholds {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return thresholds
+}
+
+// Watchlist returns the tickers to scan, read from the comma separated WHALE_WATCHLIST env var
+// and defaulting to the chain snapshot watchlist
+func Watchlist() []string {
+	env := os.Getenv("WHALE_WATCHLIST")
+	if env == "" {
+		return chainhistory.Watchlist()
+	}
+
+	var tickers []string
+	for _, t := range strings.Split(env, ",") {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t != "" {
+			tickers = append(tickers, t)
+		}
+	}
+	return tickers
+}
+
+// Flag returns the contracts in chain that meet every threshold, as alerts stamped with now.
+// Alerts are keyed on SessionDate(now), so scans after the close or before the open update that session's flows.
+func Flag(chain []calculator.OptionContract, ticker string, spot float64, t Thresholds, now time.Time) []storage.WhaleAlert {
+	var alerts []storage.WhaleAlert
+	for _, c := range chain {
+		if c.Volume <= 0 || c.Volume < t.MinVolume {
+			continue
+		}
+
+		volumeOI := 0.0
+		if c.OpenInterest > 0 {
+			volumeOI = float64(c.Volume) / float64(c.OpenInterest)
+			if volumeOI < t.MinVolumeOI {
+				continue
+			}
+		}
+
+		price := c.Last
+		if c.Bid > 0 && c.Ask > 0 {
+			price = (c.Bid + c.Ask) / 2
+		}
+		premium := float64(c.Volume) * price * 100
+		if premium < t.MinPremium {
+			continue
+		}
+
+		otm := (c.Strike - spot) / spot * 100
+		if c.Type == calculator.Put {
+			otm = -otm
+		}
+		if t.MinOTMPercent > 0 && otm < t.MinOTMPercent {
+			continue
+		}
+
+		alerts = append(alerts, storage.WhaleAlert{
+			Ticker:       ticker,
+			Expiry:       c.Expiry,
+			Type:         string(c.Type),
+			Strike:       c.Strike,
+			TradeDate:    calculator.SessionDate(now).Format("2006-01-02"),
+			Volume:       c.Volume,
+			OpenInterest: c.OpenInterest,
+			VolumeOI:     math.Round(volumeOI*100) / 100,
+			Premium:      math.Round(premium),
+			OTMPercent:   math.Round(otm*100) / 100,
+			Price:        math.Round(price*100) / 100,
+			Underlying:   spot,
+			IV:           c.Vol,
+			DetectedAt:   now,
+			UpdatedAt:    now,
+		})
+	}
+	return alerts
+}
+
+// Scan flags unusual activity in ticker's expirations within MaxDTE and stores the alerts.
+// Only the primary provider is used, so fallback (mock) activity is never persisted.
+func Scan(ticker string) ([]storage.WhaleAlert, error) {
+	t := GetThresholds()
+	provider, _ := calculator.Providers()
+	now := time.Now()
+
+	spot, err := provider.Quote(ticker)
+	if err != nil {
+		return nil, fmt.Errorf("quote: %v", err)
+	}
+	chain, err := calculator.GetFullChainFrom(provider, ticker, 0, t.MaxDTE)
+	if err != nil {
+		return nil, fmt.Errorf("chain: %v", err)
+	}
+
+	alerts := Flag(chain, ticker, spot, t, now)
+	if len(alerts) == 0 {
+		return alerts, nil
+	}
+	if err := storage.SaveWhaleAlerts(alerts); err != nil {
+		return nil, err
+	}
+	log.Printf("Flagged %d unusual contracts for %s", len(alerts), ticker)
+	return alerts, nil
+}
+
+// Run scans the watchlist every interval while the market is open. It never returns.
+func Run(tickers []string, interval time.Duration) {
+	for {
+		if !calculator.IsMarketOpen(time.Now()) {
+			time.Sleep(interval)
+			continue
+		}
+		for _, ticker := range tickers {
+			if _, err := Scan(ticker); err != nil {
+				log.Printf("Error scanning %s for unusual activity: %v", ticker, err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,58 @@
+package whales
+
+import (
+	"strikelogic/calculator"
+	"testing"
+	"time"
+)
+
+func TestFlag(t *testing.T) {
+	// Wednesday 2030-01-09, 10:00 in New York
+	now := time.Date(2030, 1, 9, 15, 0, 0, 0, time.UTC)
+	// 1000 contracts at a $2 mid are $200k of premium
+	base := calculator.OptionContract{Expiry: "2030-02-15", Type: calculator.Call, Strike: 110, Bid: 1.9, Ask: 2.1, Volume: 1000, OpenInterest: 500, Vol: 0.4}
+
+	tests := []struct {
+		name   string
+		change func(c *calculator.OptionContract)
+		flag   bool
+	}{
+		{"unusual", func(c *calculator.OptionContract) {}, true},
+		{"no volume", func(c *calculator.OptionContract) { c.Volume = 0 }, false},
+		{"low volume", func(c *calculator.OptionContract) { c.Volume, c.OpenInterest = 400, 100 }, false},
+		{"below open interest", func(c *calculator.OptionContract) { c.OpenInterest = 1500 }, false},
+		{"no open interest", func(c *calculator.OptionContract) { c.OpenInterest = 0 }, true},
+		{"small premium", func(c *calculator.OptionContract) { c.Bid, c.Ask = 0.4, 0.5 }, false},
+		{"last trade without a market", func(c *calculator.OptionContract) { c.Bid, c.Ask, c.Last = 0, 0, 1.5 }, true},
+		{"near the money", func(c *calculator.OptionContract) { c.Strike = 104 }, false},
+		{"in the money", func(c *calculator.OptionContract) { c.Strike = 90 }, false},
+		{"OTM put", func(c *calculator.OptionContract) { c.Type, c.Strike = calculator.Put, 90 }, true},
+		{"ITM put", func(c *calculator.OptionContract) { c.Type = calculator.Put }, false},
+	}
+	for _, tt := range tests {
+		c := base
+		tt.change(&c)
+		alerts := Flag([]calculator.OptionContract{c}, "XYZ", 100, DefaultThresholds(), now)
+		if got := len(alerts) == 1; got != tt.flag {
+			t.Errorf("%s: flagged = %v, want %v", tt.name, got, tt.flag)
+		}
+	}
+
+	alerts := Flag([]calculator.OptionContract{base}, "XYZ", 100, DefaultThresholds(), now)
+	a := alerts[0]
+	if a.Ticker != "XYZ" || a.TradeDate != "2030-01-09" || a.VolumeOI != 2 || a.Premium != 200000 || a.OTMPercent != 10 || a.Price != 2 {
+		t.Errorf("alert %+v", a)
+	}
+
+	// Zero thresholds disable the checks, but contracts must still trade
+	all := Flag([]calculator.OptionContract{base, {Strike: 100, Type: calculator.Call, Volume: 1, Last: 0.01}, {Strike: 100, Type: calculator.Put}}, "XYZ", 100, Thresholds{}, now)
+	if len(all) != 2 {
+		t.Errorf("zero thresholds flagged %d contracts, want 2", len(all))
+	}
+
+	// Scans before the open are stamped with the previous session
+	early := Flag([]calculator.OptionContract{base}, "XYZ", 100, DefaultThresholds(), time.Date(2030, 1, 9, 13, 0, 0, 0, time.UTC))
+	if early[0].TradeDate != "2030-01-08" {
+		t.Errorf("pre-market alert dated %s, want 2030-01-08", early[0].TradeDate)
+	}
+}